@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+)
+
+func newDiffCmd() *cobra.Command {
+	var environment, chart string
+	var includeValues bool
+	cmd := &cobra.Command{
+		Use:   "diff <pipeline>",
+		Short: "Show the changes the promotions in a pipeline would make",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := textOutputOnly(cmd); err != nil {
+				return err
+			}
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			pipeline, err := helm.FindPipeline(ctx, cl, pipelineKeys, args[0])
+			if err != nil {
+				return err
+			}
+
+			promotions := filterPromotions(helm.CalculatePromotions(*pipeline, promotionOptions(includeValues)...), environment, chart)
+			for _, promotion := range promotions {
+				diff, err := helm.DiffPromotion(ctx, cl, promotion)
+				if err != nil {
+					return fmt.Errorf("failed to diff promotion of %s to %s: %w", promotion.To.Name, promotion.Environment, err)
+				}
+				printPromotion(promotion)
+				fmt.Printf("values:\n%s\nmanifests:\n%s\n", diff.ValuesDiff, diff.ManifestDiff)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&environment, "to", "", "only diff promotions to this environment")
+	cmd.Flags().StringVar(&chart, "chart", "", "only diff promotions of this chart")
+	cmd.Flags().BoolVar(&includeValues, "include-values", false, "treat changes to values as promotions")
+
+	return cmd
+}
+
+func newValuesDiffCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "values-diff <pipeline>",
+		Short: "Show the differences in values between the environments in a pipeline",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := textOutputOnly(cmd); err != nil {
+				return err
+			}
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			pipeline, err := helm.FindPipeline(ctx, cl, pipelineKeys, args[0])
+			if err != nil {
+				return err
+			}
+
+			diffs, err := helm.DiffEnvironmentValues(ctx, cl, *pipeline)
+			if err != nil {
+				return fmt.Errorf("failed to diff values: %w", err)
+			}
+			for _, diff := range diffs {
+				fmt.Printf("chart: %s %s -> %s\n%s\n", diff.Chart, diff.From, diff.To, diff.Diff)
+			}
+			return nil
+		},
+	}
+}
+
+// textOutputOnly rejects the output flag for commands that print diffs, which
+// have no table, JSON or YAML representation.
+func textOutputOnly(cmd *cobra.Command) error {
+	if cmd.Flags().Changed(outputFlag) {
+		return fmt.Errorf("%s prints diffs as text and doesn't support --%s", cmd.Name(), outputFlag)
+	}
+
+	return nil
+}
+
+func printPromotion(p helm.Promotion) {
+	fmt.Printf("environment: %s chart: %s %s -> %s\n", p.Environment, p.To.Name, p.From.Version, p.To.Version)
+}
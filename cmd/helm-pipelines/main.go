@@ -1,12 +1,11 @@
 package main
 
 import (
-	"context"
 	"fmt"
 
-	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
 	runclient "github.com/fluxcd/pkg/runtime/client"
-	"github.com/gitops-tools/apps-scanner/pkg/pipelines"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -14,62 +13,80 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 
-	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/printers"
+)
+
+const (
+	outputFlag = "output"
 )
 
 var (
 	scheme            = runtime.NewScheme()
 	kubeclientOptions = &runclient.Options{}
+	pipelineKeys      = pipelinelabels.DefaultKeys
 )
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(helmv2.AddToScheme(scheme))
+	utilruntime.Must(sourcev1.AddToScheme(scheme))
 }
 
 func main() {
-	cfg, err := config.GetConfig()
-	cobra.CheckErr(err)
-
-	cl, err := client.New(cfg, client.Options{Scheme: scheme})
-	cobra.CheckErr(err)
-
-	rootCmd := newRootCmd(cl)
-	cobra.CheckErr(rootCmd.Execute())
+	cobra.CheckErr(newRootCmd().Execute())
 }
 
-func newRootCmd(cl client.Client) *cobra.Command {
+func newRootCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "helm-pipelines",
-		Short: "List pipelines in the cluster",
-		RunE:  listPipelines(cl),
+		Use:          "helm-pipelines",
+		Short:        "Manage the pipelines of HelmReleases in the cluster",
+		SilenceUsage: true,
 	}
 
 	kubeclientOptions.BindFlags(cmd.PersistentFlags())
+	cmd.PersistentFlags().StringVar(&pipelineKeys.Pipeline, "pipeline-label", pipelineKeys.Pipeline, "label that identifies the pipeline of a resource")
+	cmd.PersistentFlags().StringVar(&pipelineKeys.Environment, "environment-label", pipelineKeys.Environment, "label that identifies the environment of a resource")
+	cmd.PersistentFlags().StringVar(&pipelineKeys.After, "after-label", pipelineKeys.After, "label that identifies the environment that precedes the environment of a resource")
+	cmd.PersistentFlags().StringVar(&pipelineKeys.Application, "application-label", pipelineKeys.Application, "label that identifies the application deployed by a resource")
+	cmd.PersistentFlags().BoolVar(&pipelineKeys.Annotations, "annotations", pipelineKeys.Annotations, "also read the pipeline keys from annotations")
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return pipelineKeys.Validate()
+	}
+	cmd.PersistentFlags().StringP(outputFlag, "o", string(printers.Table), fmt.Sprintf("output format, one of %v", printers.Formats))
+	cmd.AddCommand(newListCmd())
+	cmd.AddCommand(newShowCmd())
+	cmd.AddCommand(newUpgradesCmd())
+	cmd.AddCommand(newPromotionsCmd())
+	cmd.AddCommand(newMatrixCmd())
+	cmd.AddCommand(newPromoteCmd())
+	cmd.AddCommand(newDiffCmd())
+	cmd.AddCommand(newValuesDiffCmd())
+	cmd.AddCommand(newSnapshotCmd())
+	cmd.AddCommand(newCompareCmd())
 
 	return cmd
 }
 
-func listPipelines(cl client.Client) func(*cobra.Command, []string) error {
-	return func(cmd *cobra.Command, args []string) error {
-		fmt.Println("Starting to scan for helm releases")
-		helmReleaseList := &helmv2.HelmReleaseList{}
-		err := cl.List(context.Background(), helmReleaseList, client.HasLabels([]string{pipelines.PipelineNameLabel}))
-		if err != nil {
-			return fmt.Errorf("failed to list helm releases: %w", err)
-		}
-		fmt.Printf("found %d helm releases\n", len(helmReleaseList.Items))
+// newClient creates a client for the cluster in the kubeconfig, commands
+// create the client when they run, so that commands that only read files, like
+// compare, don't need a kubeconfig.
+func newClient() (client.Client, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.QPS = kubeclientOptions.QPS
+	cfg.Burst = kubeclientOptions.Burst
 
-		helmPipelines, err := helm.ParseHelmReleasePipelines(helmReleaseList.Items)
-		if err != nil {
-			return fmt.Errorf("failed to discover pipelines: %w", err)
-		}
+	return client.New(cfg, client.Options{Scheme: scheme})
+}
 
-		for _, v := range helmPipelines {
-			for _, env := range v.Environments {
-				fmt.Printf("pipeline: %s stage: %s: %v\n", v.Name, env.Name, env.Charts)
-			}
-		}
-		return nil
+func outputFormat(cmd *cobra.Command) (printers.Format, error) {
+	v, err := cmd.Flags().GetString(outputFlag)
+	if err != nil {
+		return "", err
 	}
+
+	return printers.ParseFormat(v)
 }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/printers"
+)
+
+func newMatrixCmd() *cobra.Command {
+	var skipUpgrades bool
+	cmd := &cobra.Command{
+		Use:   "matrix <pipeline>",
+		Short: "Show the versions of each chart in each environment of a pipeline",
+		Long: `Show the versions of each chart in each environment of a pipeline.
+
+An environment that lags the preceding environment is shown as "1.0.9 < 1.0.12"
+and a newer version in the chart repository is shown as "1.0.12 (1.1.0)".`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			pipeline, err := helm.FindPipeline(ctx, cl, pipelineKeys, args[0])
+			if err != nil {
+				return err
+			}
+
+			upgrades := []helm.ChartUpgrade{}
+			if !skipUpgrades {
+				upgrades, err = helm.IdentifyUpgrades(ctx, *pipeline, cl)
+				if err != nil {
+					return fmt.Errorf("failed to identify upgrades: %w", err)
+				}
+			}
+			m := helm.NewVersionMatrix(*pipeline, helm.CalculatePromotions(*pipeline), upgrades)
+
+			return printers.PrintMatrix(os.Stdout, format, m)
+		},
+	}
+	cmd.Flags().BoolVar(&skipUpgrades, "skip-upgrades", false, "do not check the chart repositories for newer versions")
+
+	return cmd
+}
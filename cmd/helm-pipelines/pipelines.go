@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/convert"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/printers"
+)
+
+func newListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List pipelines in the cluster",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			helmPipelines, diagnostics, err := helm.DiscoverPipelines(context.Background(), cl, pipelineKeys)
+			if err != nil {
+				return err
+			}
+			if err := printers.PrintPipelines(os.Stdout, format, convert.Pipelines(helmPipelines), convert.Diagnostics(diagnostics)); err != nil {
+				return err
+			}
+			if format == printers.Table || format == printers.Wide {
+				return printers.PrintDiagnostics(os.Stderr, convert.Diagnostics(diagnostics))
+			}
+
+			return nil
+		},
+	}
+}
+
+func newShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <pipeline>",
+		Short: "Show the charts deployed to each environment in a pipeline",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			pipeline, err := helm.FindPipeline(context.Background(), cl, pipelineKeys, args[0])
+			if err != nil {
+				return err
+			}
+
+			return printers.PrintPipeline(os.Stdout, format, convert.Pipeline(*pipeline))
+		},
+	}
+}
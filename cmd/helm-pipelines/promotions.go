@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/convert"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/printers"
+)
+
+func newPromotionsCmd() *cobra.Command {
+	var includeValues bool
+	cmd := &cobra.Command{
+		Use:   "promotions <pipeline>",
+		Short: "List the promotions between the environments in a pipeline",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			pipeline, err := helm.FindPipeline(context.Background(), cl, pipelineKeys, args[0])
+			if err != nil {
+				return err
+			}
+
+			promotions := helm.CalculatePromotions(*pipeline, promotionOptions(includeValues)...)
+			return printers.PrintPromotions(os.Stdout, format, convert.Promotions(promotions))
+		},
+	}
+	cmd.Flags().BoolVar(&includeValues, "include-values", false, "treat changes to values as promotions")
+
+	return cmd
+}
+
+func newPromoteCmd() *cobra.Command {
+	var environment, chart string
+	var includeValues, dryRun bool
+	cmd := &cobra.Command{
+		Use:   "promote <pipeline>",
+		Short: "Promote the charts from the preceding environment to an environment",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			pipeline, err := helm.FindPipeline(ctx, cl, pipelineKeys, args[0])
+			if err != nil {
+				return err
+			}
+
+			promotions := filterPromotions(helm.CalculatePromotions(*pipeline, promotionOptions(includeValues)...), environment, chart)
+			if len(promotions) == 0 {
+				fmt.Fprintf(os.Stderr, "no promotions to environment %s\n", environment)
+				return nil
+			}
+			if err := printers.PrintPromotions(os.Stdout, format, convert.Promotions(promotions)); err != nil {
+				return err
+			}
+			if dryRun {
+				return nil
+			}
+
+			if err := helm.ApplyPromotions(ctx, cl, promotions); err != nil {
+				return fmt.Errorf("failed to apply promotions: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "applied %d promotions to environment %s\n", len(promotions), environment)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&environment, "to", "", "environment to promote to")
+	cmd.Flags().StringVar(&chart, "chart", "", "only promote this chart")
+	cmd.Flags().BoolVar(&includeValues, "include-values", false, "promote changes to values along with versions, replacing the values and valuesFrom of the promoted HelmReleases")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show the promotions without applying them")
+	cobra.CheckErr(cmd.MarkFlagRequired("to"))
+
+	return cmd
+}
+
+func promotionOptions(includeValues bool) []helm.PromotionOption {
+	opts := []helm.PromotionOption{}
+	if includeValues {
+		opts = append(opts, helm.WithValuesPromotions())
+	}
+
+	return opts
+}
+
+func filterPromotions(promotions []helm.Promotion, environment, chart string) []helm.Promotion {
+	filtered := []helm.Promotion{}
+	for _, promotion := range promotions {
+		if environment != "" && promotion.Environment != environment {
+			continue
+		}
+		if chart != "" && promotion.To.Name != chart {
+			continue
+		}
+		filtered = append(filtered, promotion)
+	}
+
+	return filtered
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/printers"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/snapshots"
+)
+
+func newSnapshotCmd() *cobra.Command {
+	var filename string
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Record the charts deployed to each environment of every pipeline as JSON",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			helmPipelines, diagnostics, err := helm.DiscoverPipelines(context.Background(), cl, pipelineKeys)
+			if err != nil {
+				return err
+			}
+			snapshot := snapshots.New(helmPipelines, diagnostics, time.Now())
+			if filename == "" {
+				return snapshots.Write(os.Stdout, snapshot)
+			}
+
+			f, err := os.Create(filename)
+			if err != nil {
+				return fmt.Errorf("failed to create snapshot file: %w", err)
+			}
+			if err := snapshots.Write(f, snapshot); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		},
+	}
+	cmd.Flags().StringVarP(&filename, "file", "f", "", "write the snapshot to this file rather than stdout")
+
+	return cmd
+}
+
+func newCompareCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "compare <snapshot-a> <snapshot-b>",
+		Short: "Show what changed in the pipelines between two snapshots",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if args[0] == "-" && args[1] == "-" {
+				return errors.New("only one snapshot can be read from stdin")
+			}
+			format, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			older, err := readSnapshot(args[0])
+			if err != nil {
+				return err
+			}
+			newer, err := readSnapshot(args[1])
+			if err != nil {
+				return err
+			}
+
+			return printers.PrintChanges(os.Stdout, format, snapshots.Compare(older, newer))
+		},
+	}
+}
+
+func readSnapshot(filename string) (*snapshots.Snapshot, error) {
+	var r io.Reader = os.Stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open snapshot: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	s, err := snapshots.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/convert"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/printers"
+)
+
+func newUpgradesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "upgrades <pipeline>",
+		Short: "List newer versions of the charts in a pipeline",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			pipeline, err := helm.FindPipeline(ctx, cl, pipelineKeys, args[0])
+			if err != nil {
+				return err
+			}
+
+			upgrades, err := helm.IdentifyUpgrades(ctx, *pipeline, cl)
+			if err != nil {
+				return fmt.Errorf("failed to identify upgrades: %w", err)
+			}
+
+			return printers.PrintUpgrades(os.Stdout, format, convert.Upgrades(upgrades))
+		},
+	}
+}
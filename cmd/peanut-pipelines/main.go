@@ -1,43 +1,86 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
 	"github.com/go-logr/zapr"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	kubeconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
 
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/auth"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/config"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/metrics"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/rbac"
 	"github.com/bigkevmcd/peanut-helmpipelines/pkg/server"
 )
 
 const (
-	listenFlag = "listen"
+	configFlag = "config"
 )
 
+// flagKeys maps the flags to the keys in the configuration file.
+var flagKeys = map[string]string{
+	"listen":             "listen",
+	"namespaces":         "namespaces",
+	"gateway-listen":     "gateway.listen",
+	"metrics-listen":     "metrics.listen",
+	"shutdown-timeout":   "shutdown-timeout",
+	"tls-cert-file":      "tls.cert-file",
+	"tls-key-file":       "tls.key-file",
+	"tls-client-ca-file": "tls.client-ca-file",
+	"token-auth":         "auth.token-auth",
+	"impersonate":        "auth.impersonate",
+	"log-level":          "log.level",
+	"log-format":         "log.format",
+	"pipeline-label":     "labels.pipeline",
+	"environment-label":  "labels.environment",
+	"after-label":        "labels.after",
+	"application-label":  "labels.application",
+	"annotations":        "labels.annotations",
+}
+
 var (
 	scheme = runtime.NewScheme()
 )
 
 func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(helmv2.AddToScheme(scheme))
+	utilruntime.Must(sourcev1.AddToScheme(scheme))
 	cobra.OnInitialize(initConfig)
 }
 
 func initConfig() {
-	viper.AutomaticEnv()
+	config.SetDefaults(viper.GetViper())
+	viper.SetDefault("listen", portFromEnv())
 }
 
 func makeRootCmd() *cobra.Command {
@@ -45,42 +88,37 @@ func makeRootCmd() *cobra.Command {
 		Use:   "peanut-pipelines",
 		Short: "Provides a gRPC API for parsing HelmReleases into pipelines",
 		Run: func(cmd *cobra.Command, args []string) {
-			zapLog, err := zap.NewDevelopment()
-			cobra.CheckErr(err)
-			logger := zapr.NewLogger(zapLog)
-
-			cfg, err := config.GetConfig()
-			cobra.CheckErr(err)
-
-			cl, err := client.New(cfg, client.Options{Scheme: scheme})
+			filename, err := cmd.Flags().GetString(configFlag)
 			cobra.CheckErr(err)
-
-			srv := server.NewGRPCServer(logger, cl,
-				grpc.StreamInterceptor(
-					grpc_middleware.ChainStreamServer(grpc_prometheus.StreamServerInterceptor),
-				),
-				grpc.UnaryInterceptor(
-					grpc_middleware.ChainUnaryServer(
-						grpc_prometheus.UnaryServerInterceptor,
-						grpc_zap.UnaryServerInterceptor(zapLog),
-					),
-				),
-			)
-			reflection.Register(srv)
-
-			log.Printf("Listening at %s", viper.GetString(listenFlag))
-			lis, err := net.Listen("tcp", ":"+viper.GetString(listenFlag))
+			cfg, err := config.Load(viper.GetViper(), filename)
 			cobra.CheckErr(err)
-			cobra.CheckErr(srv.Serve(lis))
+			cobra.CheckErr(run(cmd.Context(), cfg))
 		},
 	}
 
-	cmd.Flags().String(
-		listenFlag,
-		portFromEnv(),
-		"gRPC server listen port",
-	)
-	cobra.CheckErr(viper.BindPFlag(listenFlag, cmd.Flags().Lookup(listenFlag)))
+	cmd.Flags().String(configFlag, "", "path to a YAML configuration file")
+	cmd.Flags().String("listen", portFromEnv(), "gRPC server listen port")
+	cmd.Flags().StringSlice("namespaces", nil, "only discover pipelines in these namespaces, this only needs a Role in each namespace")
+	cmd.Flags().String("gateway-listen", "8081", "REST/JSON gateway listen port")
+	cmd.Flags().String("metrics-listen", "8082", "metrics and health checks listen port")
+	cmd.Flags().Duration("shutdown-timeout", 30*time.Second, "how long to wait for requests to finish when shutting down")
+	cmd.Flags().String("tls-cert-file", "", "serve TLS with the certificate in this file")
+	cmd.Flags().String("tls-key-file", "", "the key for the TLS certificate")
+	cmd.Flags().String("tls-client-ca-file", "", "require client certificates signed by a CA in this file")
+	cmd.Flags().Bool("token-auth", false, "authenticate bearer tokens with TokenReviews")
+	cmd.Flags().Bool("impersonate", false, "impersonate the authenticated user when reading resources")
+	cmd.Flags().String("log-level", "info", "log level, one of debug, info, warn or error")
+	cmd.Flags().String("log-format", "console", "log format, either json or console")
+	cmd.Flags().String("pipeline-label", pipelinelabels.DefaultKeys.Pipeline, "label that identifies the pipeline of a resource")
+	cmd.Flags().String("environment-label", pipelinelabels.DefaultKeys.Environment, "label that identifies the environment of a resource")
+	cmd.Flags().String("after-label", pipelinelabels.DefaultKeys.After, "label that identifies the environment that precedes the environment of a resource")
+	cmd.Flags().String("application-label", pipelinelabels.DefaultKeys.Application, "label that identifies the application deployed by a resource")
+	cmd.Flags().Bool("annotations", false, "also read the pipeline labels from annotations")
+	for flag, key := range flagKeys {
+		cobra.CheckErr(viper.BindPFlag(key, cmd.Flags().Lookup(flag)))
+	}
+	cmd.AddCommand(makeRBACCmd())
+
 	return cmd
 }
 
@@ -88,6 +126,168 @@ func main() {
 	cobra.CheckErr(makeRootCmd().Execute())
 }
 
+func run(ctx context.Context, cfg *config.Config) error {
+	zapLog, err := newZapLogger(cfg.Log)
+	if err != nil {
+		return err
+	}
+	logger := zapr.NewLogger(zapLog)
+
+	restConfig, err := kubeconfig.GetConfig()
+	if err != nil {
+		return err
+	}
+	cl, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	if err := rbac.CheckAccess(ctx, cl, cfg.Namespaces); err != nil {
+		return fmt.Errorf("the server can't discover pipelines, grant the permissions in deploy/role.yaml, or generate Roles with peanut-pipelines rbac: %w", err)
+	}
+	if cfg.Auth.TokenAuth {
+		if err := rbac.CheckTokenReviewAccess(ctx, cl); err != nil {
+			return fmt.Errorf("the server can't authenticate tokens, grant the permissions in deploy/role.yaml, or generate a ClusterRoleBinding with peanut-pipelines rbac --token-auth: %w", err)
+		}
+	}
+
+	streamInterceptors := []grpc.StreamServerInterceptor{grpc_prometheus.StreamServerInterceptor}
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		grpc_prometheus.UnaryServerInterceptor,
+		grpc_zap.UnaryServerInterceptor(zapLog),
+	}
+	if cfg.Auth.TokenAuth {
+		authenticator := auth.NewTokenReviewAuthenticator(cl)
+		streamInterceptors = append(streamInterceptors, auth.StreamServerInterceptor(authenticator))
+		unaryInterceptors = append(unaryInterceptors, auth.UnaryServerInterceptor(authenticator))
+	}
+	serverOpts := []server.Option{server.WithKeys(cfg.Labels.Keys()), server.WithNamespaces(cfg.Namespaces...)}
+	if cfg.Auth.Impersonate {
+		factory, err := auth.NewImpersonatingClientFactory(restConfig, client.Options{Scheme: scheme, Mapper: cl.RESTMapper()})
+		if err != nil {
+			return err
+		}
+		serverOpts = append(serverOpts, server.WithClientFactory(factory))
+	}
+	if cfg.Promotions.IncludeValues {
+		serverOpts = append(serverOpts, server.WithValuesPromotions())
+	}
+
+	unaryInterceptor := grpc_middleware.ChainUnaryServer(unaryInterceptors...)
+	grpcOpts := []grpc.ServerOption{
+		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(streamInterceptors...)),
+		grpc.UnaryInterceptor(unaryInterceptor),
+	}
+	var tlsConfig *tls.Config
+	if cfg.TLS.CertFile != "" {
+		tlsConfig, err = auth.TLSConfig(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	pipelinesServer := server.NewPipelinesServer(logger, cl, serverOpts...)
+	srv := server.NewGRPCServer(pipelinesServer, grpcOpts...)
+	hs := server.NewHealthServer(srv)
+	reflection.Register(srv)
+	grpc_prometheus.Register(srv)
+	collector := metrics.NewPipelinesCollector(logger, cl, cfg.Labels.Keys(), cfg.Namespaces, cfg.Metrics.Interval, cfg.Metrics.Timeout)
+	prometheus.MustRegister(collector)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	gateway, err := server.NewGatewayHandler(ctx, pipelinesServer, unaryInterceptor)
+	if err != nil {
+		return err
+	}
+	gatewayServer := &http.Server{
+		Addr:      ":" + cfg.Gateway.Listen,
+		Handler:   gateway,
+		TLSConfig: tlsConfig,
+	}
+	metricsServer := &http.Server{
+		Addr:    ":" + cfg.Metrics.Listen,
+		Handler: server.NewMetricsHandler(hs),
+	}
+
+	lis, err := net.Listen("tcp", ":"+cfg.Listen)
+	if err != nil {
+		return err
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		log.Printf("Listening at %s", cfg.Listen)
+		return srv.Serve(lis)
+	})
+	g.Go(func() error {
+		log.Printf("Gateway listening at %s", cfg.Gateway.Listen)
+		if tlsConfig != nil {
+			return ignoreServerClosed(gatewayServer.ListenAndServeTLS("", ""))
+		}
+		return ignoreServerClosed(gatewayServer.ListenAndServe())
+	})
+	g.Go(func() error {
+		log.Printf("Metrics listening at %s", cfg.Metrics.Listen)
+		return ignoreServerClosed(metricsServer.ListenAndServe())
+	})
+	g.Go(func() error {
+		return collector.Run(gctx)
+	})
+	g.Go(func() error {
+		<-gctx.Done()
+		log.Printf("Shutting down")
+		hs.Shutdown()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		gracefulStop(shutdownCtx, srv)
+		if err := gatewayServer.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("failed to shut down the gateway: %w", err)
+		}
+		return metricsServer.Shutdown(shutdownCtx)
+	})
+
+	return g.Wait()
+}
+
+func newZapLogger(cfg config.Log) (*zap.Logger, error) {
+	zapConfig := zap.NewDevelopmentConfig()
+	if cfg.Format == "json" {
+		zapConfig = zap.NewProductionConfig()
+	}
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level: %w", err)
+	}
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+
+	return zapConfig.Build()
+}
+
+// gracefulStop waits for the gRPC server to finish handling requests, or
+// stops it if the context is done first.
+func gracefulStop(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
+func ignoreServerClosed(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
 func portFromEnv() string {
 	if v := os.Getenv("PORT"); v != "" {
 		return v
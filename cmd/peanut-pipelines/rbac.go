@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/rbac"
+)
+
+func makeRBACCmd() *cobra.Command {
+	var (
+		name           string
+		serviceAccount client.ObjectKey
+		namespaces     []string
+		tokenAuth      bool
+	)
+	cmd := &cobra.Command{
+		Use:   "rbac",
+		Short: "Print the Roles and RoleBindings for running the server with --namespaces",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(namespaces) == 0 {
+				return errors.New("at least one namespace must be provided with --namespaces")
+			}
+			objs := rbac.NamespacedRoles(name, serviceAccount, namespaces)
+			if tokenAuth {
+				objs = append(objs, rbac.TokenReviewBinding(name, serviceAccount))
+			}
+			for i, obj := range objs {
+				b, err := yaml.Marshal(obj)
+				if err != nil {
+					return fmt.Errorf("failed to marshal %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, err)
+				}
+				if i > 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "---")
+				}
+				fmt.Fprint(cmd.OutOrStdout(), string(b))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&namespaces, "namespaces", nil, "namespaces to create the Roles in")
+	cmd.Flags().BoolVar(&tokenAuth, "token-auth", false, "also bind the system:auth-delegator ClusterRole, which is needed for --token-auth")
+	cmd.Flags().StringVar(&name, "name", "peanut-helmpipelines", "name of the Roles and RoleBindings")
+	cmd.Flags().StringVar(&serviceAccount.Name, "service-account", "peanut-helmpipelines", "name of the server's ServiceAccount")
+	cmd.Flags().StringVar(&serviceAccount.Namespace, "service-account-namespace", "default", "namespace of the server's ServiceAccount")
+
+	return cmd
+}
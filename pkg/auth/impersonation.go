@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ImpersonatedExtras are the keys of the extra information about the user
+// that are impersonated, each key must be granted as a userextras resource in
+// the impersonation ClusterRole, other extras, for example the pod-name of a
+// ServiceAccount token, are not impersonated.
+var ImpersonatedExtras = []string{"scopes"}
+
+// ImpersonatingClientFactory creates clients that impersonate the
+// authenticated user in the context, so that requests are authorized with
+// the user's own RBAC.
+//
+// A client is created for each request, the clients share the connections to
+// the API server, and the RESTMapper in the client options, so that they are
+// cheap to create, and nothing is kept for each user.
+type ImpersonatingClientFactory struct {
+	config    *rest.Config
+	options   client.Options
+	transport http.RoundTripper
+}
+
+// NewImpersonatingClientFactory creates a new ImpersonatingClientFactory.
+//
+// The config must be for a user that is allowed to impersonate users, groups
+// and ServiceAccounts, and the ImpersonatedExtras.
+func NewImpersonatingClientFactory(cfg *rest.Config, opts client.Options) (*ImpersonatingClientFactory, error) {
+	rt, err := rest.TransportFor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transport for impersonating clients: %w", err)
+	}
+
+	return &ImpersonatingClientFactory{config: cfg, options: opts, transport: rt}, nil
+}
+
+// ClientFor returns a client that impersonates the user in the context.
+func (f *ImpersonatingClientFactory) ClientFor(ctx context.Context) (client.Client, error) {
+	u := UserFromContext(ctx)
+	if u == nil {
+		return nil, errors.New("no authenticated user to impersonate")
+	}
+	impersonate := transport.ImpersonationConfig{
+		UserName: u.Name,
+		UID:      u.UID,
+		Groups:   u.Groups,
+		Extra:    impersonatedExtras(u.Extra),
+	}
+	opts := f.options
+	opts.HTTPClient = &http.Client{
+		Transport: transport.NewImpersonatingRoundTripper(impersonate, f.transport),
+		Timeout:   f.config.Timeout,
+	}
+	cl, err := client.New(f.config, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client impersonating %s: %w", u.Name, err)
+	}
+
+	return cl, nil
+}
+
+func impersonatedExtras(extra map[string][]string) map[string][]string {
+	impersonated := map[string][]string{}
+	for _, k := range ImpersonatedExtras {
+		if v, ok := extra[k]; ok {
+			impersonated[k] = v
+		}
+	}
+	if len(impersonated) == 0 {
+		return nil
+	}
+
+	return impersonated
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestImpersonatingClientFactory(t *testing.T) {
+	var headers http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"default"}}`))
+	}))
+	t.Cleanup(ts.Close)
+	f := newImpersonatingClientFactory(t, &rest.Config{Host: ts.URL})
+	ctx := WithUser(context.TODO(), &User{
+		Name:   "testing",
+		Groups: []string{"developers", "testers"},
+		Extra: map[string][]string{
+			"scopes":                                {"user:info"},
+			"authentication.kubernetes.io/pod-name": {"demo-pod"},
+		},
+	})
+
+	cl, err := f.ClientFor(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cl.Get(ctx, client.ObjectKey{Name: "default"}, &corev1.Namespace{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"Impersonate-User":         {"testing"},
+		"Impersonate-Group":        {"developers", "testers"},
+		"Impersonate-Extra-Scopes": {"user:info"},
+	}
+	got := map[string][]string{}
+	for k, v := range headers {
+		if strings.HasPrefix(k, "Impersonate-") {
+			got[k] = v
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("incorrect impersonation headers:\n%s", diff)
+	}
+}
+
+func TestImpersonatingClientFactory_client_per_request(t *testing.T) {
+	users := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		users = append(users, r.Header.Get("Impersonate-User"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"default"}}`))
+	}))
+	t.Cleanup(ts.Close)
+	f := newImpersonatingClientFactory(t, &rest.Config{Host: ts.URL})
+
+	for _, name := range []string{"testing", "other", "testing"} {
+		ctx := WithUser(context.TODO(), &User{Name: name})
+		cl, err := f.ClientFor(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.Get(ctx, client.ObjectKey{Name: "default"}, &corev1.Namespace{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if diff := cmp.Diff([]string{"testing", "other", "testing"}, users); diff != "" {
+		t.Fatalf("incorrect impersonated users:\n%s", diff)
+	}
+}
+
+func TestImpersonatingClientFactory_no_user(t *testing.T) {
+	f := newImpersonatingClientFactory(t, &rest.Config{Host: "https://kubernetes.example.com"})
+
+	_, err := f.ClientFor(context.TODO())
+	if msg := "no authenticated user to impersonate"; err == nil || err.Error() != msg {
+		t.Fatalf("got error %v, want %q", err, msg)
+	}
+}
+
+func newImpersonatingClientFactory(t *testing.T, cfg *rest.Config) *ImpersonatingClientFactory {
+	t.Helper()
+	f, err := NewImpersonatingClientFactory(cfg, newClientOptions(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func newClientOptions(t *testing.T) client.Options {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+
+	return client.Options{Scheme: scheme, Mapper: mapper}
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor authenticates the bearer token in the
+// "authorization" metadata of each request, and adds the authenticated user
+// to the context.
+func UnaryServerInterceptor(a Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := AuthenticateRequest(ctx, a, metadata.ValueFromIncomingContext(ctx, "authorization"))
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authenticates the bearer token in the
+// "authorization" metadata of each stream, and adds the authenticated user to
+// the context of the stream.
+func StreamServerInterceptor(a Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := AuthenticateRequest(ss.Context(), a, metadata.ValueFromIncomingContext(ss.Context(), "authorization"))
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// AuthenticateRequest authenticates the bearer token in the authorization
+// values from a request, and returns a copy of the context with the
+// authenticated user.
+//
+// The errors are gRPC status errors, Unauthenticated if the token is missing
+// or invalid.
+func AuthenticateRequest(ctx context.Context, a Authenticator, authorization []string) (context.Context, error) {
+	token, ok := bearerToken(authorization)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	u, err := a.Authenticate(ctx, token)
+	if errors.Is(err, ErrUnauthenticated) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to authenticate: %s", err)
+	}
+
+	return WithUser(ctx, u), nil
+}
+
+func bearerToken(values []string) (string, bool) {
+	if len(values) != 1 {
+		return "", false
+	}
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || token == "" {
+		return "", false
+	}
+
+	return token, true
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthenticateRequest(t *testing.T) {
+	a := stubAuthenticator{"valid-token": {Name: "testing"}}
+
+	authTests := []struct {
+		name          string
+		authorization []string
+		wantUser      *User
+		wantCode      codes.Code
+	}{
+		{"valid token", []string{"Bearer valid-token"}, &User{Name: "testing"}, codes.OK},
+		{"lowercase scheme", []string{"bearer valid-token"}, &User{Name: "testing"}, codes.OK},
+		{"missing header", nil, nil, codes.Unauthenticated},
+		{"basic auth", []string{"Basic dGVzdGluZzp0ZXN0aW5n"}, nil, codes.Unauthenticated},
+		{"empty token", []string{"Bearer "}, nil, codes.Unauthenticated},
+		{"invalid token", []string{"Bearer invalid-token"}, nil, codes.Unauthenticated},
+		{"authentication failure", []string{"Bearer failing-token"}, nil, codes.Unavailable},
+	}
+
+	for _, tt := range authTests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := AuthenticateRequest(context.TODO(), a, tt.authorization)
+
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("got error code %v, want %v", code, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.wantUser, UserFromContext(ctx)); diff != "" {
+				t.Fatalf("incorrect user:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(stubAuthenticator{"valid-token": {Name: "testing"}})
+	var authenticated *User
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		authenticated = UserFromContext(ctx)
+		return "ok", nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", "Bearer valid-token"))
+	if _, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&User{Name: "testing"}, authenticated); diff != "" {
+		t.Fatalf("incorrect user:\n%s", diff)
+	}
+
+	_, err := interceptor(context.TODO(), nil, &grpc.UnaryServerInfo{}, handler)
+	if code := status.Code(err); code != codes.Unauthenticated {
+		t.Fatalf("got error code %v, want %v", code, codes.Unauthenticated)
+	}
+}
+
+// stubAuthenticator authenticates the tokens in the map, "failing-token"
+// fails as if the API server could not be reached.
+type stubAuthenticator map[string]*User
+
+func (a stubAuthenticator) Authenticate(ctx context.Context, token string) (*User, error) {
+	if token == "failing-token" {
+		return nil, errors.New("connection refused")
+	}
+	u, ok := a[token]
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	return u, nil
+}
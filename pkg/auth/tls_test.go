@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTLSConfig(t *testing.T) {
+	certFile, keyFile := writeCertificate(t)
+
+	cfg, err := TLSConfig(certFile, keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ClientAuth != tls.NoClientCert {
+		t.Fatalf("got client auth %v, want %v", cfg.ClientAuth, tls.NoClientCert)
+	}
+
+	cfg, err = TLSConfig(certFile, keyFile, certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatalf("got client auth %v, want %v", cfg.ClientAuth, tls.RequireAndVerifyClientCert)
+	}
+}
+
+func TestTLSConfig_errors(t *testing.T) {
+	certFile, keyFile := writeCertificate(t)
+
+	_, err := TLSConfig(certFile, keyFile, keyFile)
+	if msg := "no certificates found in client CA file " + keyFile; err == nil || err.Error() != msg {
+		t.Fatalf("got error %v, want %q", err, msg)
+	}
+
+	_, err = TLSConfig(filepath.Join(t.TempDir(), "missing.crt"), keyFile, "")
+	if err == nil {
+		t.Fatal("expected an error loading a missing certificate")
+	}
+}
+
+// writeCertificate writes a self-signed certificate and key to temporary
+// files.
+func writeCertificate(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "peanut-pipelines"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return certFile, keyFile
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrUnauthenticated is returned when a token can't be authenticated.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authenticator authenticates bearer tokens.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*User, error)
+}
+
+// TokenReviewAuthenticator authenticates bearer tokens by creating a
+// TokenReview in the cluster, so any token accepted by the API server,
+// including ServiceAccount and OIDC tokens, can be used.
+type TokenReviewAuthenticator struct {
+	client.Client
+	// Audiences are the audiences that the token must be issued for, if empty
+	// the API server's audiences are used.
+	Audiences []string
+}
+
+// NewTokenReviewAuthenticator creates a new TokenReviewAuthenticator.
+func NewTokenReviewAuthenticator(c client.Client, audiences ...string) *TokenReviewAuthenticator {
+	return &TokenReviewAuthenticator{Client: c, Audiences: audiences}
+}
+
+// Authenticate implements the Authenticator interface.
+func (a *TokenReviewAuthenticator) Authenticate(ctx context.Context, token string) (*User, error) {
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: a.Audiences,
+		},
+	}
+	if err := a.Create(ctx, review); err != nil {
+		return nil, fmt.Errorf("failed to create TokenReview: %w", err)
+	}
+	if !review.Status.Authenticated {
+		if review.Status.Error != "" {
+			return nil, fmt.Errorf("%w: %s", ErrUnauthenticated, review.Status.Error)
+		}
+		return nil, ErrUnauthenticated
+	}
+
+	u := &User{
+		Name:   review.Status.User.Username,
+		UID:    review.Status.User.UID,
+		Groups: review.Status.User.Groups,
+	}
+	if len(review.Status.User.Extra) > 0 {
+		u.Extra = map[string][]string{}
+		for k, v := range review.Status.User.Extra {
+			u.Extra[k] = v
+		}
+	}
+
+	return u, nil
+}
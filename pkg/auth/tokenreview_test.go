@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestTokenReviewAuthenticator(t *testing.T) {
+	a := NewTokenReviewAuthenticator(newFakeClient(t, map[string]authenticationv1.TokenReviewStatus{
+		"valid-token": {
+			Authenticated: true,
+			User: authenticationv1.UserInfo{
+				Username: "system:serviceaccount:default:testing",
+				UID:      "1234",
+				Groups:   []string{"system:serviceaccounts"},
+				Extra:    map[string]authenticationv1.ExtraValue{"scopes": {"view"}},
+			},
+		},
+		"expired-token": {Error: "token has expired"},
+	}))
+
+	u, err := a.Authenticate(context.TODO(), "valid-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &User{
+		Name:   "system:serviceaccount:default:testing",
+		UID:    "1234",
+		Groups: []string{"system:serviceaccounts"},
+		Extra:  map[string][]string{"scopes": {"view"}},
+	}
+	if diff := cmp.Diff(want, u); diff != "" {
+		t.Fatalf("incorrect user:\n%s", diff)
+	}
+
+	_, err = a.Authenticate(context.TODO(), "expired-token")
+	if !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("got error %v, want %v", err, ErrUnauthenticated)
+	}
+	if msg := "unauthenticated: token has expired"; err.Error() != msg {
+		t.Fatalf("got error %q, want %q", err, msg)
+	}
+
+	_, err = a.Authenticate(context.TODO(), "unknown-token")
+	if !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("got error %v, want %v", err, ErrUnauthenticated)
+	}
+}
+
+// newFakeClient returns a client that completes TokenReviews with the status
+// for the token.
+func newFakeClient(t *testing.T, tokens map[string]authenticationv1.TokenReviewStatus) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := authenticationv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authenticationv1.TokenReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				review.Status = tokens[review.Spec.Token]
+				return nil
+			},
+		}).
+		Build()
+}
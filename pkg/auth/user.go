@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"context"
+)
+
+// User is an authenticated user.
+type User struct {
+	Name   string
+	UID    string
+	Groups []string
+	Extra  map[string][]string
+}
+
+type userKey struct{}
+
+// WithUser returns a copy of the context with the authenticated user.
+func WithUser(ctx context.Context, u *User) context.Context {
+	return context.WithValue(ctx, userKey{}, u)
+}
+
+// UserFromContext returns the authenticated user from the context, or nil if
+// the request was not authenticated.
+func UserFromContext(ctx context.Context) *User {
+	u, _ := ctx.Value(userKey{}).(*User)
+	return u
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+)
+
+// Config is the configuration for the peanut-pipelines server.
+//
+// The configuration is read from a YAML file, environment variables and
+// flags, with flags taking precedence over environment variables, and
+// environment variables over the file.
+type Config struct {
+	Listen string `mapstructure:"listen"`
+	// Namespaces restricts the server to discovering pipelines in these
+	// namespaces, if empty pipelines are discovered in every namespace.
+	Namespaces      []string      `mapstructure:"namespaces"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown-timeout"`
+	Gateway         Gateway       `mapstructure:"gateway"`
+	Metrics         Metrics       `mapstructure:"metrics"`
+	TLS             TLS           `mapstructure:"tls"`
+	Auth            Auth          `mapstructure:"auth"`
+	Log             Log           `mapstructure:"log"`
+	Promotions      Promotions    `mapstructure:"promotions"`
+	Labels          Labels        `mapstructure:"labels"`
+}
+
+// Gateway configures the REST/JSON gateway.
+type Gateway struct {
+	Listen string `mapstructure:"listen"`
+}
+
+// Metrics configures the metrics and health check listener.
+type Metrics struct {
+	Listen string `mapstructure:"listen"`
+	// Interval is how often the pipelines are discovered for the metrics,
+	// scrapes report the last discovery.
+	Interval time.Duration `mapstructure:"interval"`
+	// Timeout is how long discovering the pipelines for the metrics can take.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// TLS configures serving TLS, TLS is not served if the CertFile is empty.
+type TLS struct {
+	CertFile     string `mapstructure:"cert-file"`
+	KeyFile      string `mapstructure:"key-file"`
+	ClientCAFile string `mapstructure:"client-ca-file"`
+}
+
+// Auth configures the authentication and authorization of requests.
+type Auth struct {
+	TokenAuth   bool `mapstructure:"token-auth"`
+	Impersonate bool `mapstructure:"impersonate"`
+}
+
+// Log configures the logging.
+type Log struct {
+	// Level is one of debug, info, warn or error.
+	Level string `mapstructure:"level"`
+	// Format is either json or console.
+	Format string `mapstructure:"format"`
+}
+
+// Promotions configures the defaults for calculating promotions.
+type Promotions struct {
+	// IncludeValues treats differences in the values of a chart as
+	// promotable, for every ListPromotions request, promoting values replaces
+	// the values of the promoted HelmReleases.
+	//
+	// Requests can't turn this off, without it each request chooses with
+	// include_values.
+	IncludeValues bool `mapstructure:"include-values"`
+}
+
+// Labels configures the labels that place resources into pipelines.
+type Labels struct {
+	Pipeline    string `mapstructure:"pipeline"`
+	Environment string `mapstructure:"environment"`
+	After       string `mapstructure:"after"`
+	Application string `mapstructure:"application"`
+	// Annotations also reads the labels from annotations, which is slower
+	// because resources can't be filtered by annotations.
+	Annotations bool `mapstructure:"annotations"`
+}
+
+// Keys returns the pipelinelabels.Keys for the labels.
+func (l Labels) Keys() pipelinelabels.Keys {
+	return pipelinelabels.Keys{
+		Pipeline:    l.Pipeline,
+		Environment: l.Environment,
+		After:       l.After,
+		Application: l.Application,
+		Annotations: l.Annotations,
+	}
+}
+
+// SetDefaults sets the default configuration.
+//
+// Every key has a default so that it can be overridden from the environment.
+func SetDefaults(v *viper.Viper) {
+	v.SetDefault("listen", "8080")
+	v.SetDefault("namespaces", []string{})
+	v.SetDefault("shutdown-timeout", 30*time.Second)
+	v.SetDefault("gateway.listen", "8081")
+	v.SetDefault("metrics.listen", "8082")
+	v.SetDefault("metrics.interval", time.Minute)
+	v.SetDefault("metrics.timeout", 20*time.Second)
+	v.SetDefault("tls.cert-file", "")
+	v.SetDefault("tls.key-file", "")
+	v.SetDefault("tls.client-ca-file", "")
+	v.SetDefault("auth.token-auth", false)
+	v.SetDefault("auth.impersonate", false)
+	v.SetDefault("log.level", "info")
+	v.SetDefault("log.format", "console")
+	v.SetDefault("promotions.include-values", false)
+	v.SetDefault("labels.pipeline", pipelinelabels.DefaultKeys.Pipeline)
+	v.SetDefault("labels.environment", pipelinelabels.DefaultKeys.Environment)
+	v.SetDefault("labels.after", pipelinelabels.DefaultKeys.After)
+	v.SetDefault("labels.application", pipelinelabels.DefaultKeys.Application)
+	v.SetDefault("labels.annotations", false)
+}
+
+// EnvPrefix is the prefix of the environment variables that override the
+// configuration.
+const EnvPrefix = "PEANUT_PIPELINES"
+
+// Load reads the configuration from the file, if one is provided, and from
+// the environment, where the keys are upper-cased, "." and "-" are replaced
+// with "_" and prefixed with EnvPrefix, for example
+// PEANUT_PIPELINES_LOG_LEVEL or PEANUT_PIPELINES_TLS_CERT_FILE.
+//
+// The loaded configuration is validated.
+func Load(v *viper.Viper, filename string) (*Config, error) {
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	v.AutomaticEnv()
+	if filename != "" {
+		v.SetConfigFile(filename)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", filename, err)
+		}
+	}
+
+	cfg := &Config{}
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse configuration: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks that the configuration is complete and consistent.
+func (c *Config) Validate() error {
+	var errs []error
+	listeners := []struct{ key, value string }{
+		{"listen", c.Listen},
+		{"gateway.listen", c.Gateway.Listen},
+		{"metrics.listen", c.Metrics.Listen},
+	}
+	for _, l := range listeners {
+		if l.value == "" {
+			errs = append(errs, fmt.Errorf("%s must be provided", l.key))
+		}
+	}
+	seen := map[string]bool{}
+	for _, ns := range c.Namespaces {
+		if msgs := validation.IsDNS1123Label(ns); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("namespaces: invalid namespace %q: %s", ns, strings.Join(msgs, ", ")))
+		}
+		if seen[ns] {
+			errs = append(errs, fmt.Errorf("namespaces: duplicate namespace %q", ns))
+		}
+		seen[ns] = true
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown-timeout must be greater than zero"))
+	}
+	if c.Metrics.Interval <= 0 {
+		errs = append(errs, errors.New("metrics.interval must be greater than zero"))
+	}
+	if c.Metrics.Timeout <= 0 {
+		errs = append(errs, errors.New("metrics.timeout must be greater than zero"))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls.cert-file and tls.key-file must be provided together"))
+	}
+	if c.TLS.ClientCAFile != "" && c.TLS.CertFile == "" {
+		errs = append(errs, errors.New("tls.client-ca-file requires tls.cert-file"))
+	}
+	if c.Auth.Impersonate && !c.Auth.TokenAuth {
+		errs = append(errs, errors.New("auth.impersonate requires auth.token-auth"))
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level must be one of debug, info, warn or error, got %q", c.Log.Level))
+	}
+	switch c.Log.Format {
+	case "json", "console":
+	default:
+		errs = append(errs, fmt.Errorf("log.format must be json or console, got %q", c.Log.Format))
+	}
+	if err := c.Labels.Keys().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("labels: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/viper"
+)
+
+func TestLoad(t *testing.T) {
+	cfg, err := Load(newViper(), "testdata/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Config{
+		Listen:          "9000",
+		Namespaces:      []string{"staging", "production"},
+		ShutdownTimeout: 10 * time.Second,
+		Gateway:         Gateway{Listen: "9001"},
+		Metrics:         Metrics{Listen: "9002", Interval: 30 * time.Second, Timeout: 5 * time.Second},
+		TLS:             TLS{CertFile: "/etc/peanut-pipelines/tls.crt", KeyFile: "/etc/peanut-pipelines/tls.key"},
+		Auth:            Auth{TokenAuth: true, Impersonate: true},
+		Log:             Log{Level: "debug", Format: "json"},
+		Promotions:      Promotions{IncludeValues: true},
+		Labels: Labels{
+			Pipeline:    "app.example.com/pipeline",
+			Environment: "app.example.com/environment",
+			After:       "app.example.com/after",
+			Application: "app.example.com/application",
+			Annotations: true,
+		},
+	}
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Fatalf("failed to load configuration:\n%s", diff)
+	}
+}
+
+func TestLoad_defaults(t *testing.T) {
+	cfg, err := Load(newViper(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Config{
+		Listen:          "8080",
+		Namespaces:      []string{},
+		ShutdownTimeout: 30 * time.Second,
+		Gateway:         Gateway{Listen: "8081"},
+		Metrics:         Metrics{Listen: "8082", Interval: time.Minute, Timeout: 20 * time.Second},
+		Log:             Log{Level: "info", Format: "console"},
+		Labels: Labels{
+			Pipeline:    "gitops.pro/pipeline",
+			Environment: "gitops.pro/pipeline-environment",
+			After:       "gitops.pro/pipeline-after",
+			Application: "gitops.pro/pipeline-application",
+		},
+	}
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Fatalf("failed to load configuration:\n%s", diff)
+	}
+}
+
+func TestLoad_environment(t *testing.T) {
+	t.Setenv("PEANUT_PIPELINES_LOG_LEVEL", "warn")
+	t.Setenv("PEANUT_PIPELINES_GATEWAY_LISTEN", "9999")
+	t.Setenv("PEANUT_PIPELINES_AUTH_TOKEN_AUTH", "true")
+	t.Setenv("PEANUT_PIPELINES_NAMESPACES", "dev,test")
+	t.Setenv("LISTEN", "7777")
+
+	cfg, err := Load(newViper(), "testdata/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Log.Level != "warn" {
+		t.Errorf("got log level %q, want %q", cfg.Log.Level, "warn")
+	}
+	if cfg.Gateway.Listen != "9999" {
+		t.Errorf("got gateway listen %q, want %q", cfg.Gateway.Listen, "9999")
+	}
+	if !cfg.Auth.TokenAuth {
+		t.Error("token auth not enabled from the environment")
+	}
+	if diff := cmp.Diff([]string{"dev", "test"}, cfg.Namespaces); diff != "" {
+		t.Errorf("incorrect namespaces from the environment:\n%s", diff)
+	}
+	if cfg.Listen != "9000" {
+		t.Errorf("got listen %q from an unprefixed variable, want %q", cfg.Listen, "9000")
+	}
+}
+
+func TestLoad_errors(t *testing.T) {
+	_, err := Load(newViper(), "testdata/missing.yaml")
+	if err == nil {
+		t.Fatal("expected an error loading a missing file")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	validTests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"missing listen", func(c *Config) { c.Gateway.Listen = "" }, "gateway.listen must be provided"},
+		{"invalid namespace", func(c *Config) { c.Namespaces = []string{"Staging"} }, `namespaces: invalid namespace "Staging": a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')`},
+		{"duplicate namespace", func(c *Config) { c.Namespaces = []string{"staging", "staging"} }, `namespaces: duplicate namespace "staging"`},
+		{"zero shutdown timeout", func(c *Config) { c.ShutdownTimeout = 0 }, "shutdown-timeout must be greater than zero"},
+		{"zero metrics interval", func(c *Config) { c.Metrics.Interval = 0 }, "metrics.interval must be greater than zero"},
+		{"zero metrics timeout", func(c *Config) { c.Metrics.Timeout = 0 }, "metrics.timeout must be greater than zero"},
+		{"cert without key", func(c *Config) { c.TLS.CertFile = "tls.crt" }, "tls.cert-file and tls.key-file must be provided together"},
+		{"client CA without cert", func(c *Config) { c.TLS.ClientCAFile = "ca.crt" }, "tls.client-ca-file requires tls.cert-file"},
+		{"impersonate without token auth", func(c *Config) { c.Auth.Impersonate = true }, "auth.impersonate requires auth.token-auth"},
+		{"unknown log level", func(c *Config) { c.Log.Level = "trace" }, `log.level must be one of debug, info, warn or error, got "trace"`},
+		{"unknown log format", func(c *Config) { c.Log.Format = "text" }, `log.format must be json or console, got "text"`},
+		{"missing label", func(c *Config) { c.Labels.Pipeline = "" }, "labels: the pipeline key must be provided"},
+		{
+			"multiple errors",
+			func(c *Config) { c.Listen = ""; c.Log.Format = "text" },
+			"listen must be provided\nlog.format must be json or console, got \"text\"",
+		},
+	}
+
+	for _, tt := range validTests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(newViper(), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(cfg)
+
+			err = cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func newViper() *viper.Viper {
+	v := viper.New()
+	SetDefaults(v)
+	return v
+}
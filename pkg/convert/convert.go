@@ -0,0 +1,136 @@
+package convert
+
+import (
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+	pipelinesv1 "github.com/bigkevmcd/peanut-helmpipelines/pkg/protos/pipelines/v1"
+)
+
+// Pipelines converts parsed pipelines to their API representation.
+func Pipelines(hp []helm.HelmReleasePipeline) []*pipelinesv1.Pipeline {
+	result := []*pipelinesv1.Pipeline{}
+	for _, v := range hp {
+		result = append(result, Pipeline(v))
+	}
+	return result
+}
+
+// Pipeline converts a parsed pipeline to its API representation.
+func Pipeline(p helm.HelmReleasePipeline) *pipelinesv1.Pipeline {
+	return &pipelinesv1.Pipeline{
+		Name:         p.Name,
+		Environments: environments(p),
+	}
+}
+
+// Promotions converts calculated promotions to their API representation.
+func Promotions(proms []helm.Promotion) []*pipelinesv1.Promotion {
+	result := []*pipelinesv1.Promotion{}
+	for _, p := range proms {
+		result = append(result, &pipelinesv1.Promotion{
+			Environment:      p.Environment,
+			From:             Chart(p.From),
+			To:               Chart(p.To),
+			PromotedReleases: references(p.PromotedReleases),
+			PromoteValues:    p.PromoteValues,
+		})
+	}
+	return result
+}
+
+// Upgrades converts identified chart upgrades to their API representation.
+func Upgrades(upgrades []helm.ChartUpgrade) []*pipelinesv1.ChartUpgrade {
+	result := []*pipelinesv1.ChartUpgrade{}
+	for _, u := range upgrades {
+		result = append(result, &pipelinesv1.ChartUpgrade{
+			Current:   Chart(u.Current),
+			Available: Chart(u.Available),
+		})
+	}
+	return result
+}
+
+// Diagnostics converts pipeline diagnostics to their API representation.
+func Diagnostics(diagnostics []helm.Diagnostic) []*pipelinesv1.Diagnostic {
+	result := []*pipelinesv1.Diagnostic{}
+	for _, d := range diagnostics {
+		pd := &pipelinesv1.Diagnostic{
+			Pipeline:    d.Pipeline,
+			Environment: d.Environment,
+			Reason:      string(d.Reason),
+			Message:     d.Message,
+		}
+		if d.HelmRelease.Name != "" {
+			pd.HelmRelease = Reference(d.HelmRelease)
+		}
+		result = append(result, pd)
+	}
+	return result
+}
+
+// Chart converts a chart to its API representation.
+func Chart(c helm.HelmReleaseChart) *pipelinesv1.Pipeline_Environment_HelmChart {
+	return &pipelinesv1.Pipeline_Environment_HelmChart{
+		Application: c.Application,
+		Name:        c.Name,
+		Version:     c.Version,
+		Source:      Reference(c.Source),
+	}
+}
+
+// Reference converts an object reference to its API representation.
+func Reference(r helmv2.CrossNamespaceObjectReference) *pipelinesv1.CrossNamespaceObjectReference {
+	return &pipelinesv1.CrossNamespaceObjectReference{
+		Kind:      r.Kind,
+		Namespace: r.Namespace,
+		Name:      r.Name,
+	}
+}
+
+func environments(p helm.HelmReleasePipeline) []*pipelinesv1.Pipeline_Environment {
+	result := []*pipelinesv1.Pipeline_Environment{}
+	for _, ev := range p.Environments {
+		pe := &pipelinesv1.Pipeline_Environment{Name: ev.Name, After: ev.After}
+		for _, c := range ev.Charts {
+			pc := Chart(c)
+			pc.Releases = releaseStatuses(p, ev.Name, c)
+			pe.Charts = append(pe.Charts, pc)
+		}
+		result = append(result, pe)
+	}
+	return result
+}
+
+// releaseStatuses converts the status of the HelmReleases that deploy the
+// chart in the environment.
+func releaseStatuses(p helm.HelmReleasePipeline, env string, c helm.HelmReleaseChart) []*pipelinesv1.HelmReleaseStatus {
+	var result []*pipelinesv1.HelmReleaseStatus
+	for _, r := range p.ChartHelmReleases[c] {
+		status, ok := p.Releases[r]
+		if !ok || status.Environment != env {
+			continue
+		}
+		rs := &pipelinesv1.HelmReleaseStatus{
+			HelmRelease:         Reference(r),
+			Ready:               string(status.Ready),
+			LastAppliedRevision: status.LastAppliedRevision,
+			FailureMessage:      status.Message,
+			ValuesDigest:        status.ValuesDigest,
+		}
+		if !status.ReadyTransitionTime.IsZero() {
+			rs.ReadyTransitionTime = timestamppb.New(status.ReadyTransitionTime)
+		}
+		result = append(result, rs)
+	}
+	return result
+}
+
+func references(refs []helmv2.CrossNamespaceObjectReference) []*pipelinesv1.CrossNamespaceObjectReference {
+	result := []*pipelinesv1.CrossNamespaceObjectReference{}
+	for _, r := range refs {
+		result = append(result, Reference(r))
+	}
+	return result
+}
@@ -0,0 +1,178 @@
+package convert
+
+import (
+	"testing"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+	pipelinesv1 "github.com/bigkevmcd/peanut-helmpipelines/pkg/protos/pipelines/v1"
+)
+
+var (
+	testSource = helmv2.CrossNamespaceObjectReference{Kind: "HelmRepository", Namespace: "default", Name: "test-repository"}
+	apiSource  = &pipelinesv1.CrossNamespaceObjectReference{Kind: "HelmRepository", Namespace: "default", Name: "test-repository"}
+)
+
+func TestPipelines(t *testing.T) {
+	pipelines := []helm.HelmReleasePipeline{
+		{
+			Name: "demo-pipeline",
+			Environments: []helm.HelmReleaseEnvironment{
+				{
+					Name: "staging",
+					Charts: []helm.HelmReleaseChart{
+						{Name: "redis", Version: "1.0.9", Source: testSource},
+					},
+				},
+				{
+					Name:   "production",
+					After:  []string{"staging"},
+					Charts: []helm.HelmReleaseChart{{Name: "redis", Version: "1.0.9", Source: testSource}},
+				},
+			},
+		},
+	}
+
+	want := []*pipelinesv1.Pipeline{
+		{
+			Name: "demo-pipeline",
+			Environments: []*pipelinesv1.Pipeline_Environment{
+				{
+					Name: "staging",
+					Charts: []*pipelinesv1.Pipeline_Environment_HelmChart{
+						{Name: "redis", Version: "1.0.9", Source: apiSource},
+					},
+				},
+				{
+					Name:   "production",
+					After:  []string{"staging"},
+					Charts: []*pipelinesv1.Pipeline_Environment_HelmChart{{Name: "redis", Version: "1.0.9", Source: apiSource}},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, Pipelines(pipelines), protocmp.Transform()); diff != "" {
+		t.Fatalf("failed to convert pipelines:\n%s", diff)
+	}
+}
+
+func TestPipeline_release_status(t *testing.T) {
+	transitionTime := time.Date(2022, time.June, 28, 7, 46, 20, 0, time.UTC)
+	chart := helm.HelmReleaseChart{Name: "redis", Version: "1.0.9", Source: testSource}
+	staging := helmv2.CrossNamespaceObjectReference{Kind: "HelmRelease", Namespace: "staging", Name: "redis"}
+	production := helmv2.CrossNamespaceObjectReference{Kind: "HelmRelease", Namespace: "production", Name: "redis"}
+	pipeline := helm.HelmReleasePipeline{
+		Name: "demo-pipeline",
+		Environments: []helm.HelmReleaseEnvironment{
+			{Name: "staging", Charts: []helm.HelmReleaseChart{chart}},
+			{Name: "production", After: []string{"staging"}, Charts: []helm.HelmReleaseChart{chart}},
+		},
+		ChartHelmReleases: map[helm.HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+			chart: {production, staging},
+		},
+		Releases: map[helmv2.CrossNamespaceObjectReference]helm.HelmReleaseStatus{
+			staging: {
+				Environment: "staging", Ready: metav1.ConditionTrue, LastAppliedRevision: "1.0.9", ReadyTransitionTime: transitionTime,
+				ValuesDigest: "sha256:test",
+			},
+			production: {
+				Environment: "production", Ready: metav1.ConditionFalse, ReadyTransitionTime: transitionTime, Message: "upgrade retries exhausted",
+			},
+		},
+	}
+
+	want := &pipelinesv1.Pipeline{
+		Name: "demo-pipeline",
+		Environments: []*pipelinesv1.Pipeline_Environment{
+			{
+				Name: "staging",
+				Charts: []*pipelinesv1.Pipeline_Environment_HelmChart{
+					{
+						Name: "redis", Version: "1.0.9", Source: apiSource,
+						Releases: []*pipelinesv1.HelmReleaseStatus{
+							{
+								HelmRelease:         &pipelinesv1.CrossNamespaceObjectReference{Kind: "HelmRelease", Namespace: "staging", Name: "redis"},
+								Ready:               "True",
+								LastAppliedRevision: "1.0.9",
+								ReadyTransitionTime: timestamppb.New(transitionTime),
+								ValuesDigest:        "sha256:test",
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:  "production",
+				After: []string{"staging"},
+				Charts: []*pipelinesv1.Pipeline_Environment_HelmChart{
+					{
+						Name: "redis", Version: "1.0.9", Source: apiSource,
+						Releases: []*pipelinesv1.HelmReleaseStatus{
+							{
+								HelmRelease:         &pipelinesv1.CrossNamespaceObjectReference{Kind: "HelmRelease", Namespace: "production", Name: "redis"},
+								Ready:               "False",
+								ReadyTransitionTime: timestamppb.New(transitionTime),
+								FailureMessage:      "upgrade retries exhausted",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, Pipeline(pipeline), protocmp.Transform()); diff != "" {
+		t.Fatalf("failed to convert pipeline:\n%s", diff)
+	}
+}
+
+func TestPromotions(t *testing.T) {
+	promotions := []helm.Promotion{
+		{
+			Environment: "production",
+			From:        helm.HelmReleaseChart{Name: "redis", Version: "1.0.9", Source: testSource},
+			To:          helm.HelmReleaseChart{Name: "redis", Version: "1.0.12", Source: testSource},
+			PromotedReleases: []helmv2.CrossNamespaceObjectReference{
+				{Kind: "HelmRelease", Namespace: "production", Name: "redis"},
+			},
+		},
+	}
+
+	want := []*pipelinesv1.Promotion{
+		{
+			Environment: "production",
+			From:        &pipelinesv1.Pipeline_Environment_HelmChart{Name: "redis", Version: "1.0.9", Source: apiSource},
+			To:          &pipelinesv1.Pipeline_Environment_HelmChart{Name: "redis", Version: "1.0.12", Source: apiSource},
+			PromotedReleases: []*pipelinesv1.CrossNamespaceObjectReference{
+				{Kind: "HelmRelease", Namespace: "production", Name: "redis"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, Promotions(promotions), protocmp.Transform()); diff != "" {
+		t.Fatalf("failed to convert promotions:\n%s", diff)
+	}
+}
+
+func TestUpgrades(t *testing.T) {
+	upgrades := []helm.ChartUpgrade{
+		{
+			Current:   helm.HelmReleaseChart{Name: "redis", Version: "1.0.9", Source: testSource},
+			Available: helm.HelmReleaseChart{Name: "redis", Version: "1.0.12", Source: testSource},
+		},
+	}
+
+	want := []*pipelinesv1.ChartUpgrade{
+		{
+			Current:   &pipelinesv1.Pipeline_Environment_HelmChart{Name: "redis", Version: "1.0.9", Source: apiSource},
+			Available: &pipelinesv1.Pipeline_Environment_HelmChart{Name: "redis", Version: "1.0.12", Source: apiSource},
+		},
+	}
+	if diff := cmp.Diff(want, Upgrades(upgrades), protocmp.Transform()); diff != "" {
+		t.Fatalf("failed to convert upgrades:\n%s", diff)
+	}
+}
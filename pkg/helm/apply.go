@@ -2,8 +2,10 @@ package helm
 
 import (
 	"context"
+	"fmt"
 
-	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -11,15 +13,45 @@ import (
 //
 // For each promotion, the "To" version will be applied to the HelmReleases
 // identified as requiring update for that version.
+//
+// If the promotion includes values, the values and valuesFrom of the promoted
+// HelmReleases are replaced with the values and valuesFrom of the source
+// HelmReleases, values that are only set in the environment being promoted to
+// are removed. The source HelmReleases have the same values, so they're read
+// from the first.
+//
+// HelmReleases that use a chartRef are promoted by updating the version of
+// the referenced OCIRepository or HelmChart.
 func ApplyPromotions(ctx context.Context, cl client.Client, proms []Promotion) error {
 	for _, promotion := range proms {
+		var source *helmv2.HelmRelease
+		if promotion.PromoteValues && len(promotion.SourceReleases) > 0 {
+			source = &helmv2.HelmRelease{}
+			if err := cl.Get(ctx, keyFromCrossNamespaceObject(promotion.SourceReleases[0]), source); err != nil {
+				return err
+			}
+		}
 		for _, cno := range promotion.PromotedReleases {
 			hr := &helmv2.HelmRelease{}
 			if err := cl.Get(ctx, keyFromCrossNamespaceObject(cno), hr); err != nil {
 				// TODO: better error?
 				return err
 			}
-			hr.Spec.Chart.Spec.Version = promotion.To.Version
+			if hr.HasChartRef() {
+				if err := promoteChartRef(ctx, cl, hr, promotion.To.Version); err != nil {
+					return err
+				}
+				// The HelmRelease only changes if the values are promoted.
+				if source == nil {
+					continue
+				}
+			} else {
+				hr.Spec.Chart.Spec.Version = promotion.To.Version
+			}
+			if source != nil {
+				hr.Spec.Values = source.Spec.Values
+				hr.Spec.ValuesFrom = source.Spec.ValuesFrom
+			}
 			if err := cl.Update(ctx, hr); err != nil {
 				return err
 			}
@@ -29,6 +61,38 @@ func ApplyPromotions(ctx context.Context, cl client.Client, proms []Promotion) e
 	return nil
 }
 
+// promoteChartRef updates the version of the OCIRepository or HelmChart
+// referenced by the HelmRelease.
+func promoteChartRef(ctx context.Context, cl client.Client, hr *helmv2.HelmRelease, version string) error {
+	key := chartRefKey(hr)
+	switch hr.Spec.ChartRef.Kind {
+	case sourcev1.OCIRepositoryKind:
+		repo := &sourcev1.OCIRepository{}
+		if err := cl.Get(ctx, key, repo); err != nil {
+			return fmt.Errorf("failed to get %s %s: %w", sourcev1.OCIRepositoryKind, key, err)
+		}
+		if err := setOCIVersion(repo, version); err != nil {
+			return err
+		}
+		if err := cl.Update(ctx, repo); err != nil {
+			return fmt.Errorf("failed to update %s %s: %w", sourcev1.OCIRepositoryKind, key, err)
+		}
+	case sourcev1.HelmChartKind:
+		chart := &sourcev1.HelmChart{}
+		if err := cl.Get(ctx, key, chart); err != nil {
+			return fmt.Errorf("failed to get %s %s: %w", sourcev1.HelmChartKind, key, err)
+		}
+		chart.Spec.Version = version
+		if err := cl.Update(ctx, chart); err != nil {
+			return fmt.Errorf("failed to update %s %s: %w", sourcev1.HelmChartKind, key, err)
+		}
+	default:
+		return fmt.Errorf("HelmRelease %s/%s references an unsupported %s", hr.GetNamespace(), hr.GetName(), hr.Spec.ChartRef.Kind)
+	}
+
+	return nil
+}
+
 func keyFromCrossNamespaceObject(obj helmv2.CrossNamespaceObjectReference) client.ObjectKey {
 	return client.ObjectKey{
 		Name:      obj.Name,
@@ -4,8 +4,9 @@ import (
 	"context"
 	"testing"
 
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
 	"github.com/bigkevmcd/peanut-helmpipelines/test"
-	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
 	"github.com/google/go-cmp/cmp"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -15,7 +16,7 @@ func TestApplyPromotions(t *testing.T) {
 		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging"), test.ChartVersion("redis", "1.0.12")),
 		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production"), test.ChartVersion("redis", "1.0.9")),
 	}
-	pipelines, err := ParseHelmReleasePipelines(items)
+	pipelines, _, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, items)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -48,3 +49,62 @@ func TestApplyPromotions(t *testing.T) {
 		t.Fatalf("failed to apply promotions:\n%s", diff)
 	}
 }
+
+func TestApplyPromotions_does_not_update_other_pipelines(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging"), test.ChartVersion("redis", "1.0.12")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production"), test.ChartVersion("redis", "1.0.9")),
+		test.NewHelmRelease(test.InPipeline("another-pipeline", "production", ""), test.Named("other-deploy", "production"), test.ChartVersion("redis", "1.0.9")),
+	}
+	pipelines, _, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name := pipelines[1].Name; name != "demo-pipeline" {
+		t.Fatalf("got pipeline %q, want %q", name, "demo-pipeline")
+	}
+	promotions := CalculatePromotions(pipelines[1])
+	fc := newFakeClient(t, releasesToRuntimeObjects(items)...)
+
+	if err := ApplyPromotions(context.TODO(), fc, promotions); err != nil {
+		t.Fatal(err)
+	}
+
+	other := helmv2.HelmRelease{}
+	if err := fc.Get(context.TODO(), client.ObjectKeyFromObject(&items[2]), &other); err != nil {
+		t.Fatal(err)
+	}
+	if v := other.Spec.Chart.Spec.Version; v != "1.0.9" {
+		t.Fatalf("HelmRelease in other pipeline updated to %q", v)
+	}
+}
+
+func TestApplyPromotions_values(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging"),
+			test.ChartVersion("redis", "1.0.12"), test.Values(`{"replicas":3}`)),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production"),
+			test.ChartVersion("redis", "1.0.12"), test.Values(`{"replicas":1}`)),
+	}
+	pipelines, _, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promotions := CalculatePromotions(pipelines[0], WithValuesPromotions())
+	fc := newFakeClient(t, releasesToRuntimeObjects(items)...)
+
+	if err := ApplyPromotions(context.TODO(), fc, promotions); err != nil {
+		t.Fatal(err)
+	}
+
+	updated := helmv2.HelmRelease{}
+	if err := fc.Get(context.TODO(), client.ObjectKeyFromObject(&items[1]), &updated); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"replicas": float64(3)}
+	if diff := cmp.Diff(want, updated.GetValues()); diff != "" {
+		t.Fatalf("failed to apply promotions:\n%s", diff)
+	}
+}
@@ -0,0 +1,208 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+)
+
+// UnsupportedChartRefReason is reported for HelmReleases with a chartRef to
+// a kind of source that charts can't be read from.
+const UnsupportedChartRefReason DiagnosticReason = "UnsupportedChartRef"
+
+// ResolveChartRefs returns copies of the HelmReleases where the releases that
+// reference an OCIRepository or HelmChart with spec.chartRef have a chart
+// template with the chart, version and source from the referenced resource.
+//
+// Charts from an OCIRepository are named from the last element of the
+// repository URL, the version is the digest or tag of the reference, or the
+// tag that a semver range was resolved to, and the source has the
+// OCIRepository kind and the URL as the name, with no namespace, so that the
+// same chart is matched in each environment.
+//
+// Charts from a HelmChart have the source of the HelmChart, with the
+// namespace of the HelmChart only if it is not in the namespace of the
+// HelmRelease, in the same way as the sourceRef of a chart template.
+//
+// HelmReleases that reference a missing resource, or a resource that can't be
+// read, are left out, and reported as diagnostics.
+func ResolveChartRefs(ctx context.Context, cl client.Client, keys pipelinelabels.Keys, releases []helmv2.HelmRelease) ([]helmv2.HelmRelease, []Diagnostic, error) {
+	resolved := []helmv2.HelmRelease{}
+	diagnostics := []Diagnostic{}
+	for i := range releases {
+		if !releases[i].HasChartRef() || keys.PipelineName(&releases[i]) == "" {
+			resolved = append(resolved, releases[i])
+			continue
+		}
+		hr := releases[i].DeepCopy()
+		ref := chartRefKey(hr)
+		diagnostic := Diagnostic{
+			Pipeline:    keys.PipelineName(hr),
+			Environment: keys.EnvironmentName(hr),
+			HelmRelease: objectReferenceFromObject(hr),
+		}
+		spec, err := resolveChartRef(ctx, cl, hr.Spec.ChartRef.Kind, ref, hr.GetNamespace())
+		switch {
+		case apierrors.IsNotFound(err):
+			diagnostic.Reason = MissingSourceReason
+			diagnostic.Message = fmt.Sprintf("%s %s/%s not found", hr.Spec.ChartRef.Kind, ref.Namespace, ref.Name)
+			diagnostics = append(diagnostics, diagnostic)
+			continue
+		case ctx.Err() != nil:
+			return nil, nil, ctx.Err()
+		case err != nil:
+			diagnostic.Reason = SourceUnavailableReason
+			diagnostic.Message = err.Error()
+			diagnostics = append(diagnostics, diagnostic)
+			continue
+		case spec == nil:
+			diagnostic.Reason = UnsupportedChartRefReason
+			diagnostic.Message = fmt.Sprintf("charts can't be read from a %s", hr.Spec.ChartRef.Kind)
+			diagnostics = append(diagnostics, diagnostic)
+			continue
+		}
+		hr.Spec.Chart = &helmv2.HelmChartTemplate{Spec: *spec}
+		resolved = append(resolved, *hr)
+	}
+
+	return resolved, diagnostics, nil
+}
+
+// resolveChartRef returns the chart template for the referenced resource, or
+// nil if charts can't be read from the kind of resource.
+func resolveChartRef(ctx context.Context, cl client.Client, kind string, key client.ObjectKey, releaseNamespace string) (*helmv2.HelmChartTemplateSpec, error) {
+	switch kind {
+	case sourcev1.OCIRepositoryKind:
+		repo := &sourcev1.OCIRepository{}
+		if err := cl.Get(ctx, key, repo); err != nil {
+			return nil, fmt.Errorf("failed to get %s %s: %w", kind, key, err)
+		}
+		return &helmv2.HelmChartTemplateSpec{
+			Chart:   path.Base(repo.Spec.URL),
+			Version: ociVersion(repo),
+			SourceRef: helmv2.CrossNamespaceObjectReference{
+				Kind: sourcev1.OCIRepositoryKind,
+				Name: repo.Spec.URL,
+			},
+		}, nil
+	case sourcev1.HelmChartKind:
+		chart := &sourcev1.HelmChart{}
+		if err := cl.Get(ctx, key, chart); err != nil {
+			return nil, fmt.Errorf("failed to get %s %s: %w", kind, key, err)
+		}
+		spec := &helmv2.HelmChartTemplateSpec{
+			Chart:   chart.Spec.Chart,
+			Version: chart.Spec.Version,
+			SourceRef: helmv2.CrossNamespaceObjectReference{
+				APIVersion: chart.Spec.SourceRef.APIVersion,
+				Kind:       chart.Spec.SourceRef.Kind,
+				Name:       chart.Spec.SourceRef.Name,
+			},
+		}
+		if ns := chart.GetNamespace(); ns != releaseNamespace {
+			spec.SourceRef.Namespace = ns
+		}
+		return spec, nil
+	}
+
+	return nil, nil
+}
+
+// ociVersion returns the version of the artifact that is pulled for the
+// reference of the OCIRepository, following the precedence of the
+// source-controller.
+//
+// A semver range is resolved to the tag of the artifact in the status, the
+// range is returned if no artifact has been pulled.
+func ociVersion(repo *sourcev1.OCIRepository) string {
+	ref := repo.Spec.Reference
+	switch {
+	case ref == nil:
+		return "latest"
+	case ref.Digest != "":
+		return ref.Digest
+	case ref.SemVer != "":
+		if tag := artifactTag(repo); tag != "" {
+			return tag
+		}
+		return ref.SemVer
+	case ref.Tag != "":
+		return ref.Tag
+	}
+
+	return "latest"
+}
+
+// artifactTag returns the tag from the revision of the artifact of the
+// OCIRepository, which is "<tag>@<digest>", or "<tag>/<digest>" in older
+// versions of the source-controller.
+func artifactTag(repo *sourcev1.OCIRepository) string {
+	if repo.Status.Artifact == nil {
+		return ""
+	}
+	revision := repo.Status.Artifact.Revision
+	if tag, _, ok := strings.Cut(revision, "@"); ok {
+		return tag
+	}
+	tag, _, _ := strings.Cut(revision, "/")
+
+	return tag
+}
+
+// setOCIVersion updates the reference of the OCIRepository to pull the
+// version, a semver range is replaced with the version if the repository
+// uses a semver range.
+//
+// The version can't be a semver range, which is the version of an
+// OCIRepository that hasn't resolved its range yet.
+func setOCIVersion(repo *sourcev1.OCIRepository, version string) error {
+	if isSemVerRange(version) {
+		return fmt.Errorf("can't promote %s %s/%s to the unresolved semver range %q", sourcev1.OCIRepositoryKind, repo.GetNamespace(), repo.GetName(), version)
+	}
+	ref := repo.Spec.Reference
+	if ref == nil {
+		ref = &sourcev1.OCIRepositoryRef{}
+	}
+	switch {
+	case strings.HasPrefix(version, "sha256:"):
+		ref.Digest = version
+	case ref.Digest == "" && ref.SemVer != "":
+		ref.SemVer = version
+	default:
+		*ref = sourcev1.OCIRepositoryRef{Tag: version}
+	}
+	repo.Spec.Reference = ref
+
+	return nil
+}
+
+// isSemVerRange returns true if the version is a semver constraint, rather
+// than a version.
+func isSemVerRange(version string) bool {
+	if _, err := semver.NewVersion(version); err == nil {
+		return false
+	}
+	_, err := semver.NewConstraint(version)
+
+	return err == nil
+}
+
+// chartRefKey returns the key of the resource referenced by the chartRef of
+// the HelmRelease, the namespace defaults to the namespace of the release.
+func chartRefKey(hr *helmv2.HelmRelease) client.ObjectKey {
+	key := client.ObjectKey{Name: hr.Spec.ChartRef.Name, Namespace: hr.Spec.ChartRef.Namespace}
+	if key.Namespace == "" {
+		key.Namespace = hr.GetNamespace()
+	}
+
+	return key
+}
@@ -0,0 +1,250 @@
+package helm
+
+import (
+	"context"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	apiv1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+	"github.com/bigkevmcd/peanut-helmpipelines/test"
+)
+
+const podinfoURL = "oci://ghcr.io/stefanprodan/charts/podinfo"
+
+func TestDiscoverPipelines_chart_refs(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging"),
+			test.ChartRef("OCIRepository", "", "podinfo")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production"),
+			test.ChartRef("OCIRepository", "", "podinfo")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("redis-deploy", "staging"),
+			test.ChartRef("HelmChart", "charts", "redis")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("missing-deploy", "production"),
+			test.ChartRef("HelmChart", "", "missing")),
+	}
+	objs := append(releasesToRuntimeObjects(items),
+		test.NewOCIRepository(podinfoURL, "6.1.6", test.Named("podinfo", "staging")),
+		test.NewOCIRepository(podinfoURL, "6.1.5", test.Named("podinfo", "production")),
+		test.NewHelmChart("redis", "1.0.9", test.Named("redis", "charts")),
+	)
+	fc := newFakeClient(t, objs...)
+
+	discovered, diagnostics, err := DiscoverPipelines(context.TODO(), fc, pipelinelabels.DefaultKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ociSource := helmv2.CrossNamespaceObjectReference{Kind: "OCIRepository", Name: podinfoURL}
+	redis := HelmReleaseChart{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "charts", "test-repository")}
+	want := []HelmReleaseEnvironment{
+		{
+			Name: "staging",
+			Charts: []HelmReleaseChart{
+				{Name: "podinfo", Version: "6.1.6", Source: ociSource},
+				redis,
+			},
+		},
+		{
+			Name:   "production",
+			After:  []string{"staging"},
+			Charts: []HelmReleaseChart{{Name: "podinfo", Version: "6.1.5", Source: ociSource}},
+		},
+	}
+	if diff := cmp.Diff(want, discovered[0].Environments, cmpopts.SortSlices(func(x, y HelmReleaseChart) bool { return x.Name < y.Name })); diff != "" {
+		t.Fatalf("failed to discover pipelines:\n%s", diff)
+	}
+
+	promotions := CalculatePromotions(discovered[0])
+	if l := len(promotions); l != 1 {
+		t.Fatalf("got %d promotions, want 1", l)
+	}
+	if v := promotions[0].To.Version; v != "6.1.6" {
+		t.Fatalf("got promotion to %q, want %q", v, "6.1.6")
+	}
+
+	wantDiagnostics := []Diagnostic{
+		{
+			Pipeline: "demo-pipeline", Environment: "production",
+			HelmRelease: helmReleaseRef("missing-deploy", "production"),
+			Reason:      MissingSourceReason,
+			Message:     "HelmChart production/missing not found",
+		},
+		{
+			Pipeline: "demo-pipeline", Environment: "staging",
+			HelmRelease: helmReleaseRef("redis-deploy", "staging"),
+			Reason:      MissingSourceReason,
+			Message:     "HelmRepository charts/test-repository not found",
+		},
+	}
+	if diff := cmp.Diff(wantDiagnostics, diagnostics); diff != "" {
+		t.Fatalf("incorrect diagnostics:\n%s", diff)
+	}
+}
+
+func TestApplyPromotions_chart_refs(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging"),
+			test.ChartRef("OCIRepository", "", "podinfo")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production"),
+			test.ChartRef("OCIRepository", "", "podinfo")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("redis-staging", "staging"),
+			test.ChartRef("HelmChart", "charts", "redis-staging")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("redis-production", "production"),
+			test.ChartRef("HelmChart", "charts", "redis-production")),
+	}
+	production := test.NewOCIRepository(podinfoURL, "", test.Named("podinfo", "production"))
+	production.Spec.Reference = &sourcev1.OCIRepositoryRef{SemVer: "6.1.x"}
+	objs := append(releasesToRuntimeObjects(items),
+		test.NewOCIRepository(podinfoURL, "6.1.6", test.Named("podinfo", "staging")), production,
+		test.NewHelmChart("redis", "1.0.12", test.Named("redis-staging", "charts")),
+		test.NewHelmChart("redis", "1.0.9", test.Named("redis-production", "charts")),
+	)
+	fc := newFakeClient(t, objs...)
+	pipeline, err := FindPipeline(context.TODO(), fc, pipelinelabels.DefaultKeys, "demo-pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ApplyPromotions(context.TODO(), fc, CalculatePromotions(*pipeline)); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &sourcev1.OCIRepository{}
+	if err := fc.Get(context.TODO(), client.ObjectKeyFromObject(production), repo); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&sourcev1.OCIRepositoryRef{SemVer: "6.1.6"}, repo.Spec.Reference); diff != "" {
+		t.Fatalf("failed to promote OCIRepository:\n%s", diff)
+	}
+	chart := &sourcev1.HelmChart{}
+	if err := fc.Get(context.TODO(), client.ObjectKey{Name: "redis-production", Namespace: "charts"}, chart); err != nil {
+		t.Fatal(err)
+	}
+	if v := chart.Spec.Version; v != "1.0.12" {
+		t.Fatalf("got HelmChart version %q, want %q", v, "1.0.12")
+	}
+}
+
+func TestSetOCIVersion(t *testing.T) {
+	versionTests := []struct {
+		name    string
+		ref     *sourcev1.OCIRepositoryRef
+		version string
+		want    *sourcev1.OCIRepositoryRef
+	}{
+		{"no reference", nil, "6.1.6", &sourcev1.OCIRepositoryRef{Tag: "6.1.6"}},
+		{"tag", &sourcev1.OCIRepositoryRef{Tag: "6.1.5"}, "6.1.6", &sourcev1.OCIRepositoryRef{Tag: "6.1.6"}},
+		{"semver range", &sourcev1.OCIRepositoryRef{SemVer: "6.1.x"}, "6.1.6", &sourcev1.OCIRepositoryRef{SemVer: "6.1.6"}},
+		{"digest to tag", &sourcev1.OCIRepositoryRef{Digest: "sha256:abc"}, "6.1.6", &sourcev1.OCIRepositoryRef{Tag: "6.1.6"}},
+		{"digest", &sourcev1.OCIRepositoryRef{Tag: "6.1.5"}, "sha256:def", &sourcev1.OCIRepositoryRef{Tag: "6.1.5", Digest: "sha256:def"}},
+	}
+
+	for _, tt := range versionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := test.NewOCIRepository(podinfoURL, "")
+			repo.Spec.Reference = tt.ref
+
+			if err := setOCIVersion(repo, tt.version); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.want, repo.Spec.Reference); diff != "" {
+				t.Fatalf("failed to set version:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetOCIVersion_semver_range(t *testing.T) {
+	repo := test.NewOCIRepository(podinfoURL, "6.1.5", test.Named("podinfo", "production"))
+
+	err := setOCIVersion(repo, ">=6.0.0")
+
+	want := `can't promote OCIRepository production/podinfo to the unresolved semver range ">=6.0.0"`
+	if err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+}
+
+func TestApplyPromotions_chart_refs_resolved_semver_range(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging"),
+			test.ChartRef("OCIRepository", "", "podinfo")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production"),
+			test.ChartRef("OCIRepository", "", "podinfo")),
+	}
+	staging := test.NewOCIRepository(podinfoURL, "", test.Named("podinfo", "staging"))
+	staging.Spec.Reference = &sourcev1.OCIRepositoryRef{SemVer: ">=6.0.0"}
+	staging.Status.Artifact = &apiv1.Artifact{Revision: "6.1.6@sha256:0a1b2c"}
+	production := test.NewOCIRepository(podinfoURL, "6.1.5", test.Named("podinfo", "production"))
+	fc := newFakeClient(t, append(releasesToRuntimeObjects(items), staging, production)...)
+	pipeline, err := FindPipeline(context.TODO(), fc, pipelinelabels.DefaultKeys, "demo-pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promotions := CalculatePromotions(*pipeline)
+	if l := len(promotions); l != 1 {
+		t.Fatalf("got %d promotions, want 1", l)
+	}
+	if v := promotions[0].To.Version; v != "6.1.6" {
+		t.Fatalf("got promotion to %q, want %q", v, "6.1.6")
+	}
+	if err := ApplyPromotions(context.TODO(), fc, promotions); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &sourcev1.OCIRepository{}
+	if err := fc.Get(context.TODO(), client.ObjectKeyFromObject(production), repo); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&sourcev1.OCIRepositoryRef{Tag: "6.1.6"}, repo.Spec.Reference); diff != "" {
+		t.Fatalf("failed to promote OCIRepository:\n%s", diff)
+	}
+}
+
+func TestApplyPromotions_chart_refs_in_environment_namespaces(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging"),
+			test.ChartRef("HelmChart", "", "redis")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production"),
+			test.ChartRef("HelmChart", "", "redis")),
+	}
+	objs := append(releasesToRuntimeObjects(items),
+		test.NewHelmChart("redis", "1.0.12", test.Named("redis", "staging")),
+		test.NewHelmChart("redis", "1.0.9", test.Named("redis", "production")),
+		&sourcev1.HelmRepository{ObjectMeta: metav1.ObjectMeta{Name: "test-repository", Namespace: "staging"}},
+		&sourcev1.HelmRepository{ObjectMeta: metav1.ObjectMeta{Name: "test-repository", Namespace: "production"}},
+	)
+	fc := newFakeClient(t, objs...)
+	discovered, diagnostics, err := DiscoverPipelines(context.TODO(), fc, pipelinelabels.DefaultKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics) != 0 {
+		t.Fatalf("got diagnostics %v, want none", diagnostics)
+	}
+
+	promotions := CalculatePromotions(discovered[0])
+	if l := len(promotions); l != 1 {
+		t.Fatalf("got %d promotions, want 1", l)
+	}
+	if err := ApplyPromotions(context.TODO(), fc, promotions); err != nil {
+		t.Fatal(err)
+	}
+
+	chart := &sourcev1.HelmChart{}
+	if err := fc.Get(context.TODO(), client.ObjectKey{Name: "redis", Namespace: "production"}, chart); err != nil {
+		t.Fatal(err)
+	}
+	if v := chart.Spec.Version; v != "1.0.12" {
+		t.Fatalf("got HelmChart version %q, want %q", v, "1.0.12")
+	}
+}
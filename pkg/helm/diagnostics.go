@@ -0,0 +1,258 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/gitops-tools/pkg/sets"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+)
+
+// DiagnosticReason identifies the problem that a Diagnostic reports.
+type DiagnosticReason string
+
+const (
+	// MissingEnvironmentReason is reported for HelmReleases that are labelled
+	// with a pipeline, but not an environment.
+	MissingEnvironmentReason DiagnosticReason = "MissingEnvironment"
+	// UnknownAfterEnvironmentReason is reported for HelmReleases that are
+	// labelled as following an environment that is not in the pipeline.
+	UnknownAfterEnvironmentReason DiagnosticReason = "UnknownAfterEnvironment"
+	// UnwatchedAfterEnvironmentReason is reported when discovering pipelines
+	// in a set of namespaces, for HelmReleases that are labelled as following
+	// an environment with no HelmReleases in those namespaces, the
+	// HelmReleases are treated as being in the first environment.
+	UnwatchedAfterEnvironmentReason DiagnosticReason = "UnwatchedAfterEnvironment"
+	// DuplicateEnvironmentReason is reported for HelmReleases in an
+	// environment that is labelled as both following another environment, and
+	// as being the first environment.
+	DuplicateEnvironmentReason DiagnosticReason = "DuplicateEnvironment"
+	// EnvironmentCycleReason is reported when the environments in a pipeline
+	// follow each other in a cycle.
+	EnvironmentCycleReason DiagnosticReason = "EnvironmentCycle"
+	// MissingSourceReason is reported for HelmReleases that reference a
+	// source that does not exist.
+	MissingSourceReason DiagnosticReason = "MissingSource"
+	// SourceUnavailableReason is reported for HelmReleases that reference a
+	// source that can't be read, for example because access is forbidden.
+	SourceUnavailableReason DiagnosticReason = "SourceUnavailable"
+)
+
+// Diagnostic describes a problem with the configuration of a pipeline.
+//
+// Pipelines with problems in the ordering of their environments are not
+// returned, HelmReleases that can't be placed in an environment are left out
+// of their pipeline, and environments that follow an unwatched environment
+// start their pipeline.
+type Diagnostic struct {
+	Pipeline    string
+	Environment string
+	// HelmRelease is the release with the problem, this is empty for
+	// problems with the pipeline as a whole.
+	HelmRelease helmv2.CrossNamespaceObjectReference
+	Reason      DiagnosticReason
+	Message     string
+}
+
+// validatePipelineReleases checks the pipeline labels on the HelmReleases and
+// returns the releases that can be placed into valid pipelines, and
+// diagnostics for the rest.
+func validatePipelineReleases(keys pipelinelabels.Keys, releases []helmv2.HelmRelease) ([]helmv2.HelmRelease, []Diagnostic) {
+	diagnostics := []Diagnostic{}
+	pipelineReleases := map[string][]helmv2.HelmRelease{}
+	for _, hr := range releases {
+		pipeline := keys.PipelineName(&hr)
+		if pipeline == "" {
+			continue
+		}
+		if keys.EnvironmentName(&hr) == "" {
+			diagnostics = append(diagnostics, Diagnostic{
+				Pipeline:    pipeline,
+				HelmRelease: objectReferenceFromObject(&hr),
+				Reason:      MissingEnvironmentReason,
+				Message:     fmt.Sprintf("HelmRelease %s/%s has no %s label", hr.GetNamespace(), hr.GetName(), keys.Environment),
+			})
+			continue
+		}
+		pipelineReleases[pipeline] = append(pipelineReleases[pipeline], hr)
+	}
+
+	names := []string{}
+	for name := range pipelineReleases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	valid := []helmv2.HelmRelease{}
+	for _, name := range names {
+		pipelineDiagnostics := validateEnvironments(keys, name, pipelineReleases[name])
+		if len(pipelineDiagnostics) > 0 {
+			diagnostics = append(diagnostics, pipelineDiagnostics...)
+			continue
+		}
+		valid = append(valid, pipelineReleases[name]...)
+	}
+
+	return valid, diagnostics
+}
+
+// validateEnvironments checks that the environments of the releases in a
+// pipeline can be ordered.
+func validateEnvironments(keys pipelinelabels.Keys, pipeline string, releases []helmv2.HelmRelease) []Diagnostic {
+	graph := parseEnvironmentGraphs(keys, releases)[0].environments
+
+	diagnostics := []Diagnostic{}
+	for _, hr := range releases {
+		env, after := keys.EnvironmentName(&hr), keys.AfterEnvironment(&hr)
+		switch {
+		case graph[env].Has("") && graph[env].Len() > 1:
+			diagnostics = append(diagnostics, Diagnostic{
+				Pipeline:    pipeline,
+				Environment: env,
+				HelmRelease: objectReferenceFromObject(&hr),
+				Reason:      DuplicateEnvironmentReason,
+				Message:     fmt.Sprintf("environment %q is labelled as both following and not following another environment", env),
+			})
+		case after != "" && graph[after] == nil:
+			diagnostics = append(diagnostics, Diagnostic{
+				Pipeline:    pipeline,
+				Environment: env,
+				HelmRelease: objectReferenceFromObject(&hr),
+				Reason:      UnknownAfterEnvironmentReason,
+				Message:     fmt.Sprintf("environment %q follows unknown environment %q", env, after),
+			})
+		}
+	}
+	if len(diagnostics) > 0 {
+		return diagnostics
+	}
+
+	if cycle := findEnvironmentCycle(graph); cycle != nil {
+		diagnostics = append(diagnostics, Diagnostic{
+			Pipeline: pipeline,
+			Reason:   EnvironmentCycleReason,
+			Message:  fmt.Sprintf("environments form a cycle: %s", strings.Join(cycle, " -> ")),
+		})
+	}
+
+	return diagnostics
+}
+
+// findEnvironmentCycle follows the environments that each environment is
+// labelled as following, and returns the environments in the first cycle
+// found.
+func findEnvironmentCycle(g environmentGraph) []string {
+	visited := sets.New[string]()
+	var visit func(env string, path []string) []string
+	visit = func(env string, path []string) []string {
+		for i := range path {
+			if path[i] == env {
+				return append(append([]string{}, path[i:]...), env)
+			}
+		}
+		if visited.Has(env) {
+			return nil
+		}
+		visited.Insert(env)
+		path = append(path, env)
+		for _, after := range environmentsAfter(g[env]) {
+			if cycle := visit(after, path); cycle != nil {
+				return cycle
+			}
+		}
+
+		return nil
+	}
+
+	for _, env := range sortedKeys(g) {
+		if cycle := visit(env, nil); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// sourceDiagnostics reports the HelmReleases that reference a source that
+// does not exist, or that can't be read.
+//
+// If namespaces is not nil, sources in other namespaces are not checked.
+func sourceDiagnostics(ctx context.Context, cl client.Client, keys pipelinelabels.Keys, releases []helmv2.HelmRelease, namespaces sets.Set[string]) ([]Diagnostic, error) {
+	diagnostics := []Diagnostic{}
+	checked := map[helmv2.CrossNamespaceObjectReference]error{}
+	for _, hr := range releases {
+		if keys.PipelineName(&hr) == "" || hr.Spec.Chart == nil {
+			continue
+		}
+		ref := hr.Spec.Chart.Spec.SourceRef
+		if ref.Namespace == "" {
+			ref.Namespace = hr.GetNamespace()
+		}
+		if namespaces != nil && !namespaces.Has(ref.Namespace) {
+			continue
+		}
+		err, ok := checked[ref]
+		if !ok {
+			err = getSource(ctx, cl, ref)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			checked[ref] = err
+		}
+		if err == nil {
+			continue
+		}
+		diagnostic := Diagnostic{
+			Pipeline:    keys.PipelineName(&hr),
+			Environment: keys.EnvironmentName(&hr),
+			HelmRelease: objectReferenceFromObject(&hr),
+			Reason:      SourceUnavailableReason,
+			Message:     err.Error(),
+		}
+		if apierrors.IsNotFound(err) {
+			diagnostic.Reason = MissingSourceReason
+			diagnostic.Message = fmt.Sprintf("%s %s/%s not found", ref.Kind, ref.Namespace, ref.Name)
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	return diagnostics, nil
+}
+
+// getSource gets the referenced source, to check that it can be read, other
+// kinds of source are not checked.
+func getSource(ctx context.Context, cl client.Client, ref helmv2.CrossNamespaceObjectReference) error {
+	var source client.Object
+	switch ref.Kind {
+	case sourcev1.HelmRepositoryKind:
+		source = &sourcev1.HelmRepository{}
+	case sourcev1.GitRepositoryKind:
+		source = &sourcev1.GitRepository{}
+	case sourcev1.BucketKind:
+		source = &sourcev1.Bucket{}
+	default:
+		return nil
+	}
+	if err := cl.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: ref.Namespace}, source); err != nil {
+		return fmt.Errorf("failed to get %s %s/%s: %w", ref.Kind, ref.Namespace, ref.Name, err)
+	}
+
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
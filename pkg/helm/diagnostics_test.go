@@ -0,0 +1,239 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/google/go-cmp/cmp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+	"github.com/bigkevmcd/peanut-helmpipelines/test"
+)
+
+func TestParseHelmReleasePipelines_diagnostics(t *testing.T) {
+	diagnosticTests := []struct {
+		name          string
+		items         []helmv2.HelmRelease
+		wantPipelines []string
+		want          []Diagnostic
+	}{
+		{
+			name: "valid pipeline",
+			items: []helmv2.HelmRelease{
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging")),
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production")),
+			},
+			wantPipelines: []string{"demo-pipeline"},
+			want:          []Diagnostic{},
+		},
+		{
+			name: "missing environment label",
+			items: []helmv2.HelmRelease{
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging")),
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "", ""), test.Named("unplaced-deploy", "staging")),
+			},
+			wantPipelines: []string{"demo-pipeline"},
+			want: []Diagnostic{
+				{
+					Pipeline:    "demo-pipeline",
+					HelmRelease: helmReleaseRef("unplaced-deploy", "staging"),
+					Reason:      MissingEnvironmentReason,
+					Message:     "HelmRelease staging/unplaced-deploy has no gitops.pro/pipeline-environment label",
+				},
+			},
+		},
+		{
+			name: "unknown after environment",
+			items: []helmv2.HelmRelease{
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging")),
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "testing"), test.Named("production-deploy", "production")),
+				test.NewHelmRelease(test.InPipeline("other-pipeline", "staging", ""), test.Named("other-deploy", "other")),
+			},
+			wantPipelines: []string{"other-pipeline"},
+			want: []Diagnostic{
+				{
+					Pipeline:    "demo-pipeline",
+					Environment: "production",
+					HelmRelease: helmReleaseRef("production-deploy", "production"),
+					Reason:      UnknownAfterEnvironmentReason,
+					Message:     `environment "production" follows unknown environment "testing"`,
+				},
+			},
+		},
+		{
+			name: "duplicate environments",
+			items: []helmv2.HelmRelease{
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging")),
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production")),
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", "production"), test.Named("other-deploy", "staging")),
+			},
+			wantPipelines: []string{},
+			want: []Diagnostic{
+				{
+					Pipeline:    "demo-pipeline",
+					Environment: "staging",
+					HelmRelease: helmReleaseRef("staging-deploy", "staging"),
+					Reason:      DuplicateEnvironmentReason,
+					Message:     `environment "staging" is labelled as both following and not following another environment`,
+				},
+				{
+					Pipeline:    "demo-pipeline",
+					Environment: "staging",
+					HelmRelease: helmReleaseRef("other-deploy", "staging"),
+					Reason:      DuplicateEnvironmentReason,
+					Message:     `environment "staging" is labelled as both following and not following another environment`,
+				},
+			},
+		},
+		{
+			name: "environment cycle",
+			items: []helmv2.HelmRelease{
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", "production"), test.Named("staging-deploy", "staging")),
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production")),
+			},
+			wantPipelines: []string{},
+			want: []Diagnostic{
+				{
+					Pipeline: "demo-pipeline",
+					Reason:   EnvironmentCycleReason,
+					Message:  "environments form a cycle: production -> staging -> production",
+				},
+			},
+		},
+		{
+			name: "environment cycle through a fan-in",
+			items: []helmv2.HelmRelease{
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "dev", ""), test.Named("dev-deploy", "dev")),
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", "dev"), test.Named("staging-deploy", "staging")),
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", "production"), test.Named("other-deploy", "staging")),
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production")),
+			},
+			wantPipelines: []string{},
+			want: []Diagnostic{
+				{
+					Pipeline: "demo-pipeline",
+					Reason:   EnvironmentCycleReason,
+					Message:  "environments form a cycle: production -> staging -> production",
+				},
+			},
+		},
+	}
+
+	for _, tt := range diagnosticTests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps, diagnostics, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, tt.items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, p := range ps {
+				names = append(names, p.Name)
+			}
+			if diff := cmp.Diff(tt.wantPipelines, names); diff != "" {
+				t.Fatalf("failed to parse pipelines:\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.want, diagnostics); diff != "" {
+				t.Fatalf("failed to report diagnostics:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiscoverPipelines_missing_source(t *testing.T) {
+	repository := newHelmRepository("https://example.com")
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production"),
+			test.ChartSource("HelmRepository", repository.Namespace, repository.Name)),
+	}
+	fc := newFakeClient(t, append(releasesToRuntimeObjects(items), repository)...)
+
+	_, diagnostics, err := DiscoverPipelines(context.TODO(), fc, pipelinelabels.DefaultKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Diagnostic{
+		{
+			Pipeline:    "demo-pipeline",
+			Environment: "staging",
+			HelmRelease: helmReleaseRef("staging-deploy", "staging"),
+			Reason:      MissingSourceReason,
+			Message:     "HelmRepository default/test-repository not found",
+		},
+	}
+	if diff := cmp.Diff(want, diagnostics); diff != "" {
+		t.Fatalf("failed to report diagnostics:\n%s", diff)
+	}
+}
+
+func TestDiscoverPipelines_unavailable_source(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production"),
+			test.ChartRef("OCIRepository", "", "podinfo")),
+	}
+	cl := forbiddenSourcesClient{Client: newFakeClient(t, releasesToRuntimeObjects(items)...)}
+
+	discovered, diagnostics, err := DiscoverPipelines(context.TODO(), cl, pipelinelabels.DefaultKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(discovered); l != 1 {
+		t.Fatalf("got %d pipelines, want 1", l)
+	}
+	want := []Diagnostic{
+		{
+			Pipeline:    "demo-pipeline",
+			Environment: "production",
+			HelmRelease: helmReleaseRef("production-deploy", "production"),
+			Reason:      SourceUnavailableReason,
+			Message:     `failed to get OCIRepository production/podinfo: ocirepositories.source.toolkit.fluxcd.io "podinfo" is forbidden: RBAC denied`,
+		},
+		{
+			Pipeline:    "demo-pipeline",
+			Environment: "staging",
+			HelmRelease: helmReleaseRef("staging-deploy", "staging"),
+			Reason:      SourceUnavailableReason,
+			Message:     `failed to get HelmRepository default/test-repository: helmrepositories.source.toolkit.fluxcd.io "test-repository" is forbidden: RBAC denied`,
+		},
+	}
+	if diff := cmp.Diff(want, diagnostics); diff != "" {
+		t.Fatalf("failed to report diagnostics:\n%s", diff)
+	}
+}
+
+// forbiddenSourcesClient fails to get sources as if RBAC denied access.
+type forbiddenSourcesClient struct {
+	client.Client
+}
+
+func (c forbiddenSourcesClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return err
+	}
+	if gvk.Group != sourcev1.GroupVersion.Group {
+		return c.Client.Get(ctx, key, obj, opts...)
+	}
+	resource, _ := meta.UnsafeGuessKindToResource(gvk)
+
+	return apierrors.NewForbidden(resource.GroupResource(), key.Name, errors.New("RBAC denied"))
+}
+
+func helmReleaseRef(name, namespace string) helmv2.CrossNamespaceObjectReference {
+	return helmv2.CrossNamespaceObjectReference{
+		APIVersion: "source.toolkit.fluxcd.io/v1beta2",
+		Kind:       "HelmRelease",
+		Name:       name,
+		Namespace:  namespace,
+	}
+}
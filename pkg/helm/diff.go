@@ -0,0 +1,195 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/pmezard/go-difflib/difflib"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// PromotionDiff is the difference between the charts in a Promotion.
+type PromotionDiff struct {
+	// ManifestDiff is a unified diff of the manifests rendered by the From
+	// and To charts.
+	ManifestDiff string
+	// ValuesDiff is a unified diff of the default values in the From and To
+	// charts.
+	ValuesDiff string
+}
+
+// DiffPromotion renders the From and To charts in a Promotion and returns the
+// differences between them.
+//
+// Both charts are rendered with the values from the first of the promoted
+// HelmReleases, so the diff reflects what would change in the environment
+// being promoted to.
+func DiffPromotion(ctx context.Context, c client.Client, p Promotion) (*PromotionDiff, error) {
+	release, err := loadPromotedRelease(ctx, c, p)
+	if err != nil {
+		return nil, err
+	}
+
+	hr, err := loadHelmRepository(ctx, p.To, c)
+	if err != nil {
+		return nil, err
+	}
+	index, err := getChartIndex(ctx, p.To, c)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := fetchChart(hr.Spec.URL, index, p.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := fetchChart(hr.Spec.URL, index, p.To)
+	if err != nil {
+		return nil, err
+	}
+
+	fromManifests, err := renderChart(from, release)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart %s: %w", chartFileName(p.From), err)
+	}
+	toManifests, err := renderChart(to, release)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart %s: %w", chartFileName(p.To), err)
+	}
+
+	fromValues, err := yaml.Marshal(from.Values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal values for chart %s: %w", chartFileName(p.From), err)
+	}
+	toValues, err := yaml.Marshal(to.Values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal values for chart %s: %w", chartFileName(p.To), err)
+	}
+
+	manifestDiff, err := unifiedDiff(fromManifests, toManifests, chartFileName(p.From), chartFileName(p.To))
+	if err != nil {
+		return nil, err
+	}
+	valuesDiff, err := unifiedDiff(string(fromValues), string(toValues), chartFileName(p.From), chartFileName(p.To))
+	if err != nil {
+		return nil, err
+	}
+
+	return &PromotionDiff{ManifestDiff: manifestDiff, ValuesDiff: valuesDiff}, nil
+}
+
+// loadPromotedRelease loads the first of the promoted releases, if there are
+// none, the charts are rendered with only their default values.
+func loadPromotedRelease(ctx context.Context, c client.Client, p Promotion) (*helmv2.HelmRelease, error) {
+	if len(p.PromotedReleases) == 0 {
+		return nil, nil
+	}
+	hr := &helmv2.HelmRelease{}
+	if err := c.Get(ctx, keyFromCrossNamespaceObject(p.PromotedReleases[0]), hr); err != nil {
+		return nil, fmt.Errorf("failed to load HelmRelease %s/%s: %w", p.PromotedReleases[0].Namespace, p.PromotedReleases[0].Name, err)
+	}
+
+	return hr, nil
+}
+
+func fetchChart(repoURL string, index *repo.IndexFile, c HelmReleaseChart) (*chart.Chart, error) {
+	cv, err := index.Get(c.Name, c.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find chart %s in index: %w", chartFileName(c), err)
+	}
+	if len(cv.URLs) == 0 {
+		return nil, fmt.Errorf("no URLs for chart %s in index", chartFileName(c))
+	}
+	chartURL, err := repo.ResolveReferenceURL(repoURL, cv.URLs[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve URL for chart %s: %w", chartFileName(c), err)
+	}
+	b, err := fetchURL(chartURL)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chart %s: %w", chartFileName(c), err)
+	}
+
+	loaded, err := loader.LoadArchive(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart %s: %w", chartFileName(c), err)
+	}
+
+	return loaded, nil
+}
+
+// renderChart renders the chart templates into a single stream of manifests,
+// sorted by the template name so that the output is stable.
+func renderChart(c *chart.Chart, hr *helmv2.HelmRelease) (string, error) {
+	options := chartutil.ReleaseOptions{Name: c.Name(), Namespace: "default", IsInstall: true}
+	var values map[string]interface{}
+	if hr != nil {
+		options.Name = hr.GetReleaseName()
+		options.Namespace = hr.GetReleaseNamespace()
+		values = hr.GetValues()
+	}
+
+	renderValues, err := chartutil.ToRenderValues(c, values, options, chartutil.DefaultCapabilities)
+	if err != nil {
+		return "", err
+	}
+	rendered, err := engine.Render(c, renderValues)
+	if err != nil {
+		return "", err
+	}
+
+	names := []string{}
+	for k, v := range rendered {
+		if strings.TrimSpace(v) == "" || path.Base(k) == "NOTES.txt" {
+			continue
+		}
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "---\n# Source: %s\n%s\n", name, strings.TrimSpace(rendered[name]))
+	}
+
+	return b.String(), nil
+}
+
+func unifiedDiff(a, b, fromFile, toFile string) (string, error) {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(a),
+		B:        splitLines(b),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate diff: %w", err)
+	}
+
+	return diff, nil
+}
+
+// difflib.SplitLines adds an empty line to text with a trailing newline.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+func chartFileName(c HelmReleaseChart) string {
+	return c.Name + "-" + c.Version
+}
@@ -0,0 +1,98 @@
+package helm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/test"
+)
+
+func TestDiffPromotion(t *testing.T) {
+	testServer := httptest.NewServer(http.FileServer(http.Dir("testdata/diff-charts")))
+	defer testServer.Close()
+
+	source := sourceRef("HelmRepository", "testing", "testing")
+	hr := test.NewHelmRelease(test.Named("test-service", "production"),
+		test.ChartVersion("test-service", "1.0.1"),
+		test.Values(`{"replicaCount":3}`))
+	promotion := Promotion{
+		Environment: "production",
+		From:        HelmReleaseChart{Name: "test-service", Version: "1.0.1", Source: source},
+		To:          HelmReleaseChart{Name: "test-service", Version: "1.1.2", Source: source},
+		PromotedReleases: []helmv2.CrossNamespaceObjectReference{
+			{Kind: "HelmRelease", Name: "test-service", Namespace: "production"},
+		},
+	}
+
+	diff, err := DiffPromotion(context.TODO(), newFakeClient(t, newHelmRepository(testServer.URL), &hr), promotion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &PromotionDiff{
+		ManifestDiff: `--- test-service-1.0.1
++++ test-service-1.1.2
+@@ -6,5 +6,6 @@
+   name: test-service-config
+   namespace: production
+ data:
+-  image: "example/test-service:1.0.1"
++  image: "example/test-service:1.1.2"
+   replicas: "3"
++  logLevel: "info"
+`,
+		ValuesDiff: `--- test-service-1.0.1
++++ test-service-1.1.2
+@@ -1,4 +1,5 @@
+ image:
+   repository: example/test-service
+-  tag: 1.0.1
++  tag: 1.1.2
++logLevel: info
+ replicaCount: 1
+`,
+	}
+	if diff := cmp.Diff(want, diff); diff != "" {
+		t.Fatalf("failed to diff promotion:\n%s", diff)
+	}
+}
+
+func TestDiffPromotion_missing_version(t *testing.T) {
+	testServer := httptest.NewServer(http.FileServer(http.Dir("testdata/diff-charts")))
+	defer testServer.Close()
+
+	source := sourceRef("HelmRepository", "testing", "testing")
+	promotion := Promotion{
+		Environment: "production",
+		From:        HelmReleaseChart{Name: "test-service", Version: "1.0.0", Source: source},
+		To:          HelmReleaseChart{Name: "test-service", Version: "1.1.2", Source: source},
+	}
+
+	_, err := DiffPromotion(context.TODO(), newFakeClient(t, newHelmRepository(testServer.URL)), promotion)
+	if msg := "failed to find chart test-service-1.0.0 in index"; err == nil || !strings.HasPrefix(err.Error(), msg) {
+		t.Fatalf("got error %v, want %q", err, msg)
+	}
+}
+
+func newHelmRepository(serverURL string) *sourcev1.HelmRepository {
+	return &sourcev1.HelmRepository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "testing",
+			Namespace: "testing",
+		},
+		Spec: sourcev1.HelmRepositorySpec{
+			URL: serverURL,
+		},
+		Status: sourcev1.HelmRepositoryStatus{
+			URL: serverURL + "/index.yaml",
+		},
+	}
+}
@@ -0,0 +1,268 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/gitops-tools/pkg/sets"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+)
+
+// DiscoverPipelines lists the HelmReleases that are labelled as being in a
+// pipeline, or are in a Namespace that is labelled as being in a pipeline, and
+// parses them into pipelines.
+//
+// Problems with the labelling of the HelmReleases, and HelmReleases that
+// reference missing sources are returned as diagnostics.
+//
+// The keys identify the labels, or annotations, that place the HelmReleases
+// and Namespaces into pipelines.
+func DiscoverPipelines(ctx context.Context, cl client.Client, keys pipelinelabels.Keys) ([]HelmReleasePipeline, []Diagnostic, error) {
+	namespaces, err := listPipelineNamespaces(ctx, cl, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	helmReleaseList := &helmv2.HelmReleaseList{}
+	if err := cl.List(ctx, helmReleaseList, pipelineListOptions(keys)...); err != nil {
+		return nil, nil, fmt.Errorf("failed to list helm releases: %w", err)
+	}
+	releases := helmReleaseList.Items
+	for _, ns := range namespaces {
+		nsReleaseList := &helmv2.HelmReleaseList{}
+		if err := cl.List(ctx, nsReleaseList, client.InNamespace(ns.GetName())); err != nil {
+			return nil, nil, fmt.Errorf("failed to list helm releases in namespace %s: %w", ns.GetName(), err)
+		}
+		releases = appendMissingReleases(releases, nsReleaseList.Items)
+	}
+
+	return parseDiscoveredReleases(ctx, cl, keys, InheritNamespaceLabels(keys, releases, namespaces), nil)
+}
+
+// DiscoverNamespacedPipelines discovers the pipelines from the HelmReleases
+// in the namespaces, without reading any cluster-scoped resources, so that it
+// only needs permissions granted by a Role in each namespace.
+//
+// The labels on the Namespaces are not read, so the HelmReleases must be
+// labelled, and sources outside of the namespaces are not checked.
+//
+// Environments that follow an environment with no HelmReleases in the
+// namespaces are reported as following an unwatched environment, and start
+// their pipeline.
+//
+// If no namespaces are provided, this is the same as DiscoverPipelines.
+func DiscoverNamespacedPipelines(ctx context.Context, cl client.Client, keys pipelinelabels.Keys, namespaces []string) ([]HelmReleasePipeline, []Diagnostic, error) {
+	if len(namespaces) == 0 {
+		return DiscoverPipelines(ctx, cl, keys)
+	}
+
+	return discoverNamespacedPipelines(ctx, cl, keys, namespaces, nil)
+}
+
+// DiscoverPipelinesInNamespace discovers the pipelines from the HelmReleases
+// in a single namespace, like DiscoverNamespacedPipelines, so that it only
+// needs permissions granted by a Role in the namespace.
+//
+// The Namespace is also read, so that the HelmReleases in it inherit its
+// pipeline labels, but if the client can't get the Namespace, the HelmReleases
+// must be labelled.
+func DiscoverPipelinesInNamespace(ctx context.Context, cl client.Client, keys pipelinelabels.Keys, namespace string) ([]HelmReleasePipeline, []Diagnostic, error) {
+	pipelineNamespaces := []corev1.Namespace{}
+	ns := &corev1.Namespace{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		if !apierrors.IsForbidden(err) && !apierrors.IsNotFound(err) {
+			return nil, nil, fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+		}
+	} else if keys.PipelineName(ns) != "" {
+		pipelineNamespaces = append(pipelineNamespaces, *ns)
+	}
+
+	return discoverNamespacedPipelines(ctx, cl, keys, []string{namespace}, pipelineNamespaces)
+}
+
+// discoverNamespacedPipelines discovers the pipelines from the HelmReleases in
+// the namespaces, every HelmRelease in the pipelineNamespaces is listed, and
+// inherits the pipeline labels of its Namespace.
+func discoverNamespacedPipelines(ctx context.Context, cl client.Client, keys pipelinelabels.Keys, namespaces []string, pipelineNamespaces []corev1.Namespace) ([]HelmReleasePipeline, []Diagnostic, error) {
+	inPipeline := sets.New[string]()
+	for i := range pipelineNamespaces {
+		inPipeline.Insert(pipelineNamespaces[i].GetName())
+	}
+
+	releases := []helmv2.HelmRelease{}
+	for _, ns := range namespaces {
+		nsReleaseList := &helmv2.HelmReleaseList{}
+		opts := []client.ListOption{client.InNamespace(ns)}
+		if !inPipeline.Has(ns) {
+			opts = append(opts, pipelineListOptions(keys)...)
+		}
+		if err := cl.List(ctx, nsReleaseList, opts...); err != nil {
+			return nil, nil, fmt.Errorf("failed to list helm releases in namespace %s: %w", ns, err)
+		}
+		releases = append(releases, nsReleaseList.Items...)
+	}
+	releases, unwatched := removeUnwatchedAfterEnvironments(keys, InheritNamespaceLabels(keys, releases, pipelineNamespaces))
+	helmPipelines, diagnostics, err := parseDiscoveredReleases(ctx, cl, keys, releases, sets.New(namespaces...))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return helmPipelines, append(unwatched, diagnostics...), nil
+}
+
+// removeUnwatchedAfterEnvironments returns copies of the HelmReleases without
+// the after label, or annotation, where the environment they follow has no
+// HelmReleases in the pipeline, and diagnostics for each of them.
+func removeUnwatchedAfterEnvironments(keys pipelinelabels.Keys, releases []helmv2.HelmRelease) ([]helmv2.HelmRelease, []Diagnostic) {
+	environments := map[string]sets.Set[string]{}
+	for i := range releases {
+		pipeline := keys.PipelineName(&releases[i])
+		if environments[pipeline] == nil {
+			environments[pipeline] = sets.New[string]()
+		}
+		environments[pipeline].Insert(keys.EnvironmentName(&releases[i]))
+	}
+
+	diagnostics := []Diagnostic{}
+	watched := make([]helmv2.HelmRelease, len(releases))
+	for i := range releases {
+		hr := releases[i].DeepCopy()
+		pipeline, env, after := keys.PipelineName(hr), keys.EnvironmentName(hr), keys.AfterEnvironment(hr)
+		if after != "" && env != "" && !environments[pipeline].Has(after) {
+			delete(hr.Labels, keys.After)
+			delete(hr.Annotations, keys.After)
+			diagnostics = append(diagnostics, Diagnostic{
+				Pipeline:    pipeline,
+				Environment: env,
+				HelmRelease: objectReferenceFromObject(hr),
+				Reason:      UnwatchedAfterEnvironmentReason,
+				Message:     fmt.Sprintf("environment %q follows environment %q which has no HelmReleases in the watched namespaces", env, after),
+			})
+		}
+		watched[i] = *hr
+	}
+
+	return watched, diagnostics
+}
+
+// parseDiscoveredReleases resolves the chartRefs of the releases, parses the
+// releases into pipelines, and checks the sources of the releases.
+//
+// If namespaces is not nil, only the sources in the namespaces are checked.
+func parseDiscoveredReleases(ctx context.Context, cl client.Client, keys pipelinelabels.Keys, releases []helmv2.HelmRelease, namespaces sets.Set[string]) ([]HelmReleasePipeline, []Diagnostic, error) {
+	releases, unresolved, err := ResolveChartRefs(ctx, cl, keys, releases)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover pipelines: %w", err)
+	}
+	helmPipelines, diagnostics, err := ParseHelmReleasePipelines(keys, releases)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover pipelines: %w", err)
+	}
+	diagnostics = append(diagnostics, unresolved...)
+	missingSources, err := sourceDiagnostics(ctx, cl, keys, releases, namespaces)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover pipelines: %w", err)
+	}
+
+	return helmPipelines, append(diagnostics, missingSources...), nil
+}
+
+// FindPipeline discovers the pipelines and returns the named pipeline.
+func FindPipeline(ctx context.Context, cl client.Client, keys pipelinelabels.Keys, name string) (*HelmReleasePipeline, error) {
+	helmPipelines, _, err := DiscoverPipelines(ctx, cl, keys)
+	if err != nil {
+		return nil, err
+	}
+	for i := range helmPipelines {
+		if helmPipelines[i].Name == name {
+			return &helmPipelines[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("pipeline %q not found", name)
+}
+
+// InheritNamespaceLabels returns copies of the HelmReleases with the pipeline
+// labels from their Namespace applied.
+//
+// Labels, or annotations, on the HelmRelease override the labels on the
+// Namespace, and HelmReleases in a different pipeline from their Namespace
+// inherit none of its labels.
+func InheritNamespaceLabels(keys pipelinelabels.Keys, releases []helmv2.HelmRelease, namespaces []corev1.Namespace) []helmv2.HelmRelease {
+	nsByName := map[string]*corev1.Namespace{}
+	for i := range namespaces {
+		nsByName[namespaces[i].GetName()] = &namespaces[i]
+	}
+
+	inherited := make([]helmv2.HelmRelease, len(releases))
+	for i := range releases {
+		hr := releases[i].DeepCopy()
+		lbls := hr.GetLabels()
+		if lbls == nil {
+			lbls = map[string]string{}
+		}
+		ns := nsByName[hr.GetNamespace()]
+		if ns != nil && keys.PipelineName(hr) != "" && keys.PipelineName(hr) != keys.PipelineName(ns) {
+			ns = nil
+		}
+		for _, k := range keys.PipelineKeys() {
+			if _, ok := keys.Lookup(hr, k); ok || ns == nil {
+				continue
+			}
+			if v, ok := keys.Lookup(ns, k); ok {
+				lbls[k] = v
+			}
+		}
+		hr.SetLabels(lbls)
+		inherited[i] = *hr
+	}
+
+	return inherited
+}
+
+// listPipelineNamespaces returns the Namespaces that are labelled as being in a
+// pipeline.
+func listPipelineNamespaces(ctx context.Context, cl client.Client, keys pipelinelabels.Keys) ([]corev1.Namespace, error) {
+	namespaceList := &corev1.NamespaceList{}
+	if err := cl.List(ctx, namespaceList, pipelineListOptions(keys)...); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	namespaces := []corev1.Namespace{}
+	for _, ns := range namespaceList.Items {
+		if keys.PipelineName(&ns) != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	return namespaces, nil
+}
+
+// pipelineListOptions filters the resources that are listed to those labelled
+// with a pipeline, resources can't be filtered by annotations.
+func pipelineListOptions(keys pipelinelabels.Keys) []client.ListOption {
+	if keys.Annotations {
+		return nil
+	}
+
+	return []client.ListOption{client.HasLabels([]string{keys.Pipeline})}
+}
+
+func appendMissingReleases(releases, additional []helmv2.HelmRelease) []helmv2.HelmRelease {
+	existing := map[client.ObjectKey]bool{}
+	for i := range releases {
+		existing[client.ObjectKeyFromObject(&releases[i])] = true
+	}
+	for i := range additional {
+		if !existing[client.ObjectKeyFromObject(&additional[i])] {
+			releases = append(releases, additional[i])
+		}
+	}
+
+	return releases
+}
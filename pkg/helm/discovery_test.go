@@ -0,0 +1,429 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/gitops-tools/apps-scanner/pkg/pipelines"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+	"github.com/bigkevmcd/peanut-helmpipelines/test"
+)
+
+func TestDiscoverPipelines(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging")),
+		test.NewHelmRelease(test.InPipeline("other-pipeline", "staging", ""), test.Named("other-deploy", "other")),
+		test.NewHelmRelease(test.Named("unlabelled", "staging")),
+	}
+	fc := newFakeClient(t, releasesToRuntimeObjects(items)...)
+
+	discovered, _, err := DiscoverPipelines(context.TODO(), fc, pipelinelabels.DefaultKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []HelmReleasePipeline{
+		{
+			Name: "demo-pipeline",
+			Environments: []HelmReleaseEnvironment{
+				{
+					Name: "staging",
+					Charts: []HelmReleaseChart{
+						{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")},
+					},
+				},
+			},
+			ChartHelmReleases: map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+				{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}: {
+					{Name: "staging-deploy", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"}},
+			},
+		},
+		{
+			Name: "other-pipeline",
+			Environments: []HelmReleaseEnvironment{
+				{
+					Name: "staging",
+					Charts: []HelmReleaseChart{
+						{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")},
+					},
+				},
+			},
+			ChartHelmReleases: map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+				{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}: {
+					{Name: "other-deploy", Namespace: "other", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, discovered, ignoreReleaseStatus); diff != "" {
+		t.Fatalf("failed to discover pipelines:\n%s", diff)
+	}
+}
+
+func TestFindPipeline(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging")),
+	}
+	fc := newFakeClient(t, releasesToRuntimeObjects(items)...)
+
+	pipeline, err := FindPipeline(context.TODO(), fc, pipelinelabels.DefaultKeys, "demo-pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pipeline.Name != "demo-pipeline" {
+		t.Fatalf("got pipeline %q, want %q", pipeline.Name, "demo-pipeline")
+	}
+
+	_, err = FindPipeline(context.TODO(), fc, pipelinelabels.DefaultKeys, "unknown-pipeline")
+	if msg := `pipeline "unknown-pipeline" not found`; err == nil || err.Error() != msg {
+		t.Fatalf("got error %v, want %q", err, msg)
+	}
+}
+
+func TestDiscoverPipelines_namespace_labels(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.Named("staging-deploy", "podinfo-staging")),
+		test.NewHelmRelease(test.Named("production-deploy", "podinfo-production")),
+	}
+	objs := append(releasesToRuntimeObjects(items),
+		test.NewNamespace("podinfo-staging", test.InPipeline("demo-pipeline", "staging", "")),
+		test.NewNamespace("podinfo-production", test.InPipeline("demo-pipeline", "production", "staging")),
+	)
+	fc := newFakeClient(t, objs...)
+
+	discovered, _, err := DiscoverPipelines(context.TODO(), fc, pipelinelabels.DefaultKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chart := HelmReleaseChart{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}
+	want := []HelmReleasePipeline{
+		{
+			Name: "demo-pipeline",
+			Environments: []HelmReleaseEnvironment{
+				{Name: "staging", Charts: []HelmReleaseChart{chart}},
+				{Name: "production", After: []string{"staging"}, Charts: []HelmReleaseChart{chart}},
+			},
+			ChartHelmReleases: map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+				chart: {
+					{Name: "production-deploy", Namespace: "podinfo-production", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+					{Name: "staging-deploy", Namespace: "podinfo-staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, discovered, ignoreReleaseStatus); diff != "" {
+		t.Fatalf("failed to discover pipelines:\n%s", diff)
+	}
+}
+
+func TestDiscoverPipelines_custom_keys(t *testing.T) {
+	keys := pipelinelabels.Keys{
+		Pipeline:    "app.example.com/pipeline",
+		Environment: "app.example.com/stage",
+		After:       "app.example.com/after",
+		Application: "app.example.com/name",
+		Annotations: true,
+	}
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.Named("staging-deploy", "staging"),
+			test.Annotated("app.example.com/pipeline", "demo-pipeline"), test.Annotated("app.example.com/stage", "staging")),
+		test.NewHelmRelease(test.Named("production-deploy", "production"),
+			test.Labelled("app.example.com/stage", "production"), test.Annotated("app.example.com/after", "staging")),
+		test.NewHelmRelease(test.Named("default-labels", "staging"), test.InPipeline("other-pipeline", "staging", "")),
+	}
+	objs := append(releasesToRuntimeObjects(items),
+		test.NewNamespace("production", test.Labelled("app.example.com/pipeline", "demo-pipeline")))
+	fc := newFakeClient(t, objs...)
+
+	discovered, _, err := DiscoverPipelines(context.TODO(), fc, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chart := HelmReleaseChart{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}
+	want := []HelmReleasePipeline{
+		{
+			Name: "demo-pipeline",
+			Environments: []HelmReleaseEnvironment{
+				{Name: "staging", Charts: []HelmReleaseChart{chart}},
+				{Name: "production", After: []string{"staging"}, Charts: []HelmReleaseChart{chart}},
+			},
+			ChartHelmReleases: map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+				chart: {
+					{Name: "production-deploy", Namespace: "production", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+					{Name: "staging-deploy", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, discovered, ignoreReleaseStatus); diff != "" {
+		t.Fatalf("failed to discover pipelines:\n%s", diff)
+	}
+
+	// The labels of the releases have the keys from annotations, and from
+	// the Namespace.
+	resolved := map[string][]string{}
+	for ref, status := range discovered[0].Releases {
+		resolved[ref.Name] = []string{status.Labels[keys.Pipeline], status.Labels[keys.Environment], status.Labels[keys.After]}
+	}
+	wantResolved := map[string][]string{
+		"staging-deploy":    {"demo-pipeline", "staging", ""},
+		"production-deploy": {"demo-pipeline", "production", "staging"},
+	}
+	if diff := cmp.Diff(wantResolved, resolved); diff != "" {
+		t.Fatalf("failed to resolve labels:\n%s", diff)
+	}
+}
+
+func TestDiscoverNamespacedPipelines(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "testing", "staging"), test.Named("testing-deploy", "testing")),
+	}
+	objs := append(releasesToRuntimeObjects(items),
+		test.NewNamespace("staging", test.InPipeline("other-pipeline", "staging", "")))
+	cl := namespacedClient{Client: newFakeClient(t, objs...), namespaces: []string{"staging", "production"}}
+
+	discovered, diagnostics, err := DiscoverNamespacedPipelines(context.TODO(), cl, pipelinelabels.DefaultKeys, []string{"staging", "production"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chart := HelmReleaseChart{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}
+	want := []HelmReleasePipeline{
+		{
+			Name: "demo-pipeline",
+			Environments: []HelmReleaseEnvironment{
+				{Name: "staging", Charts: []HelmReleaseChart{chart}},
+				{Name: "production", After: []string{"staging"}, Charts: []HelmReleaseChart{chart}},
+			},
+			ChartHelmReleases: map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+				chart: {
+					{Name: "production-deploy", Namespace: "production", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+					{Name: "staging-deploy", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, discovered, ignoreReleaseStatus); diff != "" {
+		t.Fatalf("failed to discover pipelines:\n%s", diff)
+	}
+	if len(diagnostics) != 0 {
+		t.Fatalf("got diagnostics %v, want none", diagnostics)
+	}
+}
+
+func TestDiscoverNamespacedPipelines_unwatched_after_environment(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "dev", ""), test.Named("dev-deploy", "dev")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", "dev"), test.Named("staging-deploy", "staging")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production")),
+	}
+	cl := namespacedClient{Client: newFakeClient(t, releasesToRuntimeObjects(items)...), namespaces: []string{"staging", "production"}}
+
+	discovered, diagnostics, err := DiscoverNamespacedPipelines(context.TODO(), cl, pipelinelabels.DefaultKeys, []string{"staging", "production"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantEnvironments := []HelmReleaseEnvironment{
+		{Name: "staging"},
+		{Name: "production", After: []string{"staging"}},
+	}
+	if l := len(discovered); l != 1 {
+		t.Fatalf("got %d pipelines, want 1", l)
+	}
+	if diff := cmp.Diff(wantEnvironments, discovered[0].Environments, cmpopts.IgnoreFields(HelmReleaseEnvironment{}, "Charts")); diff != "" {
+		t.Fatalf("failed to discover environments:\n%s", diff)
+	}
+	want := []Diagnostic{
+		{
+			Pipeline:    "demo-pipeline",
+			Environment: "staging",
+			HelmRelease: helmReleaseRef("staging-deploy", "staging"),
+			Reason:      UnwatchedAfterEnvironmentReason,
+			Message:     `environment "staging" follows environment "dev" which has no HelmReleases in the watched namespaces`,
+		},
+	}
+	if diff := cmp.Diff(want, diagnostics); diff != "" {
+		t.Fatalf("failed to report diagnostics:\n%s", diff)
+	}
+}
+
+func TestDiscoverNamespacedPipelines_forbidden(t *testing.T) {
+	cl := namespacedClient{Client: newFakeClient(t), namespaces: []string{"staging"}}
+
+	_, _, err := DiscoverNamespacedPipelines(context.TODO(), cl, pipelinelabels.DefaultKeys, []string{"staging", "production"})
+	if !apierrors.IsForbidden(err) {
+		t.Fatalf("got error %v, want forbidden", err)
+	}
+}
+
+func TestDiscoverPipelinesInNamespace(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.Named("staging-deploy", "podinfo-staging")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production")),
+	}
+	objs := append(releasesToRuntimeObjects(items),
+		test.NewNamespace("podinfo-staging", test.InPipeline("demo-pipeline", "staging", "")))
+	cl := newFakeClient(t, objs...)
+
+	discovered, diagnostics, err := DiscoverPipelinesInNamespace(context.TODO(), cl, pipelinelabels.DefaultKeys, "podinfo-staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chart := HelmReleaseChart{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}
+	want := []HelmReleasePipeline{
+		{
+			Name: "demo-pipeline",
+			Environments: []HelmReleaseEnvironment{
+				{Name: "staging", Charts: []HelmReleaseChart{chart}},
+			},
+			ChartHelmReleases: map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+				chart: {
+					{Name: "staging-deploy", Namespace: "podinfo-staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, discovered, ignoreReleaseStatus); diff != "" {
+		t.Fatalf("failed to discover pipelines:\n%s", diff)
+	}
+	if len(diagnostics) != 0 {
+		t.Fatalf("got diagnostics %v, want none", diagnostics)
+	}
+}
+
+func TestDiscoverPipelinesInNamespace_forbidden_namespace(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.Named("unlabelled-deploy", "staging")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging")),
+	}
+	objs := append(releasesToRuntimeObjects(items),
+		test.NewNamespace("staging", test.InPipeline("demo-pipeline", "staging", "")))
+	cl := namespacedClient{Client: newFakeClient(t, objs...), namespaces: []string{"staging"}}
+
+	discovered, _, err := DiscoverPipelinesInNamespace(context.TODO(), cl, pipelinelabels.DefaultKeys, "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+		{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}: {
+			{Name: "staging-deploy", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+		},
+	}
+	if l := len(discovered); l != 1 {
+		t.Fatalf("got %d pipelines, want 1", l)
+	}
+	if diff := cmp.Diff(want, discovered[0].ChartHelmReleases); diff != "" {
+		t.Fatalf("failed to discover releases:\n%s", diff)
+	}
+}
+
+func TestInheritNamespaceLabels(t *testing.T) {
+	namespaces := []corev1.Namespace{
+		*test.NewNamespace("staging", test.InPipeline("demo-pipeline", "staging", "dev")),
+	}
+
+	inheritTests := []struct {
+		name    string
+		release helmv2.HelmRelease
+		want    map[string]string
+	}{
+		{
+			name:    "release without labels",
+			release: test.NewHelmRelease(test.Named("demo", "staging")),
+			want: map[string]string{
+				pipelines.PipelineNameLabel:             "demo-pipeline",
+				pipelines.PipelineEnvironmentLabel:      "staging",
+				pipelines.PipelineEnvironmentAfterLabel: "dev",
+			},
+		},
+		{
+			name:    "release labels override namespace labels",
+			release: test.NewHelmRelease(test.Named("demo", "staging"), test.InPipeline("other-pipeline", "testing", "")),
+			want: map[string]string{
+				pipelines.PipelineNameLabel:             "other-pipeline",
+				pipelines.PipelineEnvironmentLabel:      "testing",
+				pipelines.PipelineEnvironmentAfterLabel: "",
+			},
+		},
+		{
+			name:    "release in the same pipeline as the namespace",
+			release: test.NewHelmRelease(test.Named("demo", "staging"), test.Labelled(pipelines.PipelineNameLabel, "demo-pipeline")),
+			want: map[string]string{
+				pipelines.PipelineNameLabel:             "demo-pipeline",
+				pipelines.PipelineEnvironmentLabel:      "staging",
+				pipelines.PipelineEnvironmentAfterLabel: "dev",
+			},
+		},
+		{
+			name:    "release in a different pipeline from the namespace",
+			release: test.NewHelmRelease(test.Named("demo", "staging"), test.Labelled(pipelines.PipelineNameLabel, "other-pipeline")),
+			want: map[string]string{
+				pipelines.PipelineNameLabel: "other-pipeline",
+			},
+		},
+		{
+			name:    "release in unlabelled namespace",
+			release: test.NewHelmRelease(test.Named("demo", "production")),
+			want:    map[string]string{},
+		},
+	}
+
+	for _, tt := range inheritTests {
+		t.Run(tt.name, func(t *testing.T) {
+			inherited := InheritNamespaceLabels(pipelinelabels.DefaultKeys, []helmv2.HelmRelease{tt.release}, namespaces)
+
+			if diff := cmp.Diff(tt.want, inherited[0].GetLabels()); diff != "" {
+				t.Fatalf("failed to inherit labels:\n%s", diff)
+			}
+		})
+	}
+}
+
+// namespacedClient only allows listing and getting resources in the
+// namespaces, as if it had a Role in each namespace.
+type namespacedClient struct {
+	client.Client
+	namespaces []string
+}
+
+func (c namespacedClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.check(key.Namespace); err != nil {
+		return err
+	}
+
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c namespacedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if err := c.check(listOpts.Namespace); err != nil {
+		return err
+	}
+
+	return c.Client.List(ctx, list, opts...)
+}
+
+func (c namespacedClient) check(ns string) error {
+	if slices.Contains(c.namespaces, ns) {
+		return nil
+	}
+
+	return apierrors.NewForbidden(schema.GroupResource{Group: "helm.toolkit.fluxcd.io", Resource: "helmreleases"}, "", errors.New("namespace not allowed"))
+}
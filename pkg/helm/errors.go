@@ -0,0 +1,26 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+)
+
+// ErrUnsupportedSource is returned when a chart can't be fetched from its
+// source, charts can only be fetched from HelmRepositories.
+var ErrUnsupportedSource = errors.New("charts can only be fetched from a HelmRepository")
+
+// ReleaseError is returned when a HelmRelease in a pipeline can't be parsed.
+type ReleaseError struct {
+	HelmRelease helmv2.CrossNamespaceObjectReference
+	Err         error
+}
+
+func (e *ReleaseError) Error() string {
+	return fmt.Sprintf("failed to parse HelmRelease %s/%s: %s", e.HelmRelease.Namespace, e.HelmRelease.Name, e.Err)
+}
+
+func (e *ReleaseError) Unwrap() error {
+	return e.Err
+}
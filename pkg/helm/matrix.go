@@ -0,0 +1,106 @@
+package helm
+
+import (
+	"strings"
+)
+
+// VersionMatrix is a view of the versions of each chart deployed to each
+// environment in a pipeline.
+type VersionMatrix struct {
+	Environments []string           `json:"environments"`
+	Rows         []VersionMatrixRow `json:"rows"`
+}
+
+// VersionMatrixRow is the versions of a chart in each environment, the cells
+// are in the same order as the environments in the matrix.
+type VersionMatrixRow struct {
+	Application string              `json:"application,omitempty"`
+	Chart       string              `json:"chart"`
+	Source      string              `json:"source"`
+	Cells       []VersionMatrixCell `json:"cells"`
+}
+
+// VersionMatrixCell is the version of a chart in an environment.
+type VersionMatrixCell struct {
+	// Version is the deployed version, if the chart is deployed more than
+	// once with different versions, the versions are comma separated.
+	Version string `json:"version,omitempty"`
+	// PromoteTo is the version in the preceding environment if this
+	// environment lags behind it.
+	PromoteTo string `json:"promoteTo,omitempty"`
+	// Available is a newer version of the chart in the chart repository.
+	Available string `json:"available,omitempty"`
+}
+
+type matrixKey struct {
+	application string
+	name        string
+	source      string
+}
+
+// NewVersionMatrix creates a VersionMatrix from a pipeline and the promotions
+// and upgrades calculated from it.
+//
+// The rows are in the order that the charts first appear in the environments.
+func NewVersionMatrix(p HelmReleasePipeline, promotions []Promotion, upgrades []ChartUpgrade) VersionMatrix {
+	m := VersionMatrix{Environments: []string{}, Rows: []VersionMatrixRow{}}
+	rows := map[matrixKey]int{}
+	for _, env := range p.Environments {
+		m.Environments = append(m.Environments, env.Name)
+	}
+
+	for i, env := range p.Environments {
+		for _, chart := range env.Charts {
+			key := matrixKey{application: chart.Application, name: chart.Name, source: sourceString(chart)}
+			row, ok := rows[key]
+			if !ok {
+				row = len(m.Rows)
+				rows[key] = row
+				m.Rows = append(m.Rows, VersionMatrixRow{
+					Application: chart.Application,
+					Chart:       chart.Name,
+					Source:      key.source,
+					Cells:       make([]VersionMatrixCell, len(p.Environments)),
+				})
+			}
+
+			cell := m.Rows[row].Cells[i]
+			cell.Version = appendVersion(cell.Version, chart.Version)
+			for _, promotion := range promotions {
+				if promotion.Environment == env.Name && promotion.From == chart && promotion.To.Version != chart.Version {
+					cell.PromoteTo = promotion.To.Version
+				}
+			}
+			for _, upgrade := range upgrades {
+				if upgrade.Current.Name == chart.Name && upgrade.Current.Source == chart.Source && upgrade.Current.Version == chart.Version {
+					cell.Available = upgrade.Available.Version
+				}
+			}
+			m.Rows[row].Cells[i] = cell
+		}
+	}
+
+	return m
+}
+
+func appendVersion(versions, version string) string {
+	if versions == "" {
+		return version
+	}
+	for _, v := range strings.Split(versions, ",") {
+		if v == version {
+			return versions
+		}
+	}
+
+	return versions + "," + version
+}
+
+func sourceString(c HelmReleaseChart) string {
+	// Charts from an OCIRepository are identified by the URL.
+	if c.Source.Namespace == "" {
+		return c.Source.Kind + "/" + c.Source.Name
+	}
+
+	return c.Source.Kind + "/" + c.Source.Namespace + "/" + c.Source.Name
+}
@@ -0,0 +1,53 @@
+package helm
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewVersionMatrix(t *testing.T) {
+	source := sourceRef("HelmRepository", "default", "test-repository")
+	redisStaging := HelmReleaseChart{Name: "redis", Version: "1.0.12", Source: source}
+	redisProduction := HelmReleaseChart{Name: "redis", Version: "1.0.9", Source: source}
+	postgresStaging := HelmReleaseChart{Name: "postgresql", Version: "13.0.1", Source: source}
+	pipeline := HelmReleasePipeline{
+		Name: "demo-pipeline",
+		Environments: []HelmReleaseEnvironment{
+			{Name: "staging", Charts: []HelmReleaseChart{redisStaging, postgresStaging}},
+			{Name: "production", After: []string{"staging"}, Charts: []HelmReleaseChart{redisProduction}},
+		},
+	}
+	promotions := CalculatePromotions(pipeline)
+	upgrades := []ChartUpgrade{
+		{Current: redisStaging, Available: HelmReleaseChart{Name: "redis", Version: "1.1.0", Source: source}},
+		{Current: redisProduction, Available: HelmReleaseChart{Name: "redis", Version: "1.1.0", Source: source}},
+	}
+
+	m := NewVersionMatrix(pipeline, promotions, upgrades)
+
+	want := VersionMatrix{
+		Environments: []string{"staging", "production"},
+		Rows: []VersionMatrixRow{
+			{
+				Chart:  "redis",
+				Source: "HelmRepository/default/test-repository",
+				Cells: []VersionMatrixCell{
+					{Version: "1.0.12", Available: "1.1.0"},
+					{Version: "1.0.9", PromoteTo: "1.0.12", Available: "1.1.0"},
+				},
+			},
+			{
+				Chart:  "postgresql",
+				Source: "HelmRepository/default/test-repository",
+				Cells: []VersionMatrixCell{
+					{Version: "13.0.1"},
+					{},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, m); diff != "" {
+		t.Fatalf("failed to create matrix:\n%s", diff)
+	}
+}
@@ -1,59 +1,78 @@
 package helm
 
 import (
-	"fmt"
 	"sort"
 
-	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
 	"github.com/gitops-tools/pkg/sets"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	"github.com/gitops-tools/apps-scanner/pkg/pipelines"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
 )
 
+// PipelineApplicationLabel is a label that identifies the HelmReleases that
+// deploy the same application in each environment of a pipeline.
+const PipelineApplicationLabel = pipelinelabels.ApplicationLabel
+
 // HelmReleasePipelines provides a mapping of Helm charts in environments to
 // their pipelines.
 type HelmReleasePipeline struct {
 	Name              string
 	Environments      []HelmReleaseEnvironment
 	ChartHelmReleases map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference
+	// Releases is the status of each HelmRelease in the pipeline.
+	Releases map[helmv2.CrossNamespaceObjectReference]HelmReleaseStatus
 }
 
 // HelmReleaseEnvironment represents the charts in a specific staged of a
 // pipeline.
 type HelmReleaseEnvironment struct {
-	Name   string
+	Name string
+	// After is the names of the environments that this environment is
+	// promoted from, this is empty for the first environments in a pipeline.
+	After  []string
 	Charts []HelmReleaseChart
 }
 
 // HelmReleaseChart is the specific version of the chart in a HelmRelease.
 type HelmReleaseChart struct {
-	Name    string
-	Version string
-	Source  helmv2.CrossNamespaceObjectReference
+	// Application identifies the chart across the environments of a
+	// pipeline, this is empty if the HelmRelease has no application label or
+	// release name, and the chart is identified by the name and source.
+	Application string
+	Name        string
+	Version     string
+	Source      helmv2.CrossNamespaceObjectReference
 }
 
 // ParseHelmReleasePipelines parses the pipelines and the versions of the charts
 // used by the HelmReleases in each stage in each pipeline.
-func ParseHelmReleasePipelines(hl []helmv2.HelmRelease) ([]HelmReleasePipeline, error) {
-	p := pipelines.NewParser()
-	if err := p.Add(releasesToRuntimeObjects(hl)); err != nil {
-		return nil, fmt.Errorf("failed to parse HelmReleases: %w", err)
-	}
-	ps, err := p.Pipelines()
+//
+// HelmReleases that can't be placed into a pipeline are reported as
+// diagnostics, rather than failing the parsing of every pipeline.
+//
+// The keys identify the labels, or annotations, that place the HelmReleases
+// into pipelines.
+//
+// HelmReleases that use a chartRef are ignored unless they have been resolved
+// with ResolveChartRefs.
+func ParseHelmReleasePipelines(keys pipelinelabels.Keys, hl []helmv2.HelmRelease) ([]HelmReleasePipeline, []Diagnostic, error) {
+	valid, diagnostics := validatePipelineReleases(keys, hl)
+	graphs := parseEnvironmentGraphs(keys, valid)
+
+	charts, err := parsePipelineCharts(keys, valid)
 	if err != nil {
-		return nil, fmt.Errorf("failed to calculate pipelines: %w", err)
+		return nil, nil, err
 	}
-
-	charts := parsePipelineCharts(hl)
 	parsed := []HelmReleasePipeline{}
-	chartHelmReleases := map[HelmReleaseChart]sets.Set[helmv2.CrossNamespaceObjectReference]{}
-	for _, pipeline := range ps {
+	for _, pipeline := range graphs {
 		envsToCharts := map[string]sets.Set[HelmReleaseChart]{}
-		for _, c := range charts[pipeline.Name] {
+		chartHelmReleases := map[HelmReleaseChart]sets.Set[helmv2.CrossNamespaceObjectReference]{}
+		releases := map[helmv2.CrossNamespaceObjectReference]HelmReleaseStatus{}
+		for _, c := range charts[pipeline.name] {
 			envCharts := envsToCharts[c.environment]
-			hrc := HelmReleaseChart{Name: c.chart, Version: c.version, Source: c.source}
+			hrc := HelmReleaseChart{Application: c.application, Name: c.chart, Version: c.version, Source: c.source}
 			if envCharts == nil {
 				envCharts = sets.New[HelmReleaseChart]()
 			}
@@ -65,23 +84,107 @@ func ParseHelmReleasePipelines(hl []helmv2.HelmRelease) ([]HelmReleasePipeline,
 			helmReleases.Insert(c.helmRelease)
 			envsToCharts[c.environment] = envCharts
 			chartHelmReleases[hrc] = helmReleases
+			releases[c.helmRelease] = c.status
 		}
 
 		hrp := HelmReleasePipeline{
-			Name:              pipeline.Name,
+			Name:              pipeline.name,
 			Environments:      []HelmReleaseEnvironment{},
 			ChartHelmReleases: unpackChartReleases(chartHelmReleases),
+			Releases:          releases,
 		}
-		for _, envName := range pipeline.Environments {
+		for _, envName := range orderEnvironments(pipeline.environments) {
 			hrp.Environments = append(hrp.Environments,
 				HelmReleaseEnvironment{Name: envName,
+					After:  environmentsAfter(pipeline.environments[envName]),
 					Charts: envsToCharts[envName].List(),
 				})
 		}
 		parsed = append(parsed, hrp)
 	}
 
-	return parsed, nil
+	return parsed, diagnostics, nil
+}
+
+// environmentGraph maps the environments in a pipeline to the environments
+// they are labelled as following.
+//
+// Environments that follow no environment, follow "".
+type environmentGraph map[string]sets.Set[string]
+
+type pipelineEnvironments struct {
+	name         string
+	environments environmentGraph
+}
+
+// parseEnvironmentGraphs parses the environments of each pipeline from the
+// labels on the HelmReleases.
+//
+// A pipeline can fan-out, with more than one environment following the same
+// environment, and fan-in, with the HelmReleases in an environment following
+// different environments.
+func parseEnvironmentGraphs(keys pipelinelabels.Keys, releases []helmv2.HelmRelease) []pipelineEnvironments {
+	graphs := map[string]environmentGraph{}
+	for i := range releases {
+		hr := &releases[i]
+		pipeline, env := keys.PipelineName(hr), keys.EnvironmentName(hr)
+		if pipeline == "" || env == "" {
+			continue
+		}
+		if graphs[pipeline] == nil {
+			graphs[pipeline] = environmentGraph{}
+		}
+		if graphs[pipeline][env] == nil {
+			graphs[pipeline][env] = sets.New[string]()
+		}
+		graphs[pipeline][env].Insert(keys.AfterEnvironment(hr))
+	}
+
+	parsed := []pipelineEnvironments{}
+	for _, name := range sortedKeys(graphs) {
+		parsed = append(parsed, pipelineEnvironments{name: name, environments: graphs[name]})
+	}
+
+	return parsed
+}
+
+// orderEnvironments orders the environments so that each environment comes
+// after all the environments that it follows.
+//
+// Environments that can be promoted to at the same time are ordered by name.
+func orderEnvironments(g environmentGraph) []string {
+	ordered := []string{}
+	placed := sets.New[string]("")
+	for len(ordered) < len(g) {
+		ready := []string{}
+		for _, env := range sortedKeys(g) {
+			if !placed.Has(env) && placed.IsSuperset(g[env]) {
+				ready = append(ready, env)
+			}
+		}
+		// Cycles are reported by validatePipelineReleases.
+		if len(ready) == 0 {
+			break
+		}
+		placed.Insert(ready...)
+		ordered = append(ordered, ready...)
+	}
+
+	return ordered
+}
+
+func environmentsAfter(after sets.Set[string]) []string {
+	envs := []string{}
+	for _, env := range after.SortedList(func(x, y string) bool { return x < y }) {
+		if env != "" {
+			envs = append(envs, env)
+		}
+	}
+	if len(envs) == 0 {
+		return nil
+	}
+
+	return envs
 }
 
 func unpackChartReleases(packed map[HelmReleaseChart]sets.Set[helmv2.CrossNamespaceObjectReference]) map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference {
@@ -91,7 +194,7 @@ func unpackChartReleases(packed map[HelmReleaseChart]sets.Set[helmv2.CrossNamesp
 		sort.Slice(objs, func(i, j int) bool {
 			return objs[i].Name < objs[j].Name
 		})
-		unpacked[k] = v.List()
+		unpacked[k] = objs
 	}
 
 	return unpacked
@@ -100,39 +203,85 @@ func unpackChartReleases(packed map[HelmReleaseChart]sets.Set[helmv2.CrossNamesp
 type pipelineChart struct {
 	pipeline    string
 	environment string
+	application string
 	chart       string
 	version     string
 	source      helmv2.CrossNamespaceObjectReference
 	helmRelease helmv2.CrossNamespaceObjectReference
+	status      HelmReleaseStatus
 }
 
-func parsePipelineCharts(releases []helmv2.HelmRelease) map[string][]pipelineChart {
+func parsePipelineCharts(keys pipelinelabels.Keys, releases []helmv2.HelmRelease) (map[string][]pipelineChart, error) {
 	discovered := map[string][]pipelineChart{}
 
 	for _, hr := range releases {
-		lbls := hr.GetLabels()
-		pipeline := lbls[pipelines.PipelineNameLabel]
-		env := lbls[pipelines.PipelineEnvironmentLabel]
-		// We can't place HelmReleases into any env if they are not labelled.
+		pipeline := keys.PipelineName(&hr)
+		env := keys.EnvironmentName(&hr)
+		// HelmReleases without an environment are reported by
+		// validatePipelineReleases.
 		if pipeline == "" || env == "" {
 			continue
 		}
+		// HelmReleases with a chartRef are resolved by ResolveChartRefs.
+		if hr.Spec.Chart == nil {
+			continue
+		}
 		chart, version := hr.Spec.Chart.Spec.Chart, hr.Spec.Chart.Spec.Version
+		digest, err := valuesDigest(&hr)
+		if err != nil {
+			return nil, &ReleaseError{HelmRelease: objectReferenceFromObject(&hr), Err: err}
+		}
 		pc := discovered[pipeline]
 		if pc == nil {
 			pc = []pipelineChart{}
 		}
 
+		status := releaseStatus(env, &hr)
+		status.ValuesDigest = digest
+		status.Labels = resolvedLabels(keys, &hr)
 		pc = append(pc, pipelineChart{
-			pipeline: pipeline, environment: env,
+			pipeline: pipeline, environment: env, application: applicationName(keys, &hr),
 			chart: chart, version: version,
 			source:      hr.Spec.Chart.Spec.SourceRef,
 			helmRelease: objectReferenceFromObject(&hr),
+			status:      status,
 		})
 		discovered[pipeline] = pc
 	}
 
-	return discovered
+	return discovered, nil
+}
+
+// resolvedLabels returns the labels of the HelmRelease with the values of the
+// pipeline and application keys, which can be annotations.
+func resolvedLabels(keys pipelinelabels.Keys, hr *helmv2.HelmRelease) map[string]string {
+	resolved := map[string]string{}
+	for k, v := range hr.GetLabels() {
+		resolved[k] = v
+	}
+	for _, k := range append(keys.PipelineKeys(), keys.Application) {
+		if v, ok := keys.Lookup(hr, k); ok {
+			resolved[k] = v
+		}
+	}
+
+	return resolved
+}
+
+// applicationName returns the identity of the application deployed by the
+// HelmRelease from the application label, or the release name.
+//
+// Most HelmReleases have no release name, and the HelmReleases in an
+// environment that deploy the same chart from the same source without an
+// application are treated as one application, and promoted together, for
+// example the HelmReleases for each region. The application label is required
+// to promote them separately.
+func applicationName(keys pipelinelabels.Keys, hr *helmv2.HelmRelease) string {
+	if name := keys.ApplicationName(hr); name != "" {
+		return name
+	}
+
+	return hr.Spec.ReleaseName
 }
 
 func objectReferenceFromObject(obj client.Object) helmv2.CrossNamespaceObjectReference {
@@ -1,11 +1,15 @@
 package helm
 
 import (
+	"errors"
 	"testing"
 
-	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
 	"github.com/bigkevmcd/peanut-helmpipelines/test"
 )
 
@@ -71,7 +75,8 @@ func TestHelmChartPipelines(t *testing.T) {
 							},
 						},
 						{
-							Name: "production",
+							Name:  "production",
+							After: []string{"staging"},
 							Charts: []HelmReleaseChart{
 								{
 									Name:    "redis",
@@ -83,8 +88,8 @@ func TestHelmChartPipelines(t *testing.T) {
 					},
 					ChartHelmReleases: map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
 						{Name: "redis", Version: "1.0.9", Source: helmv2.CrossNamespaceObjectReference{Kind: "HelmRepository", Name: "test-repository", Namespace: "default"}}: {
-							{Name: "staging-deploy", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
-							{Name: "production-deploy", Namespace: "production", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"}},
+							{Name: "production-deploy", Namespace: "production", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+							{Name: "staging-deploy", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"}},
 					},
 				},
 			},
@@ -118,21 +123,258 @@ func TestHelmChartPipelines(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "helm releases with values in two stages of the same pipeline",
+			items: []helmv2.HelmRelease{
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging")),
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production"),
+					test.Values(`{"replicas":3,"image":{"tag":"latest"}}`)),
+			},
+			want: []HelmReleasePipeline{
+				{
+					Name: "demo-pipeline",
+					Environments: []HelmReleaseEnvironment{
+						{
+							Name: "staging",
+							Charts: []HelmReleaseChart{
+								{
+									Name:    "redis",
+									Version: "1.0.9",
+									Source:  sourceRef("HelmRepository", "default", "test-repository"),
+								},
+							},
+						},
+						{
+							Name:  "production",
+							After: []string{"staging"},
+							Charts: []HelmReleaseChart{
+								{
+									Name:    "redis",
+									Version: "1.0.9",
+									Source:  sourceRef("HelmRepository", "default", "test-repository"),
+								},
+							},
+						},
+					},
+					ChartHelmReleases: map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+						{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}: {
+							{Name: "production-deploy", Namespace: "production", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+							{Name: "staging-deploy", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"}},
+					},
+				},
+			},
+		},
+		{
+			name: "helm releases with the same chart in different pipelines",
+			items: []helmv2.HelmRelease{
+				test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("demo-deploy", "staging")),
+				test.NewHelmRelease(test.InPipeline("other-pipeline", "staging", ""), test.Named("other-deploy", "staging")),
+			},
+			want: []HelmReleasePipeline{
+				{
+					Name: "demo-pipeline",
+					Environments: []HelmReleaseEnvironment{
+						{
+							Name: "staging",
+							Charts: []HelmReleaseChart{
+								{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")},
+							},
+						},
+					},
+					ChartHelmReleases: map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+						{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}: {
+							{Name: "demo-deploy", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"}},
+					},
+				},
+				{
+					Name: "other-pipeline",
+					Environments: []HelmReleaseEnvironment{
+						{
+							Name: "staging",
+							Charts: []HelmReleaseChart{
+								{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")},
+							},
+						},
+					},
+					ChartHelmReleases: map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+						{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}: {
+							{Name: "other-deploy", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"}},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range pipelinesTests {
 		t.Run(tt.name, func(t *testing.T) {
-			ps, err := ParseHelmReleasePipelines(tt.items)
+			ps, _, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, tt.items)
 			if err != nil {
 				t.Fatal(err)
 			}
-			if diff := cmp.Diff(tt.want, ps); diff != "" {
+			if diff := cmp.Diff(tt.want, ps, ignoreReleaseStatus); diff != "" {
 				t.Fatalf("failed to parse pipelines:\n%s", diff)
 			}
 		})
 	}
 }
 
+func TestHelmChartPipelines_fan_out_and_fan_in(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging-us"), test.Named("production-us", "production")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging-us", "dev"), test.Named("staging-us", "staging-us")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging-eu"), test.Named("production-eu", "production")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "dev", ""), test.Named("dev", "dev")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging-eu", "dev"), test.Named("staging-eu", "staging-eu")),
+	}
+
+	ps, _, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chart := HelmReleaseChart{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}
+	want := []HelmReleaseEnvironment{
+		{Name: "dev", Charts: []HelmReleaseChart{chart}},
+		{Name: "staging-eu", After: []string{"dev"}, Charts: []HelmReleaseChart{chart}},
+		{Name: "staging-us", After: []string{"dev"}, Charts: []HelmReleaseChart{chart}},
+		{Name: "production", After: []string{"staging-eu", "staging-us"}, Charts: []HelmReleaseChart{chart}},
+	}
+	if diff := cmp.Diff(want, ps[0].Environments); diff != "" {
+		t.Fatalf("failed to parse environments:\n%s", diff)
+	}
+}
+
+func TestHelmChartPipelines_values(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-eu", "staging"), test.Values(`{"region":"eu"}`)),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-us", "staging"), test.Values(`{"region":"us"}`)),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-default", "staging")),
+	}
+
+	ps, _, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chart := HelmReleaseChart{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}
+	if diff := cmp.Diff([]HelmReleaseEnvironment{{Name: "staging", Charts: []HelmReleaseChart{chart}}}, ps[0].Environments); diff != "" {
+		t.Fatalf("failed to parse environments:\n%s", diff)
+	}
+	digests := map[string]string{}
+	for ref, status := range ps[0].Releases {
+		digests[ref.Name] = status.ValuesDigest
+	}
+	want := map[string]string{
+		"staging-eu":      "sha256:76211909998d960ba21cb3df232c965a70e270ed98c2da0579aa01c4d6120ebd",
+		"staging-us":      "sha256:5188d426d4e73d424961c4610a25ee39612ed4ba5bfe5be5b17c83783d2fcee1",
+		"staging-default": "",
+	}
+	if diff := cmp.Diff(want, digests); diff != "" {
+		t.Fatalf("failed to parse values digests:\n%s", diff)
+	}
+}
+
+func TestHelmChartPipelines_applications(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("cache", "staging"),
+			test.Labelled(PipelineApplicationLabel, "cache")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("sessions", "staging"),
+			test.Labelled(PipelineApplicationLabel, "sessions")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-cache", "production"),
+			test.Labelled(PipelineApplicationLabel, "cache")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-sessions", "production"),
+			test.Labelled(PipelineApplicationLabel, "sessions")),
+	}
+
+	ps, _, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := HelmReleaseChart{Application: "cache", Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}
+	sessions := HelmReleaseChart{Application: "sessions", Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}
+	want := map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+		cache: {
+			{Name: "cache", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+			{Name: "production-cache", Namespace: "production", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+		},
+		sessions: {
+			{Name: "production-sessions", Namespace: "production", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+			{Name: "sessions", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+		},
+	}
+	if diff := cmp.Diff(want, ps[0].ChartHelmReleases); diff != "" {
+		t.Fatalf("failed to parse chart releases:\n%s", diff)
+	}
+}
+
+func TestHelmChartPipelines_unlabelled_applications(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("cache", "staging")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("sessions", "staging")),
+	}
+
+	ps, _, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without an application label the HelmReleases deploy one application.
+	redis := HelmReleaseChart{Name: "redis", Version: "1.0.9", Source: sourceRef("HelmRepository", "default", "test-repository")}
+	want := map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+		redis: {
+			{Name: "cache", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+			{Name: "sessions", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"},
+		},
+	}
+	if diff := cmp.Diff(want, ps[0].ChartHelmReleases); diff != "" {
+		t.Fatalf("failed to parse chart releases:\n%s", diff)
+	}
+}
+
+func TestHelmChartPipelines_invalid_values(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("redis", "staging"), test.Values(`{`)),
+	}
+
+	_, _, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, items)
+
+	var releaseErr *ReleaseError
+	if !errors.As(err, &releaseErr) {
+		t.Fatalf("got error %v, want a ReleaseError", err)
+	}
+	want := helmv2.CrossNamespaceObjectReference{Name: "redis", Namespace: "staging", Kind: "HelmRelease", APIVersion: "source.toolkit.fluxcd.io/v1beta2"}
+	if diff := cmp.Diff(want, releaseErr.HelmRelease); diff != "" {
+		t.Fatalf("incorrect HelmRelease in error:\n%s", diff)
+	}
+}
+
+func TestApplicationName(t *testing.T) {
+	nameTests := []struct {
+		name    string
+		release helmv2.HelmRelease
+		want    string
+	}{
+		{"no label or release name", test.NewHelmRelease(), ""},
+		{"release name", test.NewHelmRelease(releaseName("cache")), "cache"},
+		{"application label", test.NewHelmRelease(releaseName("cache"), test.Labelled(PipelineApplicationLabel, "sessions")), "sessions"},
+	}
+
+	for _, tt := range nameTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applicationName(pipelinelabels.DefaultKeys, &tt.release); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func releaseName(name string) func(client.Object) {
+	return func(o client.Object) {
+		o.(*helmv2.HelmRelease).Spec.ReleaseName = name
+	}
+}
+
 func sourceRef(kind, namespace, name string) helmv2.CrossNamespaceObjectReference {
 	return helmv2.CrossNamespaceObjectReference{
 		Kind:      kind,
@@ -140,3 +382,6 @@ func sourceRef(kind, namespace, name string) helmv2.CrossNamespaceObjectReferenc
 		Namespace: namespace,
 	}
 }
+
+// The status of the releases is tested separately.
+var ignoreReleaseStatus = cmpopts.IgnoreFields(HelmReleasePipeline{}, "Releases")
@@ -1,7 +1,11 @@
 package helm
 
 import (
-	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	"slices"
+	"strings"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/gitops-tools/pkg/sets"
 )
 
 // Promotion is a calculated upgrade for an environment.
@@ -10,6 +14,30 @@ type Promotion struct {
 	From             HelmReleaseChart
 	PromotedReleases []helmv2.CrossNamespaceObjectReference
 	To               HelmReleaseChart
+	// SourceReleases are the HelmReleases in the preceding environment that
+	// the To chart is deployed by.
+	SourceReleases []helmv2.CrossNamespaceObjectReference
+	// PromoteValues indicates that the values and valuesFrom of the
+	// SourceReleases replace the values and valuesFrom of the
+	// PromotedReleases, along with the version.
+	//
+	// Values are only promoted when every SourceRelease has the same values.
+	PromoteValues bool
+}
+
+type promotionOptions struct {
+	values bool
+}
+
+// PromotionOption configures the calculation of promotions.
+type PromotionOption func(*promotionOptions)
+
+// WithValuesPromotions treats differences in the values configured for a
+// chart as promotable, along with differences in the version.
+func WithValuesPromotions() PromotionOption {
+	return func(o *promotionOptions) {
+		o.values = true
+	}
 }
 
 // CalculatePromotions calculates a set of Promotions based on the differences
@@ -17,13 +45,38 @@ type Promotion struct {
 //
 // A promotion is not necessarily a newer version, only a directly immediate
 // environment has the same chart with a different version.
-func CalculatePromotions(pipeline HelmReleasePipeline) []Promotion {
-	pairs := calculatePromotionPairs(pipeline)
+//
+// Environments that follow more than one environment are only promoted to when
+// all the environments they follow have the same version of the chart.
+func CalculatePromotions(pipeline HelmReleasePipeline, opts ...PromotionOption) []Promotion {
+	options := promotionOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	envCharts := map[string][]HelmReleaseChart{}
+	for _, env := range pipeline.Environments {
+		envCharts[env.Name] = env.Charts
+	}
+
 	promotions := []Promotion{}
-	for _, pair := range pairs {
-		for _, v := range pair.toCharts {
-			if upgrade := findChart(v, pair.fromCharts); upgrade != nil {
-				promotions = append(promotions, Promotion{Environment: pair.to, From: v, To: *upgrade, PromotedReleases: pair.promotedReleases[v]})
+	for _, env := range pipeline.Environments {
+		if len(env.After) == 0 {
+			continue
+		}
+		for _, v := range env.Charts {
+			current := deployedChart{chart: v, values: environmentValues(pipeline, v, env.Name)}
+			var from [][]deployedChart
+			for _, after := range env.After {
+				from = append(from, deployedCharts(pipeline, after, envCharts[after]))
+			}
+			if upgrade := findAgreedChart(current, from, options.values); upgrade != nil {
+				promotions = append(promotions, Promotion{
+					Environment: env.Name, From: v, To: upgrade.chart,
+					PromotedReleases: environmentReleases(pipeline, v, env.Name),
+					SourceReleases:   environmentReleases(pipeline, upgrade.chart, env.After...),
+					PromoteValues:    options.values && upgrade.promotesValues(current),
+				})
 			}
 		}
 	}
@@ -31,10 +84,89 @@ func CalculatePromotions(pipeline HelmReleasePipeline) []Promotion {
 	return promotions
 }
 
-// find a matching chart in the provided list, ignoring the version.
-func findChart(chart HelmReleaseChart, charts []HelmReleaseChart) *HelmReleaseChart {
+// deployedChart is a chart, and the values digests of the HelmReleases that
+// deploy it in an environment.
+type deployedChart struct {
+	chart HelmReleaseChart
+	// values is the sorted, distinct values digests of the HelmReleases.
+	values string
+}
+
+// promotesValues returns true if promoting the chart to the current chart
+// would change the values of the HelmReleases that deploy the current chart.
+//
+// Values are only promoted from HelmReleases that agree on the values.
+func (d deployedChart) promotesValues(current deployedChart) bool {
+	return !strings.Contains(d.values, valuesSeparator) && d.values != current.values
+}
+
+// deployedCharts returns the charts deployed in an environment with the
+// values of their HelmReleases.
+func deployedCharts(pipeline HelmReleasePipeline, env string, charts []HelmReleaseChart) []deployedChart {
+	result := []deployedChart{}
 	for _, c := range charts {
-		if (c.Name == chart.Name && c.Source == chart.Source) && c.Version != chart.Version {
+		result = append(result, deployedChart{chart: c, values: environmentValues(pipeline, c, env)})
+	}
+
+	return result
+}
+
+const valuesSeparator = ", "
+
+// environmentValues returns the distinct values digests of the HelmReleases
+// that deploy the chart in the environment, in order and separated by ", ".
+func environmentValues(pipeline HelmReleasePipeline, chart HelmReleaseChart, env string) string {
+	digests := sets.New[string]()
+	for _, ref := range environmentReleases(pipeline, chart, env) {
+		digests.Insert(pipeline.Releases[ref].ValuesDigest)
+	}
+
+	return strings.Join(digests.SortedList(func(x, y string) bool { return x < y }), valuesSeparator)
+}
+
+// environmentReleases returns the HelmReleases that deploy the chart in the
+// environments.
+//
+// The same chart can be deployed in other environments of the pipeline, and
+// those HelmReleases must not be promoted along with this environment.
+func environmentReleases(pipeline HelmReleasePipeline, chart HelmReleaseChart, envs ...string) []helmv2.CrossNamespaceObjectReference {
+	var refs []helmv2.CrossNamespaceObjectReference
+	for _, ref := range pipeline.ChartHelmReleases[chart] {
+		status, ok := pipeline.Releases[ref]
+		if ok && slices.Contains(envs, status.Environment) {
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs
+}
+
+// findAgreedChart finds the chart to promote from each of the preceding
+// environments, and returns it if every environment has the same chart.
+func findAgreedChart(chart deployedChart, fromCharts [][]deployedChart, values bool) *deployedChart {
+	var agreed *deployedChart
+	for _, charts := range fromCharts {
+		upgrade := findChart(chart, charts, values)
+		if upgrade == nil {
+			return nil
+		}
+		if agreed != nil && (agreed.chart.Version != upgrade.chart.Version || (values && agreed.values != upgrade.values)) {
+			return nil
+		}
+		agreed = upgrade
+	}
+
+	return agreed
+}
+
+// find a matching chart in the provided list with a different version, or
+// optionally different values.
+func findChart(chart deployedChart, charts []deployedChart, values bool) *deployedChart {
+	for _, c := range charts {
+		if !sameApplication(c.chart, chart.chart) {
+			continue
+		}
+		if c.chart.Version != chart.chart.Version || (values && c.promotesValues(chart)) {
 			return &c
 		}
 	}
@@ -42,29 +174,47 @@ func findChart(chart HelmReleaseChart, charts []HelmReleaseChart) *HelmReleaseCh
 	return nil
 }
 
+// find a matching chart in the provided list regardless of the version.
+func matchChart(chart HelmReleaseChart, charts []HelmReleaseChart) *HelmReleaseChart {
+	for _, c := range charts {
+		if sameApplication(c, chart) {
+			return &c
+		}
+	}
+
+	return nil
+}
+
+// sameApplication returns true if the charts are deployed by the HelmReleases
+// for the same application.
+func sameApplication(a, b HelmReleaseChart) bool {
+	return a.Application == b.Application && a.Name == b.Name && a.Source == b.Source
+}
+
 type promotionPair struct {
-	from             string
-	fromCharts       []HelmReleaseChart
-	promotedReleases map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference
+	from       string
+	fromCharts []HelmReleaseChart
 
 	to       string
 	toCharts []HelmReleaseChart
 }
 
+// calculatePromotionPairs returns a pair for each environment and the
+// environments that it follows.
 func calculatePromotionPairs(p HelmReleasePipeline) []promotionPair {
+	envCharts := map[string][]HelmReleaseChart{}
+	for _, env := range p.Environments {
+		envCharts[env.Name] = env.Charts
+	}
+
 	pairs := []promotionPair{}
-	for i := range p.Environments {
-		if i < len(p.Environments)-1 {
-			promoted := map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{}
-			for _, v := range p.Environments[i+1].Charts {
-				promoted[v] = p.ChartHelmReleases[v]
-			}
+	for _, env := range p.Environments {
+		for _, after := range env.After {
 			pairs = append(pairs,
 				promotionPair{
-					from: p.Environments[i].Name, fromCharts: p.Environments[i].Charts,
-					to: p.Environments[i+1].Name, toCharts: p.Environments[i+1].Charts,
-					promotedReleases: promoted},
-			)
+					from: after, fromCharts: envCharts[after],
+					to: env.Name, toCharts: env.Charts,
+				})
 		}
 	}
 	return pairs
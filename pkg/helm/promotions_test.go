@@ -1,11 +1,15 @@
 package helm
 
 import (
+	"sort"
 	"testing"
 
-	"github.com/fluxcd/helm-controller/api/v2beta1"
-	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+	"github.com/bigkevmcd/peanut-helmpipelines/test"
 )
 
 func TestCalculatePromotions(t *testing.T) {
@@ -50,7 +54,8 @@ func TestCalculatePromotions(t *testing.T) {
 						},
 					},
 					{
-						Name: "production",
+						Name:  "production",
+						After: []string{"staging"},
 						Charts: []HelmReleaseChart{
 							{
 								Name:    "redis",
@@ -69,6 +74,7 @@ func TestCalculatePromotions(t *testing.T) {
 						{Kind: "HelmRelease", Name: "redis-production", Namespace: "testing"},
 					},
 				},
+				Releases: releasesIn("production", releaseRef("redis-production", "testing")),
 			},
 			want: []Promotion{
 				{
@@ -83,7 +89,7 @@ func TestCalculatePromotions(t *testing.T) {
 						Version: "1.0.12",
 						Source:  sourceRef("HelmRepository", "default", "test-repository"),
 					},
-					PromotedReleases: []v2beta1.CrossNamespaceObjectReference{
+					PromotedReleases: []helmv2.CrossNamespaceObjectReference{
 						{Kind: "HelmRelease", Name: "redis-production", Namespace: "testing"},
 					},
 				},
@@ -110,7 +116,8 @@ func TestCalculatePromotions(t *testing.T) {
 						},
 					},
 					{
-						Name: "production",
+						Name:  "production",
+						After: []string{"staging"},
 						Charts: []HelmReleaseChart{
 							{
 								Name:    "redis",
@@ -129,6 +136,7 @@ func TestCalculatePromotions(t *testing.T) {
 						{Kind: "HelmRelease", Name: "redis-production", Namespace: "testing"},
 					},
 				},
+				Releases: releasesIn("production", releaseRef("redis-production", "testing")),
 			},
 			want: []Promotion{
 				{
@@ -143,7 +151,7 @@ func TestCalculatePromotions(t *testing.T) {
 						Version: "1.0.12",
 						Source:  sourceRef("HelmRepository", "default", "test-repository"),
 					},
-					PromotedReleases: []v2beta1.CrossNamespaceObjectReference{
+					PromotedReleases: []helmv2.CrossNamespaceObjectReference{
 						{Kind: "HelmRelease", Name: "redis-production", Namespace: "testing"}},
 				},
 			},
@@ -169,7 +177,8 @@ func TestCalculatePromotions(t *testing.T) {
 						},
 					},
 					{
-						Name: "production",
+						Name:  "production",
+						After: []string{"staging"},
 						Charts: []HelmReleaseChart{
 							{
 								Name:    "redis",
@@ -200,6 +209,7 @@ func TestCalculatePromotions(t *testing.T) {
 						{Kind: "HelmRelease", Name: "postgres-production", Namespace: "default"},
 					},
 				},
+				Releases: releasesIn("production", releaseRef("redis-production", "default"), releaseRef("postgres-production", "default")),
 			},
 			want: []Promotion{
 				{
@@ -214,7 +224,7 @@ func TestCalculatePromotions(t *testing.T) {
 						Version: "1.0.12",
 						Source:  sourceRef("HelmRepository", "default", "test-repository"),
 					},
-					PromotedReleases: []v2beta1.CrossNamespaceObjectReference{
+					PromotedReleases: []helmv2.CrossNamespaceObjectReference{
 						{Kind: "HelmRelease", Name: "redis-production", Namespace: "default"},
 					},
 				},
@@ -230,7 +240,7 @@ func TestCalculatePromotions(t *testing.T) {
 						Version: "13.0.1",
 						Source:  sourceRef("HelmRepository", "default", "test-repository"),
 					},
-					PromotedReleases: []v2beta1.CrossNamespaceObjectReference{
+					PromotedReleases: []helmv2.CrossNamespaceObjectReference{
 						{Kind: "HelmRelease", Name: "postgres-production", Namespace: "default"},
 					},
 				},
@@ -257,7 +267,8 @@ func TestCalculatePromotions(t *testing.T) {
 						},
 					},
 					{
-						Name: "production",
+						Name:  "production",
+						After: []string{"staging"},
 						Charts: []HelmReleaseChart{
 							{
 								Name:    "redis",
@@ -281,6 +292,7 @@ func TestCalculatePromotions(t *testing.T) {
 						{Kind: "HelmRelease", Name: "redis-production", Namespace: "testing"},
 					},
 				},
+				Releases: releasesIn("production", releaseRef("redis-production", "testing")),
 			},
 			want: []Promotion{
 				{
@@ -295,7 +307,7 @@ func TestCalculatePromotions(t *testing.T) {
 						Version: "1.0.12",
 						Source:  sourceRef("HelmRepository", "default", "test-repository"),
 					},
-					PromotedReleases: []v2beta1.CrossNamespaceObjectReference{
+					PromotedReleases: []helmv2.CrossNamespaceObjectReference{
 						{Kind: "HelmRelease", Name: "redis-production", Namespace: "testing"},
 					},
 				},
@@ -313,3 +325,267 @@ func TestCalculatePromotions(t *testing.T) {
 		})
 	}
 }
+
+func TestCalculatePromotions_values(t *testing.T) {
+	chart := HelmReleaseChart{
+		Name:    "redis",
+		Version: "1.0.12",
+		Source:  sourceRef("HelmRepository", "default", "test-repository"),
+	}
+	newPipeline := func(releases map[helmv2.CrossNamespaceObjectReference]HelmReleaseStatus) HelmReleasePipeline {
+		refs := []helmv2.CrossNamespaceObjectReference{}
+		for ref := range releases {
+			refs = append(refs, ref)
+		}
+		sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+		return HelmReleasePipeline{
+			Name: "demo-pipeline",
+			Environments: []HelmReleaseEnvironment{
+				{Name: "staging", Charts: []HelmReleaseChart{chart}},
+				{Name: "production", After: []string{"staging"}, Charts: []HelmReleaseChart{chart}},
+			},
+			ChartHelmReleases: map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{chart: refs},
+			Releases:          releases,
+		}
+	}
+	pipeline := newPipeline(map[helmv2.CrossNamespaceObjectReference]HelmReleaseStatus{
+		releaseRef("redis-staging", "testing"):    {Environment: "staging", ValuesDigest: "sha256:staging"},
+		releaseRef("redis-production", "testing"): {Environment: "production", ValuesDigest: "sha256:production"},
+	})
+	disagreeing := newPipeline(map[helmv2.CrossNamespaceObjectReference]HelmReleaseStatus{
+		releaseRef("redis-staging-eu", "testing"): {Environment: "staging", ValuesDigest: "sha256:eu"},
+		releaseRef("redis-staging-us", "testing"): {Environment: "staging", ValuesDigest: "sha256:us"},
+		releaseRef("redis-production", "testing"): {Environment: "production", ValuesDigest: "sha256:production"},
+	})
+
+	promotionTests := []struct {
+		name     string
+		pipeline HelmReleasePipeline
+		opts     []PromotionOption
+		want     []Promotion
+	}{
+		{
+			name:     "values changes are not promotions by default",
+			pipeline: pipeline,
+			want:     []Promotion{},
+		},
+		{
+			name:     "values changes are promotions with option",
+			pipeline: pipeline,
+			opts:     []PromotionOption{WithValuesPromotions()},
+			want: []Promotion{
+				{
+					Environment: "production",
+					From:        chart,
+					To:          chart,
+					PromotedReleases: []helmv2.CrossNamespaceObjectReference{
+						releaseRef("redis-production", "testing"),
+					},
+					SourceReleases: []helmv2.CrossNamespaceObjectReference{
+						releaseRef("redis-staging", "testing"),
+					},
+					PromoteValues: true,
+				},
+			},
+		},
+		{
+			name:     "values are not promoted from releases with different values",
+			pipeline: disagreeing,
+			opts:     []PromotionOption{WithValuesPromotions()},
+			want:     []Promotion{},
+		},
+	}
+
+	for _, tt := range promotionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			promotions := CalculatePromotions(tt.pipeline, tt.opts...)
+
+			if diff := cmp.Diff(tt.want, promotions); diff != "" {
+				t.Fatalf("failed to calculate promotions:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCalculatePromotions_fan_in(t *testing.T) {
+	chart := func(version string) HelmReleaseChart {
+		return HelmReleaseChart{Name: "redis", Version: version, Source: sourceRef("HelmRepository", "default", "test-repository")}
+	}
+	pipeline := func(eu, us string) HelmReleasePipeline {
+		return HelmReleasePipeline{
+			Name: "demo-pipeline",
+			Environments: []HelmReleaseEnvironment{
+				{Name: "dev", Charts: []HelmReleaseChart{chart("1.0.12")}},
+				{Name: "staging-eu", After: []string{"dev"}, Charts: []HelmReleaseChart{chart(eu)}},
+				{Name: "staging-us", After: []string{"dev"}, Charts: []HelmReleaseChart{chart(us)}},
+				{Name: "production", After: []string{"staging-eu", "staging-us"}, Charts: []HelmReleaseChart{chart("1.0.9")}},
+			},
+			ChartHelmReleases: map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{},
+		}
+	}
+
+	promotionTests := []struct {
+		name     string
+		pipeline HelmReleasePipeline
+		want     []Promotion
+	}{
+		{
+			name:     "fan-out to each following environment",
+			pipeline: pipeline("1.0.9", "1.0.9"),
+			want: []Promotion{
+				{Environment: "staging-eu", From: chart("1.0.9"), To: chart("1.0.12")},
+				{Environment: "staging-us", From: chart("1.0.9"), To: chart("1.0.12")},
+			},
+		},
+		{
+			name:     "fan-in when the preceding environments disagree",
+			pipeline: pipeline("1.0.12", "1.0.9"),
+			want: []Promotion{
+				{Environment: "staging-us", From: chart("1.0.9"), To: chart("1.0.12")},
+			},
+		},
+		{
+			name:     "fan-in when the preceding environments agree",
+			pipeline: pipeline("1.0.12", "1.0.12"),
+			want: []Promotion{
+				{Environment: "production", From: chart("1.0.9"), To: chart("1.0.12")},
+			},
+		},
+	}
+
+	for _, tt := range promotionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			promotions := CalculatePromotions(tt.pipeline)
+
+			if diff := cmp.Diff(tt.want, promotions); diff != "" {
+				t.Fatalf("failed to calculate promotions:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCalculatePromotions_applications(t *testing.T) {
+	chart := func(application, version string) HelmReleaseChart {
+		return HelmReleaseChart{Application: application, Name: "redis", Version: version, Source: sourceRef("HelmRepository", "default", "test-repository")}
+	}
+	pipeline := HelmReleasePipeline{
+		Name: "demo-pipeline",
+		Environments: []HelmReleaseEnvironment{
+			{Name: "staging", Charts: []HelmReleaseChart{chart("cache", "1.0.12"), chart("sessions", "1.0.9")}},
+			{Name: "production", After: []string{"staging"}, Charts: []HelmReleaseChart{chart("cache", "1.0.9"), chart("sessions", "1.0.9")}},
+		},
+		ChartHelmReleases: map[HelmReleaseChart][]helmv2.CrossNamespaceObjectReference{
+			chart("cache", "1.0.9"): {
+				{Kind: "HelmRelease", Name: "production-cache", Namespace: "production"},
+			},
+		},
+		Releases: releasesIn("production", releaseRef("production-cache", "production")),
+	}
+
+	promotions := CalculatePromotions(pipeline)
+
+	want := []Promotion{
+		{
+			Environment: "production",
+			From:        chart("cache", "1.0.9"),
+			To:          chart("cache", "1.0.12"),
+			PromotedReleases: []helmv2.CrossNamespaceObjectReference{
+				{Kind: "HelmRelease", Name: "production-cache", Namespace: "production"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, promotions); diff != "" {
+		t.Fatalf("failed to calculate promotions:\n%s", diff)
+	}
+}
+
+// releasesIn returns the status of HelmReleases deployed to the environment.
+func releasesIn(env string, refs ...helmv2.CrossNamespaceObjectReference) map[helmv2.CrossNamespaceObjectReference]HelmReleaseStatus {
+	releases := map[helmv2.CrossNamespaceObjectReference]HelmReleaseStatus{}
+	for _, ref := range refs {
+		releases[ref] = HelmReleaseStatus{Environment: env}
+	}
+
+	return releases
+}
+
+func releaseRef(name, namespace string) helmv2.CrossNamespaceObjectReference {
+	return helmv2.CrossNamespaceObjectReference{Kind: "HelmRelease", Name: name, Namespace: namespace}
+}
+
+func TestCalculatePromotions_three_environments(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.Named("redis", "dev"), test.InPipeline("demo-pipeline", "dev", ""),
+			test.ChartVersion("redis", "1.0.12")),
+		test.NewHelmRelease(test.Named("redis", "staging"), test.InPipeline("demo-pipeline", "staging", "dev"),
+			test.ChartVersion("redis", "1.0.9")),
+		test.NewHelmRelease(test.Named("redis", "production"), test.InPipeline("demo-pipeline", "production", "staging"),
+			test.ChartVersion("redis", "1.0.9")),
+	}
+	pipelines, _, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promotions := CalculatePromotions(pipelines[0])
+
+	chart := func(version string) HelmReleaseChart {
+		return HelmReleaseChart{Name: "redis", Version: version, Source: sourceRef("HelmRepository", "default", "test-repository")}
+	}
+	want := []Promotion{
+		{
+			Environment:      "staging",
+			From:             chart("1.0.9"),
+			To:               chart("1.0.12"),
+			PromotedReleases: []helmv2.CrossNamespaceObjectReference{helmReleaseRef("redis", "staging")},
+			SourceReleases:   []helmv2.CrossNamespaceObjectReference{helmReleaseRef("redis", "dev")},
+		},
+	}
+	if diff := cmp.Diff(want, promotions); diff != "" {
+		t.Fatalf("failed to calculate promotions:\n%s", diff)
+	}
+}
+
+func TestCalculatePromotions_applications_three_environments(t *testing.T) {
+	release := func(application, env, after, version string) helmv2.HelmRelease {
+		return test.NewHelmRelease(test.Named(application, env), test.InPipeline("demo-pipeline", env, after),
+			test.Labelled(PipelineApplicationLabel, application), test.ChartVersion("redis", version))
+	}
+	items := []helmv2.HelmRelease{
+		release("cache", "dev", "", "1.0.12"),
+		release("sessions", "dev", "", "1.0.12"),
+		release("cache", "staging", "dev", "1.0.9"),
+		release("sessions", "staging", "dev", "1.0.12"),
+		release("cache", "production", "staging", "1.0.9"),
+		release("sessions", "production", "staging", "1.0.9"),
+	}
+	pipelines, _, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promotions := CalculatePromotions(pipelines[0])
+
+	chart := func(application, version string) HelmReleaseChart {
+		return HelmReleaseChart{Application: application, Name: "redis", Version: version, Source: sourceRef("HelmRepository", "default", "test-repository")}
+	}
+	want := []Promotion{
+		{
+			Environment:      "staging",
+			From:             chart("cache", "1.0.9"),
+			To:               chart("cache", "1.0.12"),
+			PromotedReleases: []helmv2.CrossNamespaceObjectReference{helmReleaseRef("cache", "staging")},
+			SourceReleases:   []helmv2.CrossNamespaceObjectReference{helmReleaseRef("cache", "dev")},
+		},
+		{
+			Environment:      "production",
+			From:             chart("sessions", "1.0.9"),
+			To:               chart("sessions", "1.0.12"),
+			PromotedReleases: []helmv2.CrossNamespaceObjectReference{helmReleaseRef("sessions", "production")},
+			SourceReleases:   []helmv2.CrossNamespaceObjectReference{helmReleaseRef("sessions", "staging")},
+		},
+	}
+	if diff := cmp.Diff(want, promotions, cmpopts.SortSlices(func(x, y Promotion) bool { return x.Environment > y.Environment })); diff != "" {
+		t.Fatalf("failed to calculate promotions:\n%s", diff)
+	}
+}
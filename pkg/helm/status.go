@@ -0,0 +1,75 @@
+package helm
+
+import (
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/pkg/apis/meta"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HelmReleaseStatus is the state of a HelmRelease in an environment of a
+// pipeline.
+type HelmReleaseStatus struct {
+	Environment string
+	// Ready is the status of the Ready condition, this is Unknown if the
+	// HelmRelease has not been reconciled.
+	Ready metav1.ConditionStatus
+	// LastAppliedRevision is the chart version of the latest successful
+	// release in the history of the HelmRelease, failed releases are ignored.
+	LastAppliedRevision string
+	// ReadyTransitionTime is the time that the Ready condition last changed,
+	// this is not updated when the HelmRelease is reconciled without a change
+	// in its readiness.
+	ReadyTransitionTime time.Time
+	// Message is the message from the Ready condition if the HelmRelease
+	// failed to reconcile.
+	Message string
+	// ValuesDigest is a fingerprint of the values configured on the
+	// HelmRelease, this is empty if no values are configured.
+	ValuesDigest string
+	// Labels are the labels of the HelmRelease, with the pipeline keys as
+	// they were resolved from annotations, or from the labels of the
+	// Namespace.
+	Labels map[string]string
+}
+
+func releaseStatus(environment string, hr *helmv2.HelmRelease) HelmReleaseStatus {
+	status := HelmReleaseStatus{
+		Environment: environment,
+		Ready:       metav1.ConditionUnknown,
+	}
+	if deployed := latestDeployed(hr.Status.History); deployed != nil {
+		status.LastAppliedRevision = deployed.ChartVersion
+	}
+	ready := apimeta.FindStatusCondition(hr.Status.Conditions, meta.ReadyCondition)
+	if ready == nil {
+		return status
+	}
+	status.Ready = ready.Status
+	status.ReadyTransitionTime = ready.LastTransitionTime.Time
+	if ready.Status == metav1.ConditionFalse {
+		status.Message = ready.Message
+	}
+
+	return status
+}
+
+// latestDeployed returns the latest release in the history that was deployed,
+// or nil if no release was deployed.
+//
+// Unlike Snapshots.Latest, this does not sort the history in place.
+func latestDeployed(history helmv2.Snapshots) *helmv2.Snapshot {
+	var latest *helmv2.Snapshot
+	for _, s := range history {
+		if s.Status != "deployed" && s.Status != "superseded" {
+			continue
+		}
+		if latest == nil || s.Version > latest.Version {
+			latest = s
+		}
+	}
+
+	return latest
+}
@@ -0,0 +1,77 @@
+package helm
+
+import (
+	"testing"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+	"github.com/bigkevmcd/peanut-helmpipelines/test"
+)
+
+func TestHelmChartPipelines_release_status(t *testing.T) {
+	transitionTime := time.Date(2022, time.June, 28, 7, 46, 20, 0, time.UTC)
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging"),
+			readyCondition(metav1.ConditionTrue, "release reconciliation succeeded", transitionTime, "1.0.9")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production"),
+			readyCondition(metav1.ConditionFalse, "install retries exhausted", transitionTime, "")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("new-deploy", "production")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("failed-upgrade", "production"),
+			test.Deployed("redis", "1.0.8"), test.Failed("redis", "1.0.9"),
+			readyCondition(metav1.ConditionFalse, "upgrade retries exhausted", transitionTime, "")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("failed-install", "production"),
+			test.Failed("redis", "1.0.9")),
+	}
+
+	ps, _, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[helmv2.CrossNamespaceObjectReference]HelmReleaseStatus{
+		helmReleaseRef("staging-deploy", "staging"): {
+			Environment: "staging", Ready: metav1.ConditionTrue, LastAppliedRevision: "1.0.9", ReadyTransitionTime: transitionTime,
+		},
+		helmReleaseRef("production-deploy", "production"): {
+			Environment: "production", Ready: metav1.ConditionFalse, ReadyTransitionTime: transitionTime,
+			Message: "install retries exhausted",
+		},
+		helmReleaseRef("new-deploy", "production"): {
+			Environment: "production", Ready: metav1.ConditionUnknown,
+		},
+		helmReleaseRef("failed-upgrade", "production"): {
+			Environment: "production", Ready: metav1.ConditionFalse, LastAppliedRevision: "1.0.8", ReadyTransitionTime: transitionTime,
+			Message: "upgrade retries exhausted",
+		},
+		helmReleaseRef("failed-install", "production"): {
+			Environment: "production", Ready: metav1.ConditionUnknown,
+		},
+	}
+	// The resolved labels are tested with the discovery.
+	if diff := cmp.Diff(want, ps[0].Releases, cmpopts.IgnoreFields(HelmReleaseStatus{}, "Labels")); diff != "" {
+		t.Fatalf("failed to parse release status:\n%s", diff)
+	}
+}
+
+func readyCondition(status metav1.ConditionStatus, message string, transitionTime time.Time, revision string) func(client.Object) {
+	return func(o client.Object) {
+		hr := o.(*helmv2.HelmRelease)
+		if revision != "" {
+			test.Deployed(hr.Spec.Chart.Spec.Chart, revision)(hr)
+		}
+		hr.Status.Conditions = append(hr.Status.Conditions, metav1.Condition{
+			Type:               meta.ReadyCondition,
+			Status:             status,
+			Reason:             "Testing",
+			Message:            message,
+			LastTransitionTime: metav1.NewTime(transitionTime),
+		})
+	}
+}
@@ -32,11 +32,15 @@ type ChartUpgrade struct {
 
 // IdentifyUpgrades looks for upgradable charts in a pipeline.
 //
-// An upgradable chart has a newer version.
+// An upgradable chart has a newer version, only charts from a HelmRepository
+// are checked for upgrades.
 func IdentifyUpgrades(ctx context.Context, p HelmReleasePipeline, c client.Client) ([]ChartUpgrade, error) {
 	upgrades := []ChartUpgrade{}
 	for _, env := range p.Environments {
 		for _, chart := range env.Charts {
+			if chart.Source.Kind != sourcev1.HelmRepositoryKind {
+				continue
+			}
 			// TODO: cache this
 			index, err := getChartIndex(ctx, chart, c)
 			if err != nil {
@@ -61,16 +65,46 @@ func IdentifyUpgrades(ctx context.Context, p HelmReleasePipeline, c client.Clien
 }
 
 func getChartIndex(ctx context.Context, chart HelmReleaseChart, c client.Client) (*repo.IndexFile, error) {
-	// TODO: abandon if the reference is not to a HelmRepository
+	hr, err := loadHelmRepository(ctx, chart, c)
+	if err != nil {
+		return nil, err
+	}
+
+	// TODO: what if the URL == "" ?
+	b, err := fetchURL(hr.Status.URL)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching index file: %w", err)
+	}
+
+	indexFile := &repo.IndexFile{}
+	if err := yaml.Unmarshal(b, indexFile); err != nil {
+		return nil, fmt.Errorf("error unmarshaling chart response: %w", err)
+	}
+
+	if indexFile.APIVersion == "" {
+		return nil, repo.ErrNoAPIVersion
+	}
+	indexFile.SortEntries()
+
+	return indexFile, nil
+}
+
+func loadHelmRepository(ctx context.Context, chart HelmReleaseChart, c client.Client) (*sourcev1.HelmRepository, error) {
+	if chart.Source.Kind != sourcev1.HelmRepositoryKind {
+		return nil, fmt.Errorf("chart %s is from %s/%s: %w", chart.Name, chart.Source.Kind, chart.Source.Name, ErrUnsupportedSource)
+	}
 	hr := &sourcev1.HelmRepository{}
 	if err := c.Get(ctx, types.NamespacedName{Name: chart.Source.Name, Namespace: chart.Source.Namespace}, hr); err != nil {
 		return nil, err
 	}
 
-	// TODO: what if the URL == "" ?
-	u, err := url.Parse(hr.Status.URL)
+	return hr, nil
+}
+
+func fetchURL(s string) ([]byte, error) {
+	u, err := url.Parse(s)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing URL %q: %w", hr.Status.URL, err)
+		return nil, fmt.Errorf("error parsing URL %q: %w", s, err)
 	}
 
 	getter, err := chartGetters.ByScheme(u.Scheme)
@@ -80,7 +114,7 @@ func getChartIndex(ctx context.Context, chart HelmReleaseChart, c client.Client)
 
 	res, err := getter.Get(u.String())
 	if err != nil {
-		return nil, fmt.Errorf("error fetching index file: %w", err)
+		return nil, err
 	}
 
 	b, err := io.ReadAll(res)
@@ -88,17 +122,7 @@ func getChartIndex(ctx context.Context, chart HelmReleaseChart, c client.Client)
 		return nil, fmt.Errorf("error reading response: %w", err)
 	}
 
-	indexFile := &repo.IndexFile{}
-	if err := yaml.Unmarshal(b, indexFile); err != nil {
-		return nil, fmt.Errorf("error unmarshaling chart response: %w", err)
-	}
-
-	if indexFile.APIVersion == "" {
-		return nil, repo.ErrNoAPIVersion
-	}
-	indexFile.SortEntries()
-
-	return indexFile, nil
+	return b, nil
 }
 
 func findNewerVersionOfChart(chart HelmReleaseChart, index *repo.IndexFile) (*HelmReleaseChart, error) {
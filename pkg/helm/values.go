@@ -0,0 +1,113 @@
+package helm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// EnvironmentValuesDiff is the difference between the values configured for
+// the same chart in two environments of a pipeline.
+type EnvironmentValuesDiff struct {
+	From  string
+	To    string
+	Chart string
+	Diff  string
+}
+
+// releaseValues is the configuration in a HelmRelease that is merged to
+// produce the values for the chart.
+type releaseValues struct {
+	Values     map[string]interface{}   `json:"values,omitempty"`
+	ValuesFrom []helmv2.ValuesReference `json:"valuesFrom,omitempty"`
+}
+
+func releaseValuesFromHelmRelease(hr *helmv2.HelmRelease) releaseValues {
+	return releaseValues{Values: hr.GetValues(), ValuesFrom: hr.Spec.ValuesFrom}
+}
+
+// valuesDigest calculates a fingerprint for the values configured on a
+// HelmRelease.
+//
+// The contents of resources referenced from valuesFrom are not loaded, so
+// only changes to the references are detected.
+//
+// HelmReleases without values have an empty digest.
+func valuesDigest(hr *helmv2.HelmRelease) (string, error) {
+	// GetValues ignores values that can't be parsed.
+	if hr.Spec.Values != nil {
+		var values map[string]interface{}
+		if err := json.Unmarshal(hr.Spec.Values.Raw, &values); err != nil {
+			return "", fmt.Errorf("invalid values: %w", err)
+		}
+	}
+	rv := releaseValuesFromHelmRelease(hr)
+	if len(rv.Values) == 0 && len(rv.ValuesFrom) == 0 {
+		return "", nil
+	}
+	// json.Marshal sorts map keys so the digest is stable.
+	b, err := json.Marshal(rv)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal values for %s/%s: %w", hr.GetNamespace(), hr.GetName(), err)
+	}
+
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(b)), nil
+}
+
+// DiffEnvironmentValues compares the values of the charts deployed to each
+// environment with the values of the same chart in the preceding environment.
+//
+// Only charts where the values differ are returned.
+func DiffEnvironmentValues(ctx context.Context, c client.Client, pipeline HelmReleasePipeline) ([]EnvironmentValuesDiff, error) {
+	diffs := []EnvironmentValuesDiff{}
+	for _, pair := range calculatePromotionPairs(pipeline) {
+		for _, to := range pair.toCharts {
+			from := matchChart(to, pair.fromCharts)
+			if from == nil || environmentValues(pipeline, *from, pair.from) == environmentValues(pipeline, to, pair.to) {
+				continue
+			}
+			fromValues, err := loadReleaseValues(ctx, c, environmentReleases(pipeline, *from, pair.from))
+			if err != nil {
+				return nil, err
+			}
+			toValues, err := loadReleaseValues(ctx, c, environmentReleases(pipeline, to, pair.to))
+			if err != nil {
+				return nil, err
+			}
+			diff, err := unifiedDiff(fromValues, toValues, pair.from+"/"+from.Name, pair.to+"/"+to.Name)
+			if err != nil {
+				return nil, err
+			}
+			diffs = append(diffs, EnvironmentValuesDiff{From: pair.from, To: pair.to, Chart: to.Name, Diff: diff})
+		}
+	}
+
+	return diffs, nil
+}
+
+// loadReleaseValues loads the first of the releases and returns the values as
+// YAML.
+func loadReleaseValues(ctx context.Context, c client.Client, releases []helmv2.CrossNamespaceObjectReference) (string, error) {
+	if len(releases) == 0 {
+		return "", nil
+	}
+	hr := &helmv2.HelmRelease{}
+	if err := c.Get(ctx, keyFromCrossNamespaceObject(releases[0]), hr); err != nil {
+		return "", fmt.Errorf("failed to load HelmRelease %s/%s: %w", releases[0].Namespace, releases[0].Name, err)
+	}
+	rv := releaseValuesFromHelmRelease(hr)
+	if len(rv.Values) == 0 && len(rv.ValuesFrom) == 0 {
+		return "", nil
+	}
+	b, err := yaml.Marshal(rv)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal values for %s/%s: %w", hr.GetNamespace(), hr.GetName(), err)
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,85 @@
+package helm
+
+import (
+	"context"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+	"github.com/bigkevmcd/peanut-helmpipelines/test"
+)
+
+func TestValuesDigest(t *testing.T) {
+	digestTests := []struct {
+		name string
+		hr   helmv2.HelmRelease
+		want string
+	}{
+		{
+			name: "no values",
+			hr:   test.NewHelmRelease(),
+			want: "",
+		},
+		{
+			name: "with values",
+			hr:   test.NewHelmRelease(test.Values(`{"replicas":3,"image":{"tag":"latest"}}`)),
+			want: "sha256:15e07dba8c83af587db6fa1d2e28c823314a87cde64f172755e8b971e57314d4",
+		},
+		{
+			name: "with values in a different order",
+			hr:   test.NewHelmRelease(test.Values(`{"image":{"tag":"latest"},"replicas":3}`)),
+			want: "sha256:15e07dba8c83af587db6fa1d2e28c823314a87cde64f172755e8b971e57314d4",
+		},
+	}
+
+	for _, tt := range digestTests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest, err := valuesDigest(&tt.hr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if digest != tt.want {
+				t.Fatalf("got digest %q, want %q", digest, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffEnvironmentValues(t *testing.T) {
+	items := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging"),
+			test.Values(`{"replicas":1,"logLevel":"debug"}`)),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production"),
+			test.Values(`{"replicas":3,"logLevel":"debug"}`)),
+	}
+	pipelines, _, err := ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diffs, err := DiffEnvironmentValues(context.TODO(), newFakeClient(t, releasesToRuntimeObjects(items)...), pipelines[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []EnvironmentValuesDiff{
+		{
+			From:  "staging",
+			To:    "production",
+			Chart: "redis",
+			Diff: `--- staging/redis
++++ production/redis
+@@ -1,3 +1,3 @@
+ values:
+   logLevel: debug
+-  replicas: 1
++  replicas: 3
+`,
+		},
+	}
+	if diff := cmp.Diff(want, diffs); diff != "" {
+		t.Fatalf("failed to diff values:\n%s", diff)
+	}
+}
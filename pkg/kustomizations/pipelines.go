@@ -5,11 +5,17 @@ import (
 	"fmt"
 
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	"github.com/fluxcd/pkg/apis/meta"
+	apiv1 "github.com/fluxcd/source-controller/api/v1"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
 	"github.com/gitops-tools/apps-scanner/pkg/pipelines"
 	"github.com/gitops-tools/pkg/sets"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
 )
 
 // KustomizationPipelines provides a mapping of Kustomizations in environments
@@ -27,18 +33,52 @@ type KustomizationEnvironment struct {
 
 // EnvironmentKustomization is the source details for a Kustomization resource.
 type EnvironmentKustomization struct {
-	Path      string
+	Path string
+	// Reference is the reference of the GitRepository source.
 	Reference *sourcev1.GitRepositoryRef
-	URL       string
-	Source    kustomizev1.CrossNamespaceSourceReference
+	// OCIReference is the reference of the OCIRepository source.
+	OCIReference *sourcev1.OCIRepositoryRef
+	// BucketReference identifies the objects in the Bucket source.
+	BucketReference *BucketReference
+	// URL is the URL of the GitRepository or OCIRepository, or the endpoint
+	// of the Bucket.
+	URL string
+	// Revision is the revision of the latest artifact from the source.
+	Revision string
+	Source   kustomizev1.CrossNamespaceSourceReference
+	// Ready is the status of the Ready condition, this is Unknown if the
+	// Kustomization has not been reconciled.
+	Ready metav1.ConditionStatus
+	// LastAppliedRevision is the revision of the source that was last
+	// successfully applied.
+	LastAppliedRevision string
+	// LastAttemptedRevision is the revision of the source that was last
+	// applied, successfully or not.
+	LastAttemptedRevision string
+	// Message is the message from the Ready condition if the Kustomization
+	// failed to reconcile.
+	Message string
+}
+
+// BucketReference is the name of a Bucket, and the prefix of the objects that
+// are fetched from it.
+type BucketReference struct {
+	BucketName string
+	Prefix     string
 }
 
 // ParseKustomizationPipelines parses the pipelines and the versions of the
-// GitRepository resources referenced by the Kustomizations in each stage of the
-// pipeline.
-func ParseKustomizationPipelines(ctx context.Context, cl client.Client, kusts ...*kustomizev1.Kustomization) ([]KustomizationPipeline, error) {
-	p := pipelines.NewParser()
-	if err := p.Add(kustomizationsToRuntimeObjects(kusts)); err != nil {
+// GitRepository, OCIRepository and Bucket resources referenced by the
+// Kustomizations in each stage of the pipeline.
+//
+// The sources are listed once for each namespace that they are referenced
+// in.
+//
+// The keys identify the labels, or annotations, that place the Kustomizations
+// into pipelines.
+func ParseKustomizationPipelines(ctx context.Context, cl client.Client, keys pipelinelabels.Keys, kusts ...*kustomizev1.Kustomization) ([]KustomizationPipeline, error) {
+	p := pipelines.NewParser(pipelines.WithLabels(keys.Pipeline, keys.Environment, keys.After))
+	if err := p.Add(kustomizationsToRuntimeObjects(keys, kusts)); err != nil {
 		return nil, fmt.Errorf("failed to parse Kustomizations: %w", err)
 	}
 	ps, err := p.Pipelines()
@@ -46,7 +86,8 @@ func ParseKustomizationPipelines(ctx context.Context, cl client.Client, kusts ..
 		return nil, fmt.Errorf("failed to calculate pipelines: %w", err)
 	}
 
-	kustomizations := parsePipelineKustomizations(kusts)
+	kustomizations := parsePipelineKustomizations(keys, kusts)
+	sources := newSourceLister(cl)
 	parsed := []KustomizationPipeline{}
 	for _, pipeline := range ps {
 		envsToKustomizations := map[string]sets.Set[EnvironmentKustomization]{}
@@ -55,7 +96,7 @@ func ParseKustomizationPipelines(ctx context.Context, cl client.Client, kusts ..
 			if envKustomizations == nil {
 				envKustomizations = sets.New[EnvironmentKustomization]()
 			}
-			envKustomizations.Insert(EnvironmentKustomization{Source: k.source, Path: k.path})
+			envKustomizations.Insert(k.environmentKustomization())
 			envsToKustomizations[k.environment] = envKustomizations
 		}
 
@@ -66,17 +107,11 @@ func ParseKustomizationPipelines(ctx context.Context, cl client.Client, kusts ..
 				return x.Path < y.Path
 			})
 			for i := range kustomizations {
-				// TODO: Ignore if not GitRepository
-				k := kustomizations[i]
-				repo, err := loadGitRepository(ctx, cl, client.ObjectKey{Name: k.Source.Name, Namespace: k.Source.Namespace})
+				k, err := loadSource(ctx, sources, kustomizations[i])
 				if err != nil {
-					return nil, fmt.Errorf("failed to load source %v: %w", k.Source, err)
-				}
-				if repo != nil {
-					k.URL = repo.Spec.URL
-					k.Reference = repo.Spec.Reference
-					kustomizations[i] = k
+					return nil, fmt.Errorf("failed to load source %v: %w", kustomizations[i].Source, err)
 				}
+				kustomizations[i] = k
 			}
 			kp.Environments = append(kp.Environments,
 				KustomizationEnvironment{Name: envName, Kustomizations: kustomizations})
@@ -87,13 +122,110 @@ func ParseKustomizationPipelines(ctx context.Context, cl client.Client, kusts ..
 	return parsed, nil
 }
 
-func loadGitRepository(ctx context.Context, cl client.Client, o client.ObjectKey) (*sourcev1.GitRepository, error) {
-	var repo sourcev1.GitRepository
-	if err := cl.Get(ctx, o, &repo); err != nil {
-		return nil, client.IgnoreNotFound(err)
+// loadSource returns the Kustomization with the URL, reference and artifact
+// revision of its source, missing sources, and other kinds of source are left
+// empty.
+func loadSource(ctx context.Context, sources *sourceLister, k EnvironmentKustomization) (EnvironmentKustomization, error) {
+	obj, err := sources.get(ctx, k.Source)
+	if err != nil {
+		return k, err
+	}
+	switch src := obj.(type) {
+	case *sourcev1.GitRepository:
+		k.URL = src.Spec.URL
+		k.Reference = src.Spec.Reference
+		k.Revision = artifactRevision(src.Status.Artifact)
+	case *sourcev1.OCIRepository:
+		k.URL = src.Spec.URL
+		k.OCIReference = src.Spec.Reference
+		k.Revision = artifactRevision(src.Status.Artifact)
+	case *sourcev1.Bucket:
+		k.URL = src.Spec.Endpoint
+		k.BucketReference = &BucketReference{BucketName: src.Spec.BucketName, Prefix: src.Spec.Prefix}
+		k.Revision = artifactRevision(src.Status.Artifact)
+	}
+
+	return k, nil
+}
+
+func artifactRevision(a *apiv1.Artifact) string {
+	if a == nil {
+		return ""
+	}
+
+	return a.Revision
+}
+
+// sourceLister lists each kind of source once per namespace, rather than
+// getting the source of each Kustomization.
+type sourceLister struct {
+	cl      client.Client
+	listed  sets.Set[sourceKey]
+	sources map[sourceKey]client.Object
+}
+
+// sourceKey identifies a source, the name is empty when it identifies the
+// sources of a kind in a namespace.
+type sourceKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+func newSourceLister(cl client.Client) *sourceLister {
+	return &sourceLister{cl: cl, listed: sets.New[sourceKey](), sources: map[sourceKey]client.Object{}}
+}
+
+// get returns the source, listing the sources of the same kind in the
+// namespace if they have not already been listed.
+//
+// nil is returned for unknown kinds of source and missing sources.
+func (s *sourceLister) get(ctx context.Context, ref kustomizev1.CrossNamespaceSourceReference) (client.Object, error) {
+	listKey := sourceKey{kind: ref.Kind, namespace: ref.Namespace}
+	if !s.listed.Has(listKey) {
+		if err := s.list(ctx, listKey); err != nil {
+			return nil, err
+		}
+		s.listed.Insert(listKey)
+	}
+
+	return s.sources[sourceKey{kind: ref.Kind, namespace: ref.Namespace, name: ref.Name}], nil
+}
+
+func (s *sourceLister) list(ctx context.Context, key sourceKey) error {
+	var objs []client.Object
+	inNamespace := client.InNamespace(key.namespace)
+	switch key.kind {
+	case sourcev1.GitRepositoryKind:
+		var list sourcev1.GitRepositoryList
+		if err := s.cl.List(ctx, &list, inNamespace); err != nil {
+			return fmt.Errorf("failed to list %s in namespace %s: %w", key.kind, key.namespace, err)
+		}
+		for i := range list.Items {
+			objs = append(objs, &list.Items[i])
+		}
+	case sourcev1.OCIRepositoryKind:
+		var list sourcev1.OCIRepositoryList
+		if err := s.cl.List(ctx, &list, inNamespace); err != nil {
+			return fmt.Errorf("failed to list %s in namespace %s: %w", key.kind, key.namespace, err)
+		}
+		for i := range list.Items {
+			objs = append(objs, &list.Items[i])
+		}
+	case sourcev1.BucketKind:
+		var list sourcev1.BucketList
+		if err := s.cl.List(ctx, &list, inNamespace); err != nil {
+			return fmt.Errorf("failed to list %s in namespace %s: %w", key.kind, key.namespace, err)
+		}
+		for i := range list.Items {
+			objs = append(objs, &list.Items[i])
+		}
+	}
+	for _, obj := range objs {
+		s.sources[sourceKey{kind: key.kind, namespace: obj.GetNamespace(), name: obj.GetName()}] = obj
 	}
 
-	return &repo, nil
+	return nil
 }
 
 type pipelineKustomization struct {
@@ -101,21 +233,46 @@ type pipelineKustomization struct {
 	environment string
 	source      kustomizev1.CrossNamespaceSourceReference
 	path        string
+	status      kustomizev1.KustomizationStatus
+}
+
+// environmentKustomization returns the source and the deployed state of the
+// Kustomization, the details of the source are loaded separately.
+func (k pipelineKustomization) environmentKustomization() EnvironmentKustomization {
+	ek := EnvironmentKustomization{
+		Source:                k.source,
+		Path:                  k.path,
+		Ready:                 metav1.ConditionUnknown,
+		LastAppliedRevision:   k.status.LastAppliedRevision,
+		LastAttemptedRevision: k.status.LastAttemptedRevision,
+	}
+	ready := apimeta.FindStatusCondition(k.status.Conditions, meta.ReadyCondition)
+	if ready == nil {
+		return ek
+	}
+	ek.Ready = ready.Status
+	if ready.Status == metav1.ConditionFalse {
+		ek.Message = ready.Message
+	}
+
+	return ek
 }
 
 // returns a map of pipeline -> pipelineKustomization
-func parsePipelineKustomizations(kusts []*kustomizev1.Kustomization) map[string][]pipelineKustomization {
+func parsePipelineKustomizations(keys pipelinelabels.Keys, kusts []*kustomizev1.Kustomization) map[string][]pipelineKustomization {
 	discovered := map[string][]pipelineKustomization{}
 
 	for _, k := range kusts {
-		lbls := k.GetLabels()
-		pipeline := lbls[pipelines.PipelineNameLabel]
-		env := lbls[pipelines.PipelineEnvironmentLabel]
+		pipeline := keys.PipelineName(k)
+		env := keys.EnvironmentName(k)
 		// We can't place Kustomizations into any env if they are not labelled.
 		if pipeline == "" || env == "" {
 			continue
 		}
 		path, source := k.Spec.Path, k.Spec.SourceRef
+		if source.Namespace == "" {
+			source.Namespace = k.GetNamespace()
+		}
 		pc := discovered[pipeline]
 		if pc == nil {
 			pc = []pipelineKustomization{}
@@ -125,6 +282,7 @@ func parsePipelineKustomizations(kusts []*kustomizev1.Kustomization) map[string]
 			pipeline: pipeline, environment: env,
 			path:   path,
 			source: source,
+			status: k.Status,
 		})
 		discovered[pipeline] = pc
 	}
@@ -132,10 +290,28 @@ func parsePipelineKustomizations(kusts []*kustomizev1.Kustomization) map[string]
 	return discovered
 }
 
-func kustomizationsToRuntimeObjects(kusts []*kustomizev1.Kustomization) []runtime.Object {
+// kustomizationsToRuntimeObjects converts the Kustomizations for parsing,
+// the pipeline parser only reads labels, so when the keys are read from
+// annotations, the Kustomizations are copied with the annotations as labels.
+func kustomizationsToRuntimeObjects(keys pipelinelabels.Keys, kusts []*kustomizev1.Kustomization) []runtime.Object {
 	objs := make([]runtime.Object, len(kusts))
 	for i := range kusts {
-		objs[i] = kusts[i]
+		if !keys.Annotations {
+			objs[i] = kusts[i]
+			continue
+		}
+		k := kusts[i].DeepCopy()
+		lbls := k.GetLabels()
+		if lbls == nil {
+			lbls = map[string]string{}
+		}
+		for _, key := range keys.PipelineKeys() {
+			if v, ok := keys.Lookup(k, key); ok {
+				lbls[key] = v
+			}
+		}
+		k.SetLabels(lbls)
+		objs[i] = k
 	}
 
 	return objs
@@ -4,14 +4,17 @@ import (
 	"context"
 	"testing"
 
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
 	"github.com/bigkevmcd/peanut-helmpipelines/test"
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	apiv1 "github.com/fluxcd/source-controller/api/v1"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
 	"github.com/google/go-cmp/cmp"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 func TestKustomizationPipelines(t *testing.T) {
@@ -50,7 +53,8 @@ func TestKustomizationPipelines(t *testing.T) {
 							Name: "staging",
 							Kustomizations: []EnvironmentKustomization{
 								{
-									Path: "./testing",
+									Path:  "./testing",
+									Ready: metav1.ConditionUnknown,
 									Reference: &sourcev1.GitRepositoryRef{
 										Branch: "main",
 									},
@@ -89,6 +93,7 @@ func TestKustomizationPipelines(t *testing.T) {
 								{
 									Reference: &sourcev1.GitRepositoryRef{Branch: "testing"},
 									Path:      "./testing",
+									Ready:     metav1.ConditionUnknown,
 									URL:       "https://github.com/example/example.git",
 									Source: kustomizev1.CrossNamespaceSourceReference{
 										Kind: "GitRepository", Name: "test-repo", Namespace: "test-ns",
@@ -102,6 +107,7 @@ func TestKustomizationPipelines(t *testing.T) {
 								{
 									Reference: &sourcev1.GitRepositoryRef{Branch: "testing"},
 									Path:      "./testing",
+									Ready:     metav1.ConditionUnknown,
 									URL:       "https://github.com/example/example.git",
 									Source: kustomizev1.CrossNamespaceSourceReference{
 										Kind: "GitRepository", Name: "test-repo", Namespace: "test-ns",
@@ -142,6 +148,7 @@ func TestKustomizationPipelines(t *testing.T) {
 									Reference: &sourcev1.GitRepositoryRef{Branch: "testing"},
 									URL:       "https://github.com/example/example.git",
 									Path:      "./files1",
+									Ready:     metav1.ConditionUnknown,
 									Source: kustomizev1.CrossNamespaceSourceReference{
 										Kind: "GitRepository", Name: "test-repo", Namespace: "test-ns",
 									},
@@ -150,6 +157,7 @@ func TestKustomizationPipelines(t *testing.T) {
 									Reference: &sourcev1.GitRepositoryRef{Branch: "testing"},
 									URL:       "https://github.com/example/example.git",
 									Path:      "./files2",
+									Ready:     metav1.ConditionUnknown,
 									Source: kustomizev1.CrossNamespaceSourceReference{
 										Kind: "GitRepository", Name: "test-repo", Namespace: "test-ns",
 									},
@@ -173,6 +181,7 @@ func TestKustomizationPipelines(t *testing.T) {
 							Kustomizations: []EnvironmentKustomization{
 								{
 									Path:      "./testing",
+									Ready:     metav1.ConditionUnknown,
 									Reference: nil,
 									Source: kustomizev1.CrossNamespaceSourceReference{
 										Kind:      "GitRepository",
@@ -200,7 +209,7 @@ func TestKustomizationPipelines(t *testing.T) {
 
 			cl := newFakeClient(t, objs...)
 
-			ps, err := ParseKustomizationPipelines(context.TODO(), cl, tt.kustomizations...)
+			ps, err := ParseKustomizationPipelines(context.TODO(), cl, pipelinelabels.DefaultKeys, tt.kustomizations...)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -211,6 +220,184 @@ func TestKustomizationPipelines(t *testing.T) {
 	}
 }
 
+func TestKustomizationPipelines_annotation_keys(t *testing.T) {
+	keys := pipelinelabels.Keys{
+		Pipeline:    "app.example.com/pipeline",
+		Environment: "app.example.com/stage",
+		After:       "app.example.com/after",
+		Application: "app.example.com/name",
+		Annotations: true,
+	}
+	kustomizations := []*kustomizev1.Kustomization{
+		test.NewKustomization(test.Named("staging-deploys", "staging"), test.Source("test-repo", "test-ns"),
+			test.Annotated("app.example.com/pipeline", "demo-pipeline"), test.Annotated("app.example.com/stage", "staging")),
+		test.NewKustomization(test.Named("production-deploys", "production"), test.Source("test-repo", "test-ns"),
+			test.Annotated("app.example.com/pipeline", "demo-pipeline"), test.Labelled("app.example.com/stage", "production"),
+			test.Annotated("app.example.com/after", "staging")),
+	}
+	cl := newFakeClient(t)
+
+	ps, err := ParseKustomizationPipelines(context.TODO(), cl, keys, kustomizations...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := kustomizev1.CrossNamespaceSourceReference{Kind: "GitRepository", Name: "test-repo", Namespace: "test-ns"}
+	want := []KustomizationPipeline{
+		{
+			Name: "demo-pipeline",
+			Environments: []KustomizationEnvironment{
+				{Name: "staging", Kustomizations: []EnvironmentKustomization{{Path: "./testing", Source: source, Ready: metav1.ConditionUnknown}}},
+				{Name: "production", Kustomizations: []EnvironmentKustomization{{Path: "./testing", Source: source, Ready: metav1.ConditionUnknown}}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, ps); diff != "" {
+		t.Fatalf("failed to parse pipelines:\n%s", diff)
+	}
+	if _, ok := kustomizations[0].GetLabels()["app.example.com/pipeline"]; ok {
+		t.Fatal("parsing modified the Kustomization labels")
+	}
+}
+
+func TestKustomizationPipelines_sources(t *testing.T) {
+	kustomizations := []*kustomizev1.Kustomization{
+		test.NewKustomization(test.Named("staging-deploys", "staging"), test.InPipeline("demo-pipeline", "staging", ""),
+			test.Source("test-repo", "staging")),
+		test.NewKustomization(test.Named("staging-oci", "staging"), test.InPipeline("demo-pipeline", "staging", ""),
+			test.OCISource("manifests", ""), test.Path("./oci")),
+		test.NewKustomization(test.Named("staging-bucket", "staging"), test.InPipeline("demo-pipeline", "staging", ""),
+			test.BucketSource("manifests", "staging"), test.Path("./bucket")),
+	}
+	gitRepo := newGitRepository("test-repo", "staging", "https://github.com/example/example.git", &sourcev1.GitRepositoryRef{Branch: "main"})
+	gitRepo.Status.Artifact = &apiv1.Artifact{Revision: "main@sha1:abc123"}
+	ociRepo := test.NewOCIRepository("oci://ghcr.io/example/manifests", "1.2.0", test.Named("manifests", "staging"))
+	ociRepo.Status.Artifact = &apiv1.Artifact{Revision: "1.2.0@sha256:def456"}
+	bucket := test.NewBucket("minio.example.com", "manifests", "staging/", test.Named("manifests", "staging"))
+	bucket.Status.Artifact = &apiv1.Artifact{Revision: "sha256:789abc"}
+
+	var lists, gets int
+	cl := fake.NewClientBuilder().
+		WithScheme(newFakeClient(t).Scheme()).
+		WithRuntimeObjects(gitRepo, ociRepo, bucket).
+		WithInterceptorFuncs(interceptor.Funcs{
+			List: func(ctx context.Context, cl client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				lists++
+				return cl.List(ctx, list, opts...)
+			},
+			Get: func(ctx context.Context, cl client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				gets++
+				return cl.Get(ctx, key, obj, opts...)
+			},
+		}).
+		Build()
+
+	ps, err := ParseKustomizationPipelines(context.TODO(), cl, pipelinelabels.DefaultKeys, kustomizations...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []EnvironmentKustomization{
+		{
+			Path:            "./bucket",
+			Ready:           metav1.ConditionUnknown,
+			BucketReference: &BucketReference{BucketName: "manifests", Prefix: "staging/"},
+			URL:             "minio.example.com",
+			Revision:        "sha256:789abc",
+			Source:          kustomizev1.CrossNamespaceSourceReference{Kind: "Bucket", Name: "manifests", Namespace: "staging"},
+		},
+		{
+			Path:         "./oci",
+			Ready:        metav1.ConditionUnknown,
+			OCIReference: &sourcev1.OCIRepositoryRef{Tag: "1.2.0"},
+			URL:          "oci://ghcr.io/example/manifests",
+			Revision:     "1.2.0@sha256:def456",
+			Source:       kustomizev1.CrossNamespaceSourceReference{Kind: "OCIRepository", Name: "manifests", Namespace: "staging"},
+		},
+		{
+			Path:      "./testing",
+			Ready:     metav1.ConditionUnknown,
+			Reference: &sourcev1.GitRepositoryRef{Branch: "main"},
+			URL:       "https://github.com/example/example.git",
+			Revision:  "main@sha1:abc123",
+			Source:    kustomizev1.CrossNamespaceSourceReference{Kind: "GitRepository", Name: "test-repo", Namespace: "staging"},
+		},
+	}
+	if diff := cmp.Diff(want, ps[0].Environments[0].Kustomizations); diff != "" {
+		t.Fatalf("failed to parse pipelines:\n%s", diff)
+	}
+	if lists != 3 || gets != 0 {
+		t.Fatalf("got %d lists and %d gets, want 3 lists and no gets", lists, gets)
+	}
+}
+
+func TestKustomizationPipelines_lists_sources_once(t *testing.T) {
+	kustomizations := []*kustomizev1.Kustomization{}
+	for _, env := range [][2]string{{"staging", ""}, {"production", "staging"}} {
+		for _, path := range []string{"./files1", "./files2", "./files3"} {
+			kustomizations = append(kustomizations, test.NewKustomization(test.Named(env[0]+path[1:], env[0]),
+				test.InPipeline("demo-pipeline", env[0], env[1]), test.Source("test-repo", "test-ns"), test.Path(path)))
+		}
+	}
+	var lists int
+	cl := fake.NewClientBuilder().
+		WithScheme(newFakeClient(t).Scheme()).
+		WithRuntimeObjects(newGitRepository("test-repo", "test-ns", "https://github.com/example/example.git", nil)).
+		WithInterceptorFuncs(interceptor.Funcs{
+			List: func(ctx context.Context, cl client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				lists++
+				return cl.List(ctx, list, opts...)
+			},
+		}).
+		Build()
+
+	if _, err := ParseKustomizationPipelines(context.TODO(), cl, pipelinelabels.DefaultKeys, kustomizations...); err != nil {
+		t.Fatal(err)
+	}
+	if lists != 1 {
+		t.Fatalf("got %d lists, want 1", lists)
+	}
+}
+
+func TestKustomizationPipelines_deployed_revisions(t *testing.T) {
+	kustomizations := []*kustomizev1.Kustomization{
+		test.NewKustomization(test.Named("staging-deploys", "staging"), test.InPipeline("demo-pipeline", "staging", ""),
+			test.Source("test-repo", "test-ns"),
+			test.Applied("main@sha1:abc123", "main@sha1:abc123"), test.Reconciled(metav1.ConditionTrue, "Applied revision: main@sha1:abc123")),
+		test.NewKustomization(test.Named("production-deploys", "production"), test.InPipeline("demo-pipeline", "production", "staging"),
+			test.Source("test-repo", "test-ns"),
+			test.Applied("main@sha1:def456", "main@sha1:abc123"), test.Reconciled(metav1.ConditionFalse, "kustomize build failed")),
+	}
+	cl := newFakeClient(t, newGitRepository("test-repo", "test-ns", "https://github.com/example/example.git", &sourcev1.GitRepositoryRef{Branch: "main"}))
+
+	ps, err := ParseKustomizationPipelines(context.TODO(), cl, pipelinelabels.DefaultKeys, kustomizations...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := EnvironmentKustomization{
+		Path:      "./testing",
+		Reference: &sourcev1.GitRepositoryRef{Branch: "main"},
+		URL:       "https://github.com/example/example.git",
+		Source:    kustomizev1.CrossNamespaceSourceReference{Kind: "GitRepository", Name: "test-repo", Namespace: "test-ns"},
+	}
+	staging, production := source, source
+	staging.Ready = metav1.ConditionTrue
+	staging.LastAppliedRevision = "main@sha1:abc123"
+	staging.LastAttemptedRevision = "main@sha1:abc123"
+	production.Ready = metav1.ConditionFalse
+	production.LastAppliedRevision = "main@sha1:def456"
+	production.LastAttemptedRevision = "main@sha1:abc123"
+	production.Message = "kustomize build failed"
+	want := []KustomizationEnvironment{
+		{Name: "staging", Kustomizations: []EnvironmentKustomization{staging}},
+		{Name: "production", Kustomizations: []EnvironmentKustomization{production}},
+	}
+	if diff := cmp.Diff(want, ps[0].Environments); diff != "" {
+		t.Fatalf("failed to parse pipelines:\n%s", diff)
+	}
+}
+
 func newFakeClient(t *testing.T, objs ...runtime.Object) client.Client {
 	t.Helper()
 	scheme := runtime.NewScheme()
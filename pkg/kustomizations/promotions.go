@@ -0,0 +1,220 @@
+package kustomizations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Promotion is a change to the reference of the source of the Kustomizations
+// in an environment, to match the reference in the preceding environment.
+type Promotion struct {
+	Environment string
+	// From is the Kustomization in the environment being promoted to, the
+	// source of this Kustomization is updated.
+	From EnvironmentKustomization
+	// To is the Kustomization in the preceding environment, with the
+	// reference that is promoted.
+	To EnvironmentKustomization
+}
+
+// CalculatePromotions calculates the promotions between each environment and
+// the environment that precedes it.
+//
+// Kustomizations are matched across environments by the URL of their source,
+// and a promotion is calculated when the sources have different references.
+// Tags that are both semantic versions are only promoted to a newer version.
+//
+// Branches and semver ranges are not promoted, the commit or tag that the
+// preceding environment resolved them to is promoted, so sources that haven't
+// fetched an artifact are not promoted.
+//
+// Each source in an environment is promoted once, even if more than one
+// Kustomization uses it.
+func CalculatePromotions(pipeline KustomizationPipeline) []Promotion {
+	promotions := []Promotion{}
+	for i := 1; i < len(pipeline.Environments); i++ {
+		promoted := map[kustomizev1.CrossNamespaceSourceReference]bool{}
+		preceding, env := pipeline.Environments[i-1], pipeline.Environments[i]
+		for _, k := range env.Kustomizations {
+			if k.URL == "" || promoted[k.Source] {
+				continue
+			}
+			upstream := matchKustomization(k, preceding.Kustomizations)
+			if upstream == nil || upstream.Source == k.Source || !newerReference(k, *upstream) {
+				continue
+			}
+			promoted[k.Source] = true
+			promotions = append(promotions, Promotion{Environment: env.Name, From: k, To: *upstream})
+		}
+	}
+
+	return promotions
+}
+
+// ApplyPromotions updates the reference of the source of each promoted
+// Kustomization to the reference in the preceding environment, with branches
+// and semver ranges replaced by the revision they were resolved to.
+func ApplyPromotions(ctx context.Context, cl client.Client, proms []Promotion) error {
+	for _, promotion := range proms {
+		source := promotion.From.Source
+		key := client.ObjectKey{Name: source.Name, Namespace: source.Namespace}
+		switch source.Kind {
+		case sourcev1.GitRepositoryKind:
+			repo := &sourcev1.GitRepository{}
+			if err := cl.Get(ctx, key, repo); err != nil {
+				return fmt.Errorf("failed to get %s %s: %w", source.Kind, key, err)
+			}
+			ref := promotedGitReference(promotion.To)
+			if ref == nil {
+				return fmt.Errorf("can't promote %s %s to an unresolved reference", source.Kind, key)
+			}
+			repo.Spec.Reference = ref
+			if err := cl.Update(ctx, repo); err != nil {
+				return fmt.Errorf("failed to update %s %s: %w", source.Kind, key, err)
+			}
+		case sourcev1.OCIRepositoryKind:
+			repo := &sourcev1.OCIRepository{}
+			if err := cl.Get(ctx, key, repo); err != nil {
+				return fmt.Errorf("failed to get %s %s: %w", source.Kind, key, err)
+			}
+			ref := promotedOCIReference(promotion.To)
+			if ref == nil {
+				return fmt.Errorf("can't promote %s %s to an unresolved reference", source.Kind, key)
+			}
+			repo.Spec.Reference = ref
+			if err := cl.Update(ctx, repo); err != nil {
+				return fmt.Errorf("failed to update %s %s: %w", source.Kind, key, err)
+			}
+		default:
+			return fmt.Errorf("can't promote %s %s", source.Kind, key)
+		}
+	}
+
+	return nil
+}
+
+// matchKustomization finds the Kustomization with a source of the same kind
+// and URL.
+func matchKustomization(k EnvironmentKustomization, kusts []EnvironmentKustomization) *EnvironmentKustomization {
+	for i := range kusts {
+		if kusts[i].Source.Kind == k.Source.Kind && kusts[i].URL == k.URL {
+			return &kusts[i]
+		}
+	}
+
+	return nil
+}
+
+// newerReference returns true if the reference that would be promoted from
+// the upstream Kustomization is different, and is not an older semantic
+// version tag.
+func newerReference(k, upstream EnvironmentKustomization) bool {
+	switch k.Source.Kind {
+	case sourcev1.GitRepositoryKind:
+		promoted := promotedGitReference(upstream)
+		if promoted == nil {
+			return false
+		}
+		from, to := derefOrZero(k.Reference), *promoted
+		return from != to && !olderTag(from.Tag, to.Tag)
+	case sourcev1.OCIRepositoryKind:
+		promoted := promotedOCIReference(upstream)
+		if promoted == nil {
+			return false
+		}
+		from, to := derefOrZero(k.OCIReference), *promoted
+		return from != to && !olderTag(from.Tag, to.Tag)
+	}
+
+	return false
+}
+
+// promotedGitReference returns the reference of the GitRepository of the
+// Kustomization, following the precedence of the source-controller, with a
+// semver range replaced by the tag, and a branch pinned to the commit, from
+// the artifact revision.
+//
+// This returns nil if the reference needs resolving and there is no artifact.
+func promotedGitReference(k EnvironmentKustomization) *sourcev1.GitRepositoryRef {
+	ref := derefOrZero(k.Reference)
+	if ref.Commit != "" || (ref.Name == "" && ref.SemVer == "" && ref.Tag != "") {
+		return &ref
+	}
+	name, commit := parseRevision(k.Revision)
+	if _, hash, ok := strings.Cut(commit, ":"); ok {
+		commit = hash
+	}
+	if commit == "" {
+		return nil
+	}
+	switch {
+	case ref.Name != "":
+		return &sourcev1.GitRepositoryRef{Name: ref.Name, Commit: commit}
+	case ref.SemVer != "":
+		return &sourcev1.GitRepositoryRef{Tag: name}
+	}
+
+	return &sourcev1.GitRepositoryRef{Branch: ref.Branch, Commit: commit}
+}
+
+// promotedOCIReference returns the reference of the OCIRepository of the
+// Kustomization, with a semver range replaced by the tag from the artifact
+// revision.
+//
+// This returns nil if the range has no artifact.
+func promotedOCIReference(k EnvironmentKustomization) *sourcev1.OCIRepositoryRef {
+	ref := derefOrZero(k.OCIReference)
+	if ref.Digest != "" || ref.SemVer == "" {
+		return &ref
+	}
+	tag, _ := parseRevision(k.Revision)
+	if tag == "" {
+		return nil
+	}
+
+	return &sourcev1.OCIRepositoryRef{Tag: tag}
+}
+
+// parseRevision splits an artifact revision into the name of the ref or tag
+// and the commit or digest, the revision is "<name>@<algorithm>:<hash>", or
+// "<name>/<hash>" in older versions of the source-controller.
+func parseRevision(revision string) (string, string) {
+	if i := strings.LastIndex(revision, "@"); i >= 0 {
+		return revision[:i], revision[i+1:]
+	}
+	if i := strings.LastIndex(revision, "/"); i >= 0 {
+		return revision[:i], revision[i+1:]
+	}
+
+	return "", ""
+}
+
+// olderTag returns true if both tags are semantic versions, and the upstream
+// tag is not newer than the current tag.
+func olderTag(current, upstream string) bool {
+	currentVersion, err := semver.NewVersion(current)
+	if err != nil {
+		return false
+	}
+	upstreamVersion, err := semver.NewVersion(upstream)
+	if err != nil {
+		return false
+	}
+
+	return !upstreamVersion.GreaterThan(currentVersion)
+}
+
+func derefOrZero[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+
+	return *v
+}
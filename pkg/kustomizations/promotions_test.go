@@ -0,0 +1,235 @@
+package kustomizations
+
+import (
+	"context"
+	"testing"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	apiv1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+	"github.com/bigkevmcd/peanut-helmpipelines/test"
+)
+
+const exampleURL = "https://github.com/example/example.git"
+
+func TestCalculatePromotions(t *testing.T) {
+	promotionTests := []struct {
+		name    string
+		staging EnvironmentKustomization
+		prod    EnvironmentKustomization
+		want    bool
+	}{
+		{
+			"newer tag",
+			gitKustomization("staging", &sourcev1.GitRepositoryRef{Tag: "v1.2.0"}),
+			gitKustomization("production", &sourcev1.GitRepositoryRef{Tag: "v1.1.0"}),
+			true,
+		},
+		{
+			"older tag",
+			gitKustomization("staging", &sourcev1.GitRepositoryRef{Tag: "v1.0.0"}),
+			gitKustomization("production", &sourcev1.GitRepositoryRef{Tag: "v1.1.0"}),
+			false,
+		},
+		{
+			"same tag",
+			gitKustomization("staging", &sourcev1.GitRepositoryRef{Tag: "v1.1.0"}),
+			gitKustomization("production", &sourcev1.GitRepositoryRef{Tag: "v1.1.0"}),
+			false,
+		},
+		{
+			"different commit",
+			gitKustomization("staging", &sourcev1.GitRepositoryRef{Branch: "main", Commit: "abc123"}),
+			gitKustomization("production", &sourcev1.GitRepositoryRef{Branch: "main", Commit: "def456"}),
+			true,
+		},
+		{
+			"resolved semver range",
+			atRevision(gitKustomization("staging", &sourcev1.GitRepositoryRef{SemVer: ">=1.2.0"}), "v1.2.3@sha1:abc123"),
+			gitKustomization("production", &sourcev1.GitRepositoryRef{Tag: "v1.1.0"}),
+			true,
+		},
+		{
+			"semver range resolved to the same tag",
+			atRevision(gitKustomization("staging", &sourcev1.GitRepositoryRef{SemVer: ">=1.1.0"}), "v1.1.0@sha1:abc123"),
+			gitKustomization("production", &sourcev1.GitRepositoryRef{Tag: "v1.1.0"}),
+			false,
+		},
+		{
+			"unresolved semver range",
+			gitKustomization("staging", &sourcev1.GitRepositoryRef{SemVer: ">=1.2.0"}),
+			gitKustomization("production", &sourcev1.GitRepositoryRef{Tag: "v1.1.0"}),
+			false,
+		},
+		{
+			"branch at a different commit",
+			atRevision(gitKustomization("staging", &sourcev1.GitRepositoryRef{Branch: "main"}), "main@sha1:abc123"),
+			gitKustomization("production", &sourcev1.GitRepositoryRef{Branch: "main", Commit: "def456"}),
+			true,
+		},
+		{
+			"branch at the same commit",
+			atRevision(gitKustomization("staging", &sourcev1.GitRepositoryRef{Branch: "main"}), "main@sha1:def456"),
+			gitKustomization("production", &sourcev1.GitRepositoryRef{Branch: "main", Commit: "def456"}),
+			false,
+		},
+		{
+			"resolved OCI semver range",
+			atRevision(ociKustomization("staging", &sourcev1.OCIRepositoryRef{SemVer: "1.x"}), "1.2.0@sha256:abc123"),
+			ociKustomization("production", &sourcev1.OCIRepositoryRef{Tag: "1.1.0"}),
+			true,
+		},
+		{
+			"newer OCI tag",
+			ociKustomization("staging", &sourcev1.OCIRepositoryRef{Tag: "1.2.0"}),
+			ociKustomization("production", &sourcev1.OCIRepositoryRef{Tag: "1.1.0"}),
+			true,
+		},
+		{
+			"different repository",
+			gitKustomization("staging", &sourcev1.GitRepositoryRef{Tag: "v1.2.0"}),
+			EnvironmentKustomization{
+				Path: "./deploy", URL: "https://github.com/example/other.git",
+				Reference: &sourcev1.GitRepositoryRef{Tag: "v1.1.0"},
+				Source:    kustomizev1.CrossNamespaceSourceReference{Kind: "GitRepository", Name: "other", Namespace: "production"},
+			},
+			false,
+		},
+	}
+
+	for _, tt := range promotionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := KustomizationPipeline{
+				Name: "demo-pipeline",
+				Environments: []KustomizationEnvironment{
+					{Name: "staging", Kustomizations: []EnvironmentKustomization{tt.staging}},
+					{Name: "production", Kustomizations: []EnvironmentKustomization{tt.prod}},
+				},
+			}
+
+			promotions := CalculatePromotions(pipeline)
+
+			want := []Promotion{}
+			if tt.want {
+				want = append(want, Promotion{Environment: "production", From: tt.prod, To: tt.staging})
+			}
+			if diff := cmp.Diff(want, promotions); diff != "" {
+				t.Fatalf("failed to calculate promotions:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyPromotions(t *testing.T) {
+	kustomizations := []*kustomizev1.Kustomization{
+		test.NewKustomization(test.Named("staging-deploys", "staging"), test.InPipeline("demo-pipeline", "staging", ""),
+			test.Source("example", "staging")),
+		test.NewKustomization(test.Named("production-deploys", "production"), test.InPipeline("demo-pipeline", "production", "staging"),
+			test.Source("example", "production")),
+		test.NewKustomization(test.Named("staging-oci", "staging"), test.InPipeline("demo-pipeline", "staging", ""),
+			test.OCISource("podinfo", "staging"), test.Path("./oci")),
+		test.NewKustomization(test.Named("production-oci", "production"), test.InPipeline("demo-pipeline", "production", "staging"),
+			test.OCISource("podinfo", "production"), test.Path("./oci")),
+	}
+	cl := newFakeClient(t,
+		newGitRepository("example", "staging", exampleURL, &sourcev1.GitRepositoryRef{Tag: "v1.2.0"}),
+		newGitRepository("example", "production", exampleURL, &sourcev1.GitRepositoryRef{Tag: "v1.1.0"}),
+		test.NewOCIRepository("oci://ghcr.io/example/manifests", "1.2.0", test.Named("podinfo", "staging")),
+		test.NewOCIRepository("oci://ghcr.io/example/manifests", "1.1.0", test.Named("podinfo", "production")),
+	)
+	pipelines, err := ParseKustomizationPipelines(context.TODO(), cl, pipelinelabels.DefaultKeys, kustomizations...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	promotions := CalculatePromotions(pipelines[0])
+	if l := len(promotions); l != 2 {
+		t.Fatalf("got %d promotions, want 2", l)
+	}
+
+	if err := ApplyPromotions(context.TODO(), cl, promotions); err != nil {
+		t.Fatal(err)
+	}
+
+	gitRepo := &sourcev1.GitRepository{}
+	if err := cl.Get(context.TODO(), client.ObjectKey{Name: "example", Namespace: "production"}, gitRepo); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&sourcev1.GitRepositoryRef{Tag: "v1.2.0"}, gitRepo.Spec.Reference); diff != "" {
+		t.Fatalf("failed to promote GitRepository:\n%s", diff)
+	}
+	ociRepo := &sourcev1.OCIRepository{}
+	if err := cl.Get(context.TODO(), client.ObjectKey{Name: "podinfo", Namespace: "production"}, ociRepo); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&sourcev1.OCIRepositoryRef{Tag: "1.2.0"}, ociRepo.Spec.Reference); diff != "" {
+		t.Fatalf("failed to promote OCIRepository:\n%s", diff)
+	}
+}
+
+func TestApplyPromotions_resolved_references(t *testing.T) {
+	kustomizations := []*kustomizev1.Kustomization{
+		test.NewKustomization(test.Named("staging-deploys", "staging"), test.InPipeline("demo-pipeline", "staging", ""),
+			test.Source("example", "staging")),
+		test.NewKustomization(test.Named("production-deploys", "production"), test.InPipeline("demo-pipeline", "production", "staging"),
+			test.Source("example", "production")),
+		test.NewKustomization(test.Named("staging-oci", "staging"), test.InPipeline("demo-pipeline", "staging", ""),
+			test.OCISource("podinfo", "staging"), test.Path("./oci")),
+		test.NewKustomization(test.Named("production-oci", "production"), test.InPipeline("demo-pipeline", "production", "staging"),
+			test.OCISource("podinfo", "production"), test.Path("./oci")),
+	}
+	stagingGit := newGitRepository("example", "staging", exampleURL, &sourcev1.GitRepositoryRef{Branch: "main"})
+	stagingGit.Status.Artifact = &apiv1.Artifact{Revision: "main@sha1:abc123"}
+	stagingOCI := test.NewOCIRepository("oci://ghcr.io/example/manifests", "", test.Named("podinfo", "staging"))
+	stagingOCI.Spec.Reference = &sourcev1.OCIRepositoryRef{SemVer: "1.x"}
+	stagingOCI.Status.Artifact = &apiv1.Artifact{Revision: "1.2.0@sha256:def456"}
+	cl := newFakeClient(t, stagingGit, stagingOCI,
+		newGitRepository("example", "production", exampleURL, &sourcev1.GitRepositoryRef{Branch: "main", Commit: "0a1b2c"}),
+		test.NewOCIRepository("oci://ghcr.io/example/manifests", "1.1.0", test.Named("podinfo", "production")),
+	)
+	pipelines, err := ParseKustomizationPipelines(context.TODO(), cl, pipelinelabels.DefaultKeys, kustomizations...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ApplyPromotions(context.TODO(), cl, CalculatePromotions(pipelines[0])); err != nil {
+		t.Fatal(err)
+	}
+
+	gitRepo := &sourcev1.GitRepository{}
+	if err := cl.Get(context.TODO(), client.ObjectKey{Name: "example", Namespace: "production"}, gitRepo); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&sourcev1.GitRepositoryRef{Branch: "main", Commit: "abc123"}, gitRepo.Spec.Reference); diff != "" {
+		t.Fatalf("failed to promote GitRepository:\n%s", diff)
+	}
+	ociRepo := &sourcev1.OCIRepository{}
+	if err := cl.Get(context.TODO(), client.ObjectKey{Name: "podinfo", Namespace: "production"}, ociRepo); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&sourcev1.OCIRepositoryRef{Tag: "1.2.0"}, ociRepo.Spec.Reference); diff != "" {
+		t.Fatalf("failed to promote OCIRepository:\n%s", diff)
+	}
+}
+
+func gitKustomization(env string, ref *sourcev1.GitRepositoryRef) EnvironmentKustomization {
+	return EnvironmentKustomization{
+		Path: "./deploy", URL: exampleURL, Reference: ref,
+		Source: kustomizev1.CrossNamespaceSourceReference{Kind: "GitRepository", Name: "example", Namespace: env},
+	}
+}
+
+func ociKustomization(env string, ref *sourcev1.OCIRepositoryRef) EnvironmentKustomization {
+	return EnvironmentKustomization{
+		Path: "./deploy", URL: "oci://ghcr.io/example/manifests", OCIReference: ref,
+		Source: kustomizev1.CrossNamespaceSourceReference{Kind: "OCIRepository", Name: "example", Namespace: env},
+	}
+}
+
+func atRevision(k EnvironmentKustomization, revision string) EnvironmentKustomization {
+	k.Revision = revision
+	return k
+}
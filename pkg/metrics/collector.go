@@ -0,0 +1,223 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gitops-tools/pkg/sets"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+)
+
+const namespace = "peanut_pipelines"
+
+var (
+	pipelinesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "pipelines"),
+		"Number of pipelines discovered.",
+		nil, nil)
+	environmentsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "environments"),
+		"Number of environments in the pipeline.",
+		[]string{"pipeline"}, nil)
+	chartInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "chart_info"),
+		"The version of each chart deployed to each environment in the pipeline.",
+		[]string{"pipeline", "environment", "application", "chart", "version"}, nil)
+	pendingPromotionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "pending_promotions"),
+		"Number of charts that can be promoted into the environment.",
+		[]string{"pipeline", "environment"}, nil)
+	oldestPendingPromotionDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "oldest_pending_promotion_age_seconds"),
+		"Time since the oldest change that has not been promoted into the environment became ready in the preceding environment.",
+		[]string{"pipeline", "environment"}, nil)
+	availableUpgradesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "available_upgrades"),
+		"Number of charts in the environment with newer versions in their HelmRepository.",
+		[]string{"pipeline", "environment"}, nil)
+)
+
+// PipelinesCollector is a prometheus.Collector that discovers the pipelines
+// on an interval, and reports the state of each pipeline from the last
+// discovery when it's scraped.
+//
+// Identifying the upgrades fetches the index of each HelmRepository that is
+// used by the pipelines, discovering on an interval means that scrapes don't
+// fetch them.
+//
+// Failures are logged and the affected metrics are left out, rather than
+// failing the scrape of every other metric.
+type PipelinesCollector struct {
+	logr.Logger
+	client.Client
+	keys       pipelinelabels.Keys
+	namespaces []string
+	timeout    time.Duration
+	interval   time.Duration
+	now        func() time.Time
+
+	mu      sync.RWMutex
+	metrics []prometheus.Metric
+}
+
+// NewPipelinesCollector creates a new PipelinesCollector that discovers the
+// pipelines with the keys, in the namespaces if any are provided, every
+// interval, each discovery gives up after the timeout.
+func NewPipelinesCollector(l logr.Logger, c client.Client, keys pipelinelabels.Keys, namespaces []string, interval, timeout time.Duration) *PipelinesCollector {
+	return &PipelinesCollector{Logger: l, Client: c, keys: keys, namespaces: namespaces, interval: interval, timeout: timeout, now: time.Now}
+}
+
+// Run refreshes the metrics immediately and then every interval until the
+// context is done.
+func (c *PipelinesCollector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		c.Refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh discovers the pipelines and replaces the metrics that are reported
+// when the collector is scraped.
+func (c *PipelinesCollector) Refresh(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	metrics := c.discover(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = metrics
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *PipelinesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pipelinesDesc
+	ch <- environmentsDesc
+	ch <- chartInfoDesc
+	ch <- pendingPromotionsDesc
+	ch <- oldestPendingPromotionDesc
+	ch <- availableUpgradesDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *PipelinesCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, m := range c.metrics {
+		ch <- m
+	}
+}
+
+func (c *PipelinesCollector) discover(ctx context.Context) []prometheus.Metric {
+	helmPipelines, _, err := helm.DiscoverNamespacedPipelines(ctx, c.Client, c.keys, c.namespaces)
+	if err != nil {
+		c.Error(err, "failed to discover pipelines")
+		return nil
+	}
+
+	metrics := []prometheus.Metric{
+		prometheus.MustNewConstMetric(pipelinesDesc, prometheus.GaugeValue, float64(len(helmPipelines))),
+	}
+	for _, p := range helmPipelines {
+		metrics = append(metrics, prometheus.MustNewConstMetric(environmentsDesc, prometheus.GaugeValue, float64(len(p.Environments)), p.Name))
+		for _, env := range p.Environments {
+			// Charts with the same name and version from different sources
+			// have the same labels, and a duplicate series fails the scrape.
+			seen := sets.New[[3]string]()
+			for _, chart := range env.Charts {
+				labels := [3]string{chart.Application, chart.Name, chart.Version}
+				if seen.Has(labels) {
+					continue
+				}
+				seen.Insert(labels)
+				metrics = append(metrics, prometheus.MustNewConstMetric(chartInfoDesc, prometheus.GaugeValue, 1,
+					p.Name, env.Name, chart.Application, chart.Name, chart.Version))
+			}
+		}
+		metrics = append(metrics, c.promotionMetrics(p)...)
+		metrics = append(metrics, c.upgradeMetrics(ctx, p)...)
+	}
+
+	return metrics
+}
+
+func (c *PipelinesCollector) promotionMetrics(p helm.HelmReleasePipeline) []prometheus.Metric {
+	pending := map[string]int{}
+	oldest := map[string]time.Time{}
+	for _, promotion := range helm.CalculatePromotions(p) {
+		pending[promotion.Environment]++
+		changed := sourceReadyTime(p, promotion)
+		if changed.IsZero() {
+			continue
+		}
+		if current, ok := oldest[promotion.Environment]; !ok || changed.Before(current) {
+			oldest[promotion.Environment] = changed
+		}
+	}
+
+	var metrics []prometheus.Metric
+	for _, env := range p.Environments {
+		metrics = append(metrics, prometheus.MustNewConstMetric(pendingPromotionsDesc, prometheus.GaugeValue, float64(pending[env.Name]), p.Name, env.Name))
+		var age float64
+		if changed, ok := oldest[env.Name]; ok {
+			age = c.now().Sub(changed).Seconds()
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(oldestPendingPromotionDesc, prometheus.GaugeValue, age, p.Name, env.Name))
+	}
+
+	return metrics
+}
+
+func (c *PipelinesCollector) upgradeMetrics(ctx context.Context, p helm.HelmReleasePipeline) []prometheus.Metric {
+	upgrades, err := helm.IdentifyUpgrades(ctx, p, c.Client)
+	if err != nil {
+		c.Error(err, "failed to identify upgrades", "pipeline", p.Name)
+		return nil
+	}
+
+	var metrics []prometheus.Metric
+	for _, env := range p.Environments {
+		available := 0
+		for _, chart := range env.Charts {
+			for _, upgrade := range upgrades {
+				if upgrade.Current == chart {
+					available++
+					break
+				}
+			}
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(availableUpgradesDesc, prometheus.GaugeValue, float64(available), p.Name, env.Name))
+	}
+
+	return metrics
+}
+
+// sourceReadyTime returns the earliest time that the HelmReleases in the
+// preceding environment became ready with the chart being promoted, the
+// SourceReleases only include the HelmReleases in the preceding environment.
+func sourceReadyTime(p helm.HelmReleasePipeline, promotion helm.Promotion) time.Time {
+	var earliest time.Time
+	for _, ref := range promotion.SourceReleases {
+		changed := p.Releases[ref].ReadyTransitionTime
+		if changed.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || changed.Before(earliest) {
+			earliest = changed
+		}
+	}
+
+	return earliest
+}
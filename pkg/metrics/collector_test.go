@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+	"github.com/bigkevmcd/peanut-helmpipelines/test"
+)
+
+func TestPipelinesCollector(t *testing.T) {
+	testServer := httptest.NewServer(http.FileServer(http.Dir("../helm/testdata/example-charts")))
+	defer testServer.Close()
+	now := time.Date(2022, time.July, 1, 12, 0, 0, 0, time.UTC)
+
+	staging := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("test-service", "staging"),
+		test.ChartVersion("test-service", "1.1.1"), test.Ready(now.Add(-2*time.Hour)))
+	production := test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("test-service", "production"),
+		test.ChartVersion("test-service", "1.0.1"), test.Ready(now.Add(-48*time.Hour)))
+	collector := NewPipelinesCollector(logr.Discard(), newFakeClient(t, &staging, &production, newHelmRepository(testServer.URL)), pipelinelabels.DefaultKeys, nil, time.Minute, time.Minute)
+	collector.now = func() time.Time { return now }
+	collector.Refresh(context.TODO())
+
+	want := `
+# HELP peanut_pipelines_available_upgrades Number of charts in the environment with newer versions in their HelmRepository.
+# TYPE peanut_pipelines_available_upgrades gauge
+peanut_pipelines_available_upgrades{environment="production",pipeline="demo-pipeline"} 1
+peanut_pipelines_available_upgrades{environment="staging",pipeline="demo-pipeline"} 1
+# HELP peanut_pipelines_chart_info The version of each chart deployed to each environment in the pipeline.
+# TYPE peanut_pipelines_chart_info gauge
+peanut_pipelines_chart_info{application="",chart="test-service",environment="production",pipeline="demo-pipeline",version="1.0.1"} 1
+peanut_pipelines_chart_info{application="",chart="test-service",environment="staging",pipeline="demo-pipeline",version="1.1.1"} 1
+# HELP peanut_pipelines_environments Number of environments in the pipeline.
+# TYPE peanut_pipelines_environments gauge
+peanut_pipelines_environments{pipeline="demo-pipeline"} 2
+# HELP peanut_pipelines_oldest_pending_promotion_age_seconds Time since the oldest change that has not been promoted into the environment became ready in the preceding environment.
+# TYPE peanut_pipelines_oldest_pending_promotion_age_seconds gauge
+peanut_pipelines_oldest_pending_promotion_age_seconds{environment="production",pipeline="demo-pipeline"} 7200
+peanut_pipelines_oldest_pending_promotion_age_seconds{environment="staging",pipeline="demo-pipeline"} 0
+# HELP peanut_pipelines_pending_promotions Number of charts that can be promoted into the environment.
+# TYPE peanut_pipelines_pending_promotions gauge
+peanut_pipelines_pending_promotions{environment="production",pipeline="demo-pipeline"} 1
+peanut_pipelines_pending_promotions{environment="staging",pipeline="demo-pipeline"} 0
+# HELP peanut_pipelines_pipelines Number of pipelines discovered.
+# TYPE peanut_pipelines_pipelines gauge
+peanut_pipelines_pipelines 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipelinesCollector_missing_repository(t *testing.T) {
+	hr := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""))
+	collector := NewPipelinesCollector(logr.Discard(), newFakeClient(t, &hr), pipelinelabels.DefaultKeys, nil, time.Minute, time.Minute)
+	collector.Refresh(context.TODO())
+
+	want := `
+# HELP peanut_pipelines_pipelines Number of pipelines discovered.
+# TYPE peanut_pipelines_pipelines gauge
+peanut_pipelines_pipelines 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want),
+		"peanut_pipelines_pipelines", "peanut_pipelines_available_upgrades"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipelinesCollector_chart_info_duplicates(t *testing.T) {
+	releases := []runtime.Object{}
+	for _, opts := range [][]func(client.Object){
+		{test.Named("redis-eu", "staging"), test.Values(`{"region":"eu"}`)},
+		{test.Named("redis-us", "staging"), test.Values(`{"region":"us"}`)},
+		{test.Named("redis-mirror", "staging"), test.ChartSource("HelmRepository", "default", "mirror-repository")},
+	} {
+		hr := test.NewHelmRelease(append(opts, test.InPipeline("demo-pipeline", "staging", ""))...)
+		releases = append(releases, &hr)
+	}
+	collector := NewPipelinesCollector(logr.Discard(), newFakeClient(t, releases...), pipelinelabels.DefaultKeys, nil, time.Minute, time.Minute)
+	collector.Refresh(context.TODO())
+
+	want := `
+# HELP peanut_pipelines_chart_info The version of each chart deployed to each environment in the pipeline.
+# TYPE peanut_pipelines_chart_info gauge
+peanut_pipelines_chart_info{application="",chart="redis",environment="staging",pipeline="demo-pipeline",version="1.0.9"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), "peanut_pipelines_chart_info"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipelinesCollector_oldest_pending_promotion(t *testing.T) {
+	now := time.Date(2022, time.July, 1, 12, 0, 0, 0, time.UTC)
+	dev := test.NewHelmRelease(test.InPipeline("demo-pipeline", "dev", ""), test.Named("test-service", "dev"),
+		test.ChartVersion("test-service", "1.2.0"), test.Ready(now.Add(-5*time.Hour)))
+	staging := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", "dev"), test.Named("test-service", "staging"),
+		test.ChartVersion("test-service", "1.1.1"), test.Ready(now.Add(-2*time.Hour)))
+	production := test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("test-service", "production"),
+		test.ChartVersion("test-service", "1.0.1"), test.Ready(now.Add(-48*time.Hour)))
+	collector := NewPipelinesCollector(logr.Discard(), newFakeClient(t, &dev, &staging, &production), pipelinelabels.DefaultKeys, nil, time.Minute, time.Minute)
+	collector.now = func() time.Time { return now }
+	collector.Refresh(context.TODO())
+
+	// The age in production comes from staging, not from dev.
+	want := `
+# HELP peanut_pipelines_oldest_pending_promotion_age_seconds Time since the oldest change that has not been promoted into the environment became ready in the preceding environment.
+# TYPE peanut_pipelines_oldest_pending_promotion_age_seconds gauge
+peanut_pipelines_oldest_pending_promotion_age_seconds{environment="dev",pipeline="demo-pipeline"} 0
+peanut_pipelines_oldest_pending_promotion_age_seconds{environment="production",pipeline="demo-pipeline"} 7200
+peanut_pipelines_oldest_pending_promotion_age_seconds{environment="staging",pipeline="demo-pipeline"} 18000
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want),
+		"peanut_pipelines_oldest_pending_promotion_age_seconds"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipelinesCollector_reports_last_refresh(t *testing.T) {
+	hr := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""))
+	cl := newFakeClient(t, &hr)
+	collector := NewPipelinesCollector(logr.Discard(), cl, pipelinelabels.DefaultKeys, nil, time.Minute, time.Minute)
+
+	if n := testutil.CollectAndCount(collector); n != 0 {
+		t.Fatalf("got %d metrics before the first refresh, want 0", n)
+	}
+
+	collector.Refresh(context.TODO())
+	if err := cl.Delete(context.TODO(), &hr); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `
+# HELP peanut_pipelines_pipelines Number of pipelines discovered.
+# TYPE peanut_pipelines_pipelines gauge
+peanut_pipelines_pipelines 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), "peanut_pipelines_pipelines"); err != nil {
+		t.Fatal(err)
+	}
+
+	collector.Refresh(context.TODO())
+	want = `
+# HELP peanut_pipelines_pipelines Number of pipelines discovered.
+# TYPE peanut_pipelines_pipelines gauge
+peanut_pipelines_pipelines 0
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), "peanut_pipelines_pipelines"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newFakeClient(t *testing.T, objs ...runtime.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := helmv2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := sourcev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(objs...).
+		Build()
+}
+
+func newHelmRepository(serverURL string) *sourcev1.HelmRepository {
+	return &sourcev1.HelmRepository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-repository",
+			Namespace: "default",
+		},
+		Spec: sourcev1.HelmRepositorySpec{
+			URL: serverURL,
+		},
+		Status: sourcev1.HelmRepositoryStatus{
+			URL: serverURL + "/index.yaml",
+		},
+	}
+}
@@ -0,0 +1,107 @@
+package pipelinelabels
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gitops-tools/apps-scanner/pkg/pipelines"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ApplicationLabel is a label that identifies the resources that deploy the
+// same application in each environment of a pipeline.
+const ApplicationLabel = "gitops.pro/pipeline-application"
+
+// Keys are the keys of the labels, or annotations, that place resources into
+// pipelines.
+type Keys struct {
+	Pipeline    string
+	Environment string
+	After       string
+	Application string
+	// Annotations reads the keys from the annotations of resources when they
+	// are not labelled, resources can't be filtered by annotations, so every
+	// resource is listed.
+	Annotations bool
+}
+
+// DefaultKeys are the gitops.pro labels.
+var DefaultKeys = Keys{
+	Pipeline:    pipelines.PipelineNameLabel,
+	Environment: pipelines.PipelineEnvironmentLabel,
+	After:       pipelines.PipelineEnvironmentAfterLabel,
+	Application: ApplicationLabel,
+}
+
+// Lookup returns the value of the key from the labels of the object, or the
+// annotations if they are enabled, and whether the key was found.
+func (k Keys) Lookup(obj metav1.Object, key string) (string, bool) {
+	if v, ok := obj.GetLabels()[key]; ok {
+		return v, true
+	}
+	if k.Annotations {
+		v, ok := obj.GetAnnotations()[key]
+		return v, ok
+	}
+
+	return "", false
+}
+
+// Get returns the value of the key from the object, or the empty string if
+// the key is not found.
+func (k Keys) Get(obj metav1.Object, key string) string {
+	v, _ := k.Lookup(obj, key)
+	return v
+}
+
+// PipelineName returns the name of the pipeline that the object is in.
+func (k Keys) PipelineName(obj metav1.Object) string {
+	return k.Get(obj, k.Pipeline)
+}
+
+// EnvironmentName returns the name of the environment that the object is in.
+func (k Keys) EnvironmentName(obj metav1.Object) string {
+	return k.Get(obj, k.Environment)
+}
+
+// AfterEnvironment returns the name of the environment that the object's
+// environment follows.
+func (k Keys) AfterEnvironment(obj metav1.Object) string {
+	return k.Get(obj, k.After)
+}
+
+// ApplicationName returns the application that the object deploys.
+func (k Keys) ApplicationName(obj metav1.Object) string {
+	return k.Get(obj, k.Application)
+}
+
+// PipelineKeys returns the keys that place a resource in an environment of a
+// pipeline.
+func (k Keys) PipelineKeys() []string {
+	return []string{k.Pipeline, k.Environment, k.After}
+}
+
+// Validate checks that every key is provided, and that the keys are
+// different.
+func (k Keys) Validate() error {
+	var errs []error
+	seen := map[string]string{}
+	keys := []struct{ name, key string }{
+		{"pipeline", k.Pipeline},
+		{"environment", k.Environment},
+		{"after", k.After},
+		{"application", k.Application},
+	}
+	for _, v := range keys {
+		if v.key == "" {
+			errs = append(errs, fmt.Errorf("the %s key must be provided", v.name))
+			continue
+		}
+		if other, ok := seen[v.key]; ok {
+			errs = append(errs, fmt.Errorf("the %s key %q is the same as the %s key", v.name, v.key, other))
+		}
+		seen[v.key] = v.name
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,67 @@
+package pipelinelabels
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestKeys(t *testing.T) {
+	custom := Keys{
+		Pipeline:    "app.example.com/pipeline",
+		Environment: "app.example.com/stage",
+		After:       "app.example.com/after",
+		Application: "app.example.com/name",
+	}
+	withAnnotations := custom
+	withAnnotations.Annotations = true
+	obj := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				"gitops.pro/pipeline":   "demo-pipeline",
+				"app.example.com/stage": "staging",
+			},
+			Annotations: map[string]string{
+				"app.example.com/pipeline": "custom-pipeline",
+				"app.example.com/stage":    "production",
+			},
+		},
+	}
+
+	keysTests := []struct {
+		name            string
+		keys            Keys
+		wantPipeline    string
+		wantEnvironment string
+	}{
+		{"default keys", DefaultKeys, "demo-pipeline", ""},
+		{"custom labels", custom, "", "staging"},
+		{"custom annotations", withAnnotations, "custom-pipeline", "staging"},
+	}
+
+	for _, tt := range keysTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.keys.PipelineName(obj); got != tt.wantPipeline {
+				t.Errorf("got pipeline %q, want %q", got, tt.wantPipeline)
+			}
+			if got := tt.keys.EnvironmentName(obj); got != tt.wantEnvironment {
+				t.Errorf("got environment %q, want %q", got, tt.wantEnvironment)
+			}
+		})
+	}
+}
+
+func TestKeys_Validate(t *testing.T) {
+	if err := DefaultKeys.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := DefaultKeys
+	invalid.Pipeline = ""
+	invalid.After = invalid.Environment
+	want := "the pipeline key must be provided\nthe after key \"gitops.pro/pipeline-environment\" is the same as the environment key"
+	if err := invalid.Validate(); err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,68 @@
+package printers
+
+import (
+	"io"
+	"strings"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+)
+
+// PrintMatrix prints the versions of the charts in each environment.
+//
+// The application column is only printed if any of the charts are identified
+// by an application.
+//
+// In the table formats, an environment that lags the preceding environment
+// is shown as "1.0.9 < 1.0.12" and a newer version in the chart repository
+// is shown as "1.0.12 (1.1.0)".
+func PrintMatrix(w io.Writer, f Format, m helm.VersionMatrix) error {
+	if isStructured(f) {
+		return printValue(w, f, "matrix", m)
+	}
+
+	applications := false
+	for _, r := range m.Rows {
+		applications = applications || r.Application != ""
+	}
+	headers := []string{"CHART"}
+	if applications {
+		headers = append([]string{"APPLICATION"}, headers...)
+	}
+	if f == Wide {
+		headers = append(headers, "SOURCE")
+	}
+	for _, env := range m.Environments {
+		headers = append(headers, strings.ToUpper(env))
+	}
+	rows := [][]string{}
+	for _, r := range m.Rows {
+		row := []string{r.Chart}
+		if applications {
+			row = append([]string{r.Application}, row...)
+		}
+		if f == Wide {
+			row = append(row, r.Source)
+		}
+		for _, c := range r.Cells {
+			row = append(row, formatCell(c))
+		}
+		rows = append(rows, row)
+	}
+
+	return printTable(w, headers, rows)
+}
+
+func formatCell(c helm.VersionMatrixCell) string {
+	if c.Version == "" {
+		return "-"
+	}
+	s := c.Version
+	if c.PromoteTo != "" {
+		s += " < " + c.PromoteTo
+	}
+	if c.Available != "" {
+		s += " (" + c.Available + ")"
+	}
+
+	return s
+}
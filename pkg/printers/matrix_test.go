@@ -0,0 +1,118 @@
+package printers
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+)
+
+func TestPrintMatrix(t *testing.T) {
+	m := helm.VersionMatrix{
+		Environments: []string{"staging", "production"},
+		Rows: []helm.VersionMatrixRow{
+			{
+				Chart:  "redis",
+				Source: "HelmRepository/default/test-repository",
+				Cells: []helm.VersionMatrixCell{
+					{Version: "1.0.12", Available: "1.1.0"},
+					{Version: "1.0.9", PromoteTo: "1.0.12", Available: "1.1.0"},
+				},
+			},
+			{
+				Chart:  "postgresql",
+				Source: "HelmRepository/default/test-repository",
+				Cells: []helm.VersionMatrixCell{
+					{Version: "13.0.1"},
+					{},
+				},
+			},
+		},
+	}
+
+	printTests := []struct {
+		format Format
+		want   string
+	}{
+		{
+			format: Table,
+			want: `CHART       STAGING         PRODUCTION
+redis       1.0.12 (1.1.0)  1.0.9 < 1.0.12 (1.1.0)
+postgresql  13.0.1          -
+`,
+		},
+		{
+			format: Wide,
+			want: `CHART       SOURCE                                  STAGING         PRODUCTION
+redis       HelmRepository/default/test-repository  1.0.12 (1.1.0)  1.0.9 < 1.0.12 (1.1.0)
+postgresql  HelmRepository/default/test-repository  13.0.1          -
+`,
+		},
+		{
+			format: YAML,
+			want: `environments:
+- staging
+- production
+rows:
+- cells:
+  - available: 1.1.0
+    version: 1.0.12
+  - available: 1.1.0
+    promoteTo: 1.0.12
+    version: 1.0.9
+  chart: redis
+  source: HelmRepository/default/test-repository
+- cells:
+  - version: 13.0.1
+  - {}
+  chart: postgresql
+  source: HelmRepository/default/test-repository
+`,
+		},
+	}
+
+	for _, tt := range printTests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var b bytes.Buffer
+			if err := PrintMatrix(&b, tt.format, m); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, b.String()); diff != "" {
+				t.Fatalf("failed to print matrix:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrintMatrix_applications(t *testing.T) {
+	m := helm.VersionMatrix{
+		Environments: []string{"staging", "production"},
+		Rows: []helm.VersionMatrixRow{
+			{
+				Application: "cache",
+				Chart:       "redis",
+				Cells:       []helm.VersionMatrixCell{{Version: "1.0.12"}, {Version: "1.0.9", PromoteTo: "1.0.12"}},
+			},
+			{
+				Application: "sessions",
+				Chart:       "redis",
+				Cells:       []helm.VersionMatrixCell{{Version: "1.0.9"}, {Version: "1.0.9"}},
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	if err := PrintMatrix(&b, Table, m); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `APPLICATION  CHART  STAGING  PRODUCTION
+cache        redis  1.0.12   1.0.9 < 1.0.12
+sessions     redis  1.0.9    1.0.9
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Fatalf("failed to print matrix:\n%s", diff)
+	}
+}
@@ -0,0 +1,255 @@
+package printers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"sigs.k8s.io/yaml"
+
+	pipelinesv1 "github.com/bigkevmcd/peanut-helmpipelines/pkg/protos/pipelines/v1"
+)
+
+// Format is an output format for printing.
+type Format string
+
+const (
+	// Table prints a table with a column per field.
+	Table Format = "table"
+	// Wide prints a table with additional columns.
+	Wide Format = "wide"
+	// JSON prints the API representation as JSON.
+	JSON Format = "json"
+	// YAML prints the API representation as YAML.
+	YAML Format = "yaml"
+)
+
+// Formats is the list of supported output formats.
+var Formats = []Format{Table, Wide, JSON, YAML}
+
+// ParseFormat returns the Format with the provided name.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown output format %q", s)
+}
+
+// PrintPipelines prints a summary of each pipeline.
+//
+// The JSON and YAML formats print a ListPipelinesResponse including the
+// diagnostics, the table formats don't print the diagnostics, use
+// PrintDiagnostics.
+func PrintPipelines(w io.Writer, f Format, pipelines []*pipelinesv1.Pipeline, diagnostics []*pipelinesv1.Diagnostic) error {
+	if isStructured(f) {
+		return printMessage(w, f, &pipelinesv1.ListPipelinesResponse{Count: int32(len(pipelines)), Results: pipelines, Diagnostics: diagnostics})
+	}
+
+	headers := []string{"NAME", "ENVIRONMENTS"}
+	if f == Wide {
+		headers = append(headers, "CHARTS")
+	}
+	rows := [][]string{}
+	for _, p := range pipelines {
+		environments, charts := []string{}, []string{}
+		for _, env := range p.GetEnvironments() {
+			environments = append(environments, env.GetName())
+			for _, c := range env.GetCharts() {
+				charts = appendUnique(charts, c.GetName())
+			}
+		}
+		row := []string{p.GetName(), strings.Join(environments, ",")}
+		if f == Wide {
+			row = append(row, strings.Join(charts, ","))
+		}
+		rows = append(rows, row)
+	}
+
+	return printTable(w, headers, rows)
+}
+
+// PrintDiagnostics prints a warning line for each diagnostic.
+func PrintDiagnostics(w io.Writer, diagnostics []*pipelinesv1.Diagnostic) error {
+	for _, d := range diagnostics {
+		if _, err := fmt.Fprintf(w, "warning: pipeline %s: %s (%s)\n", d.GetPipeline(), d.GetMessage(), d.GetReason()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PrintPipeline prints the charts in each environment of a pipeline.
+//
+// The JSON and YAML formats print a Pipeline.
+func PrintPipeline(w io.Writer, f Format, p *pipelinesv1.Pipeline) error {
+	if isStructured(f) {
+		return printMessage(w, f, p)
+	}
+
+	headers := []string{"ENVIRONMENT", "CHART", "VERSION"}
+	if f == Wide {
+		headers = append(headers, "SOURCE", "VALUES")
+	}
+	rows := [][]string{}
+	for _, env := range p.GetEnvironments() {
+		for _, c := range env.GetCharts() {
+			row := []string{env.GetName(), c.GetName(), c.GetVersion()}
+			if f == Wide {
+				row = append(row, formatReference(c.GetSource()), formatValuesDigests(c.GetReleases()))
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return printTable(w, headers, rows)
+}
+
+// PrintPromotions prints the promotions between environments.
+//
+// The JSON and YAML formats print a ListPromotionsResponse.
+func PrintPromotions(w io.Writer, f Format, promotions []*pipelinesv1.Promotion) error {
+	if isStructured(f) {
+		return printMessage(w, f, &pipelinesv1.ListPromotionsResponse{Count: int32(len(promotions)), Results: promotions})
+	}
+
+	headers := []string{"ENVIRONMENT", "CHART", "FROM", "TO"}
+	if f == Wide {
+		headers = append(headers, "VALUES", "RELEASES")
+	}
+	rows := [][]string{}
+	for _, p := range promotions {
+		row := []string{p.GetEnvironment(), p.GetTo().GetName(), p.GetFrom().GetVersion(), p.GetTo().GetVersion()}
+		if f == Wide {
+			releases := []string{}
+			for _, r := range p.GetPromotedReleases() {
+				releases = append(releases, r.GetNamespace()+"/"+r.GetName())
+			}
+			row = append(row, fmt.Sprintf("%v", p.GetPromoteValues()), strings.Join(releases, ","))
+		}
+		rows = append(rows, row)
+	}
+
+	return printTable(w, headers, rows)
+}
+
+// PrintUpgrades prints the newer versions of charts.
+//
+// The JSON and YAML formats print a ListUpgradesResponse.
+func PrintUpgrades(w io.Writer, f Format, upgrades []*pipelinesv1.ChartUpgrade) error {
+	if isStructured(f) {
+		return printMessage(w, f, &pipelinesv1.ListUpgradesResponse{Count: int32(len(upgrades)), Results: upgrades})
+	}
+
+	headers := []string{"CHART", "CURRENT", "AVAILABLE"}
+	if f == Wide {
+		headers = append(headers, "SOURCE")
+	}
+	rows := [][]string{}
+	for _, u := range upgrades {
+		row := []string{u.GetCurrent().GetName(), u.GetCurrent().GetVersion(), u.GetAvailable().GetVersion()}
+		if f == Wide {
+			row = append(row, formatReference(u.GetCurrent().GetSource()))
+		}
+		rows = append(rows, row)
+	}
+
+	return printTable(w, headers, rows)
+}
+
+func isStructured(f Format) bool {
+	return f == JSON || f == YAML
+}
+
+// printMessage prints the message in the same JSON representation as the
+// gRPC gateway.
+func printMessage(w io.Writer, f Format, m proto.Message) error {
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %T: %w", m, err)
+	}
+
+	if f == YAML {
+		y, err := yaml.JSONToYAML(b)
+		if err != nil {
+			return fmt.Errorf("failed to convert %T to YAML: %w", m, err)
+		}
+		_, err = w.Write(y)
+		return err
+	}
+
+	// protojson deliberately varies the whitespace in the output, indenting
+	// the output makes it stable.
+	var out bytes.Buffer
+	if err := json.Indent(&out, b, "", "  "); err != nil {
+		return fmt.Errorf("failed to format %T: %w", m, err)
+	}
+	out.WriteString("\n")
+	_, err = out.WriteTo(w)
+	return err
+}
+
+// printValue prints values that have no API representation as JSON or YAML.
+func printValue(w io.Writer, f Format, name string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	if f == YAML {
+		if b, err = yaml.JSONToYAML(b); err != nil {
+			return fmt.Errorf("failed to convert %s to YAML: %w", name, err)
+		}
+	} else {
+		b = append(b, '\n')
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func printTable(w io.Writer, headers []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func formatReference(r *pipelinesv1.CrossNamespaceObjectReference) string {
+	if r == nil {
+		return ""
+	}
+
+	return r.GetKind() + "/" + r.GetNamespace() + "/" + r.GetName()
+}
+
+// formatValuesDigests returns the distinct values digests of the HelmReleases.
+func formatValuesDigests(releases []*pipelinesv1.HelmReleaseStatus) string {
+	digests := []string{}
+	for _, r := range releases {
+		if r.GetValuesDigest() != "" {
+			digests = appendUnique(digests, r.GetValuesDigest())
+		}
+	}
+
+	return strings.Join(digests, ", ")
+}
+
+func appendUnique(s []string, v string) []string {
+	for _, e := range s {
+		if e == v {
+			return s
+		}
+	}
+
+	return append(s, v)
+}
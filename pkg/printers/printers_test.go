@@ -0,0 +1,342 @@
+package printers
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	pipelinesv1 "github.com/bigkevmcd/peanut-helmpipelines/pkg/protos/pipelines/v1"
+)
+
+var testSource = &pipelinesv1.CrossNamespaceObjectReference{Kind: "HelmRepository", Namespace: "default", Name: "test-repository"}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != YAML {
+		t.Fatalf("got format %q, want %q", f, YAML)
+	}
+
+	_, err = ParseFormat("xml")
+	if msg := `unknown output format "xml"`; err == nil || err.Error() != msg {
+		t.Fatalf("got error %v, want %q", err, msg)
+	}
+}
+
+func TestPrintPipelines(t *testing.T) {
+	pipelines := []*pipelinesv1.Pipeline{
+		{
+			Name: "demo-pipeline",
+			Environments: []*pipelinesv1.Pipeline_Environment{
+				{
+					Name: "staging",
+					Charts: []*pipelinesv1.Pipeline_Environment_HelmChart{
+						{Name: "redis", Version: "1.0.12", Source: testSource},
+					},
+				},
+				{
+					Name: "production",
+					Charts: []*pipelinesv1.Pipeline_Environment_HelmChart{
+						{Name: "redis", Version: "1.0.9", Source: testSource},
+					},
+				},
+			},
+		},
+	}
+
+	printTests := []struct {
+		format Format
+		want   string
+	}{
+		{
+			format: Table,
+			want: `NAME           ENVIRONMENTS
+demo-pipeline  staging,production
+`,
+		},
+		{
+			format: Wide,
+			want: `NAME           ENVIRONMENTS        CHARTS
+demo-pipeline  staging,production  redis
+`,
+		},
+		{
+			format: JSON,
+			want: `{
+  "count": 1,
+  "results": [
+    {
+      "name": "demo-pipeline",
+      "environments": [
+        {
+          "name": "staging",
+          "charts": [
+            {
+              "name": "redis",
+              "version": "1.0.12",
+              "source": {
+                "kind": "HelmRepository",
+                "namespace": "default",
+                "name": "test-repository"
+              }
+            }
+          ]
+        },
+        {
+          "name": "production",
+          "charts": [
+            {
+              "name": "redis",
+              "version": "1.0.9",
+              "source": {
+                "kind": "HelmRepository",
+                "namespace": "default",
+                "name": "test-repository"
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+		{
+			format: YAML,
+			want: `count: 1
+results:
+- environments:
+  - charts:
+    - name: redis
+      source:
+        kind: HelmRepository
+        name: test-repository
+        namespace: default
+      version: 1.0.12
+    name: staging
+  - charts:
+    - name: redis
+      source:
+        kind: HelmRepository
+        name: test-repository
+        namespace: default
+      version: 1.0.9
+    name: production
+  name: demo-pipeline
+`,
+		},
+	}
+
+	for _, tt := range printTests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var b bytes.Buffer
+			if err := PrintPipelines(&b, tt.format, pipelines, nil); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, b.String()); diff != "" {
+				t.Fatalf("failed to print pipelines:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrintDiagnostics(t *testing.T) {
+	diagnostics := []*pipelinesv1.Diagnostic{
+		{Pipeline: "demo-pipeline", Reason: "EnvironmentCycle", Message: "environments form a cycle: staging -> production -> staging"},
+	}
+
+	var b bytes.Buffer
+	if err := PrintDiagnostics(&b, diagnostics); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "warning: pipeline demo-pipeline: environments form a cycle: staging -> production -> staging (EnvironmentCycle)\n"
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Fatalf("failed to print diagnostics:\n%s", diff)
+	}
+}
+
+func TestPrintPipeline(t *testing.T) {
+	pipeline := &pipelinesv1.Pipeline{
+		Name: "demo-pipeline",
+		Environments: []*pipelinesv1.Pipeline_Environment{
+			{
+				Name: "staging",
+				Charts: []*pipelinesv1.Pipeline_Environment_HelmChart{
+					{
+						Name: "redis", Version: "1.0.12", Source: testSource,
+						Releases: []*pipelinesv1.HelmReleaseStatus{
+							{ValuesDigest: "sha256:eu"},
+							{ValuesDigest: "sha256:us"},
+							{ValuesDigest: "sha256:eu"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	printTests := []struct {
+		format Format
+		want   string
+	}{
+		{
+			format: Table,
+			want: `ENVIRONMENT  CHART  VERSION
+staging      redis  1.0.12
+`,
+		},
+		{
+			format: Wide,
+			want: `ENVIRONMENT  CHART  VERSION  SOURCE                                  VALUES
+staging      redis  1.0.12   HelmRepository/default/test-repository  sha256:eu, sha256:us
+`,
+		},
+	}
+
+	for _, tt := range printTests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var b bytes.Buffer
+			if err := PrintPipeline(&b, tt.format, pipeline); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, b.String()); diff != "" {
+				t.Fatalf("failed to print pipeline:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrintPromotions(t *testing.T) {
+	promotions := []*pipelinesv1.Promotion{
+		{
+			Environment: "production",
+			From:        &pipelinesv1.Pipeline_Environment_HelmChart{Name: "redis", Version: "1.0.9", Source: testSource},
+			To:          &pipelinesv1.Pipeline_Environment_HelmChart{Name: "redis", Version: "1.0.12", Source: testSource},
+			PromotedReleases: []*pipelinesv1.CrossNamespaceObjectReference{
+				{Kind: "HelmRelease", Namespace: "production", Name: "redis"},
+			},
+		},
+	}
+
+	printTests := []struct {
+		format Format
+		want   string
+	}{
+		{
+			format: Table,
+			want: `ENVIRONMENT  CHART  FROM   TO
+production   redis  1.0.9  1.0.12
+`,
+		},
+		{
+			format: Wide,
+			want: `ENVIRONMENT  CHART  FROM   TO      VALUES  RELEASES
+production   redis  1.0.9  1.0.12  false   production/redis
+`,
+		},
+		{
+			format: YAML,
+			want: `count: 1
+results:
+- environment: production
+  from:
+    name: redis
+    source:
+      kind: HelmRepository
+      name: test-repository
+      namespace: default
+    version: 1.0.9
+  promotedReleases:
+  - kind: HelmRelease
+    name: redis
+    namespace: production
+  to:
+    name: redis
+    source:
+      kind: HelmRepository
+      name: test-repository
+      namespace: default
+    version: 1.0.12
+`,
+		},
+	}
+
+	for _, tt := range printTests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var b bytes.Buffer
+			if err := PrintPromotions(&b, tt.format, promotions); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, b.String()); diff != "" {
+				t.Fatalf("failed to print promotions:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrintUpgrades(t *testing.T) {
+	upgrades := []*pipelinesv1.ChartUpgrade{
+		{
+			Current:   &pipelinesv1.Pipeline_Environment_HelmChart{Name: "redis", Version: "1.0.9", Source: testSource},
+			Available: &pipelinesv1.Pipeline_Environment_HelmChart{Name: "redis", Version: "1.0.12", Source: testSource},
+		},
+	}
+
+	printTests := []struct {
+		format Format
+		want   string
+	}{
+		{
+			format: Table,
+			want: `CHART  CURRENT  AVAILABLE
+redis  1.0.9    1.0.12
+`,
+		},
+		{
+			format: JSON,
+			want: `{
+  "count": 1,
+  "results": [
+    {
+      "current": {
+        "name": "redis",
+        "version": "1.0.9",
+        "source": {
+          "kind": "HelmRepository",
+          "namespace": "default",
+          "name": "test-repository"
+        }
+      },
+      "available": {
+        "name": "redis",
+        "version": "1.0.12",
+        "source": {
+          "kind": "HelmRepository",
+          "namespace": "default",
+          "name": "test-repository"
+        }
+      }
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, tt := range printTests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var b bytes.Buffer
+			if err := PrintUpgrades(&b, tt.format, upgrades); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, b.String()); diff != "" {
+				t.Fatalf("failed to print upgrades:\n%s", diff)
+			}
+		})
+	}
+}
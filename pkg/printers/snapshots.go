@@ -0,0 +1,22 @@
+package printers
+
+import (
+	"io"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/snapshots"
+)
+
+// PrintChanges prints the changes between two snapshots.
+func PrintChanges(w io.Writer, f Format, changes []snapshots.Change) error {
+	if isStructured(f) {
+		return printValue(w, f, "changes", changes)
+	}
+
+	headers := []string{"PIPELINE", "ENVIRONMENT", "CHART", "CHANGE", "FROM", "TO"}
+	rows := [][]string{}
+	for _, c := range changes {
+		rows = append(rows, []string{c.Pipeline, c.Environment, c.Chart, string(c.Type), c.From, c.To})
+	}
+
+	return printTable(w, headers, rows)
+}
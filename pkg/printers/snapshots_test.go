@@ -0,0 +1,59 @@
+package printers
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/snapshots"
+)
+
+func TestPrintChanges(t *testing.T) {
+	changes := []snapshots.Change{
+		{Type: snapshots.VersionChanged, Pipeline: "demo-pipeline", Environment: "production", Chart: "cache", From: "1.0.9", To: "1.0.12"},
+		{Type: snapshots.ValuesChanged, Pipeline: "demo-pipeline", Environment: "production", Chart: "cache", From: "abc123", To: "def456"},
+		{Type: snapshots.Added, Pipeline: "demo-pipeline", Environment: "qa"},
+	}
+
+	printTests := []struct {
+		format  Format
+		changes []snapshots.Change
+		want    string
+	}{
+		{
+			format:  Table,
+			changes: changes[:2],
+			want: `PIPELINE       ENVIRONMENT  CHART  CHANGE          FROM    TO
+demo-pipeline  production   cache  VersionChanged  1.0.9   1.0.12
+demo-pipeline  production   cache  ValuesChanged   abc123  def456
+`,
+		},
+		{
+			format:  YAML,
+			changes: []snapshots.Change{changes[0], changes[2]},
+			want: `- chart: cache
+  environment: production
+  from: 1.0.9
+  pipeline: demo-pipeline
+  to: 1.0.12
+  type: VersionChanged
+- environment: qa
+  pipeline: demo-pipeline
+  type: Added
+`,
+		},
+	}
+
+	for _, tt := range printTests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var b bytes.Buffer
+			if err := PrintChanges(&b, tt.format, tt.changes); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, b.String()); diff != "" {
+				t.Fatalf("failed to print changes:\n%s", diff)
+			}
+		})
+	}
+}
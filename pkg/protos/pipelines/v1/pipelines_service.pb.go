@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.26.0
+// 	protoc-gen-go v1.36.10
 // 	protoc        (unknown)
 // source: pipelines/v1/pipelines_service.proto
 
@@ -10,9 +10,10 @@ import (
 	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
-	_ "google.golang.org/protobuf/types/known/timestamppb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
+	unsafe "unsafe"
 )
 
 const (
@@ -23,29 +24,586 @@ const (
 )
 
 type ListPipelinesRequest struct {
-	state         protoimpl.MessageState
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only return pipelines with HelmReleases in this namespace.
+	Namespace string `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// Only return the pipeline with this name.
+	PipelineName string `protobuf:"bytes,2,opt,name=pipeline_name,json=pipelineName,proto3" json:"pipeline_name,omitempty"`
+	// Only return pipelines with HelmReleases that match this label selector.
+	LabelSelector string `protobuf:"bytes,3,opt,name=label_selector,json=labelSelector,proto3" json:"label_selector,omitempty"`
+	// Only return pipelines that deploy a chart with this name.
+	ChartName string `protobuf:"bytes,4,opt,name=chart_name,json=chartName,proto3" json:"chart_name,omitempty"`
+	// The maximum number of pipelines to return, all the pipelines are
+	// returned if this is zero.
+	PageSize int32 `protobuf:"varint,5,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token from a previous response.
+	PageToken     string `protobuf:"bytes,6,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPipelinesRequest) Reset() {
+	*x = ListPipelinesRequest{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPipelinesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPipelinesRequest) ProtoMessage() {}
+
+func (x *ListPipelinesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPipelinesRequest.ProtoReflect.Descriptor instead.
+func (*ListPipelinesRequest) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ListPipelinesRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ListPipelinesRequest) GetPipelineName() string {
+	if x != nil {
+		return x.PipelineName
+	}
+	return ""
+}
+
+func (x *ListPipelinesRequest) GetLabelSelector() string {
+	if x != nil {
+		return x.LabelSelector
+	}
+	return ""
+}
+
+func (x *ListPipelinesRequest) GetChartName() string {
+	if x != nil {
+		return x.ChartName
+	}
+	return ""
+}
+
+func (x *ListPipelinesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListPipelinesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListPipelinesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The number of pipelines that match the filters, across all pages.
+	Count   int32       `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Results []*Pipeline `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"`
+	// Problems found with the labelling of HelmReleases in pipelines.
+	Diagnostics []*Diagnostic `protobuf:"bytes,4,rep,name=diagnostics,proto3" json:"diagnostics,omitempty"`
+	// Pass this in the page_token to get the next page, this is empty on the
+	// last page.
+	NextPageToken string `protobuf:"bytes,5,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPipelinesResponse) Reset() {
+	*x = ListPipelinesResponse{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPipelinesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPipelinesResponse) ProtoMessage() {}
+
+func (x *ListPipelinesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPipelinesResponse.ProtoReflect.Descriptor instead.
+func (*ListPipelinesResponse) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListPipelinesResponse) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *ListPipelinesResponse) GetResults() []*Pipeline {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *ListPipelinesResponse) GetDiagnostics() []*Diagnostic {
+	if x != nil {
+		return x.Diagnostics
+	}
+	return nil
+}
+
+func (x *ListPipelinesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type GetPipelineRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Only discover the pipeline from the HelmReleases in this namespace, for
+	// users that can't list HelmReleases in every namespace.
+	Namespace     string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPipelineRequest) Reset() {
+	*x = GetPipelineRequest{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPipelineRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPipelineRequest) ProtoMessage() {}
+
+func (x *GetPipelineRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPipelineRequest.ProtoReflect.Descriptor instead.
+func (*GetPipelineRequest) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetPipelineRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GetPipelineRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type GetPipelineResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pipeline      *Pipeline              `protobuf:"bytes,1,opt,name=pipeline,proto3" json:"pipeline,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPipelineResponse) Reset() {
+	*x = GetPipelineResponse{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPipelineResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPipelineResponse) ProtoMessage() {}
+
+func (x *GetPipelineResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPipelineResponse.ProtoReflect.Descriptor instead.
+func (*GetPipelineResponse) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetPipelineResponse) GetPipeline() *Pipeline {
+	if x != nil {
+		return x.Pipeline
+	}
+	return nil
+}
+
+type DiffPromotionRequest struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	PipelineName string                 `protobuf:"bytes,1,opt,name=pipeline_name,json=pipelineName,proto3" json:"pipeline_name,omitempty"`
+	Environment  string                 `protobuf:"bytes,2,opt,name=environment,proto3" json:"environment,omitempty"`
+	ChartName    string                 `protobuf:"bytes,3,opt,name=chart_name,json=chartName,proto3" json:"chart_name,omitempty"`
+	// Only discover the pipeline from the HelmReleases in this namespace, for
+	// users that can't list HelmReleases in every namespace.
+	Namespace     string `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiffPromotionRequest) Reset() {
+	*x = DiffPromotionRequest{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiffPromotionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiffPromotionRequest) ProtoMessage() {}
+
+func (x *DiffPromotionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiffPromotionRequest.ProtoReflect.Descriptor instead.
+func (*DiffPromotionRequest) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DiffPromotionRequest) GetPipelineName() string {
+	if x != nil {
+		return x.PipelineName
+	}
+	return ""
+}
+
+func (x *DiffPromotionRequest) GetEnvironment() string {
+	if x != nil {
+		return x.Environment
+	}
+	return ""
+}
+
+func (x *DiffPromotionRequest) GetChartName() string {
+	if x != nil {
+		return x.ChartName
+	}
+	return ""
+}
+
+func (x *DiffPromotionRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type DiffPromotionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ManifestDiff  string                 `protobuf:"bytes,1,opt,name=manifest_diff,json=manifestDiff,proto3" json:"manifest_diff,omitempty"`
+	ValuesDiff    string                 `protobuf:"bytes,2,opt,name=values_diff,json=valuesDiff,proto3" json:"values_diff,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiffPromotionResponse) Reset() {
+	*x = DiffPromotionResponse{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiffPromotionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiffPromotionResponse) ProtoMessage() {}
+
+func (x *DiffPromotionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiffPromotionResponse.ProtoReflect.Descriptor instead.
+func (*DiffPromotionResponse) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DiffPromotionResponse) GetManifestDiff() string {
+	if x != nil {
+		return x.ManifestDiff
+	}
+	return ""
+}
+
+func (x *DiffPromotionResponse) GetValuesDiff() string {
+	if x != nil {
+		return x.ValuesDiff
+	}
+	return ""
+}
+
+type ListPromotionsRequest struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	PipelineName string                 `protobuf:"bytes,1,opt,name=pipeline_name,json=pipelineName,proto3" json:"pipeline_name,omitempty"`
+	// Treat differences in the values of a chart as promotable. Promoting
+	// values replaces the values and valuesFrom of the promoted HelmReleases
+	// with those of the preceding environment.
+	//
+	// Servers configured with promotions.include-values always include values,
+	// and ignore this field.
+	IncludeValues bool `protobuf:"varint,2,opt,name=include_values,json=includeValues,proto3" json:"include_values,omitempty"`
+	// Only discover the pipeline from the HelmReleases in this namespace, for
+	// users that can't list HelmReleases in every namespace.
+	Namespace     string `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPromotionsRequest) Reset() {
+	*x = ListPromotionsRequest{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPromotionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPromotionsRequest) ProtoMessage() {}
+
+func (x *ListPromotionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPromotionsRequest.ProtoReflect.Descriptor instead.
+func (*ListPromotionsRequest) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListPromotionsRequest) GetPipelineName() string {
+	if x != nil {
+		return x.PipelineName
+	}
+	return ""
+}
+
+func (x *ListPromotionsRequest) GetIncludeValues() bool {
+	if x != nil {
+		return x.IncludeValues
+	}
+	return false
+}
+
+func (x *ListPromotionsRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type ListPromotionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Results       []*Promotion           `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
 	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPromotionsResponse) Reset() {
+	*x = ListPromotionsResponse{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPromotionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPromotionsResponse) ProtoMessage() {}
+
+func (x *ListPromotionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPromotionsResponse.ProtoReflect.Descriptor instead.
+func (*ListPromotionsResponse) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListPromotionsResponse) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *ListPromotionsResponse) GetResults() []*Promotion {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type ListUpgradesRequest struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	PipelineName string                 `protobuf:"bytes,1,opt,name=pipeline_name,json=pipelineName,proto3" json:"pipeline_name,omitempty"`
+	// Only discover the pipeline from the HelmReleases in this namespace, for
+	// users that can't list HelmReleases in every namespace.
+	Namespace     string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUpgradesRequest) Reset() {
+	*x = ListUpgradesRequest{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUpgradesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUpgradesRequest) ProtoMessage() {}
+
+func (x *ListUpgradesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUpgradesRequest.ProtoReflect.Descriptor instead.
+func (*ListUpgradesRequest) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *ListPipelinesRequest) Reset() {
-	*x = ListPipelinesRequest{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[0]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+func (x *ListUpgradesRequest) GetPipelineName() string {
+	if x != nil {
+		return x.PipelineName
 	}
+	return ""
 }
 
-func (x *ListPipelinesRequest) String() string {
+func (x *ListUpgradesRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type ListUpgradesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Results       []*ChartUpgrade        `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUpgradesResponse) Reset() {
+	*x = ListUpgradesResponse{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUpgradesResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListPipelinesRequest) ProtoMessage() {}
+func (*ListUpgradesResponse) ProtoMessage() {}
 
-func (x *ListPipelinesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[0]
-	if protoimpl.UnsafeEnabled && x != nil {
+func (x *ListUpgradesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[9]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -55,38 +613,127 @@ func (x *ListPipelinesRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListPipelinesRequest.ProtoReflect.Descriptor instead.
-func (*ListPipelinesRequest) Descriptor() ([]byte, []int) {
-	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use ListUpgradesResponse.ProtoReflect.Descriptor instead.
+func (*ListUpgradesResponse) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{9}
 }
 
-type ListPipelinesResponse struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+func (x *ListUpgradesResponse) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *ListUpgradesResponse) GetResults() []*ChartUpgrade {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type Promotion struct {
+	state            protoimpl.MessageState           `protogen:"open.v1"`
+	Environment      string                           `protobuf:"bytes,1,opt,name=environment,proto3" json:"environment,omitempty"`
+	From             *Pipeline_Environment_HelmChart  `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To               *Pipeline_Environment_HelmChart  `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	PromotedReleases []*CrossNamespaceObjectReference `protobuf:"bytes,4,rep,name=promoted_releases,json=promotedReleases,proto3" json:"promoted_releases,omitempty"`
+	// The values and valuesFrom of the promoted HelmReleases are replaced with
+	// those of the HelmReleases in the preceding environment.
+	PromoteValues bool `protobuf:"varint,5,opt,name=promote_values,json=promoteValues,proto3" json:"promote_values,omitempty"`
 	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-	Count   int32       `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
-	Results []*Pipeline `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"`
+func (x *Promotion) Reset() {
+	*x = Promotion{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListPipelinesResponse) Reset() {
-	*x = ListPipelinesResponse{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[1]
+func (x *Promotion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Promotion) ProtoMessage() {}
+
+func (x *Promotion) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[10]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Promotion.ProtoReflect.Descriptor instead.
+func (*Promotion) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Promotion) GetEnvironment() string {
+	if x != nil {
+		return x.Environment
 	}
+	return ""
 }
 
-func (x *ListPipelinesResponse) String() string {
+func (x *Promotion) GetFrom() *Pipeline_Environment_HelmChart {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *Promotion) GetTo() *Pipeline_Environment_HelmChart {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *Promotion) GetPromotedReleases() []*CrossNamespaceObjectReference {
+	if x != nil {
+		return x.PromotedReleases
+	}
+	return nil
+}
+
+func (x *Promotion) GetPromoteValues() bool {
+	if x != nil {
+		return x.PromoteValues
+	}
+	return false
+}
+
+type ChartUpgrade struct {
+	state         protoimpl.MessageState          `protogen:"open.v1"`
+	Current       *Pipeline_Environment_HelmChart `protobuf:"bytes,1,opt,name=current,proto3" json:"current,omitempty"`
+	Available     *Pipeline_Environment_HelmChart `protobuf:"bytes,2,opt,name=available,proto3" json:"available,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChartUpgrade) Reset() {
+	*x = ChartUpgrade{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChartUpgrade) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListPipelinesResponse) ProtoMessage() {}
+func (*ChartUpgrade) ProtoMessage() {}
 
-func (x *ListPipelinesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
+func (x *ChartUpgrade) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[11]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -96,41 +743,38 @@ func (x *ListPipelinesResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListPipelinesResponse.ProtoReflect.Descriptor instead.
-func (*ListPipelinesResponse) Descriptor() ([]byte, []int) {
-	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use ChartUpgrade.ProtoReflect.Descriptor instead.
+func (*ChartUpgrade) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{11}
 }
 
-func (x *ListPipelinesResponse) GetCount() int32 {
+func (x *ChartUpgrade) GetCurrent() *Pipeline_Environment_HelmChart {
 	if x != nil {
-		return x.Count
+		return x.Current
 	}
-	return 0
+	return nil
 }
 
-func (x *ListPipelinesResponse) GetResults() []*Pipeline {
+func (x *ChartUpgrade) GetAvailable() *Pipeline_Environment_HelmChart {
 	if x != nil {
-		return x.Results
+		return x.Available
 	}
 	return nil
 }
 
 type Pipeline struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Name          string                  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Environments  []*Pipeline_Environment `protobuf:"bytes,2,rep,name=environments,proto3" json:"environments,omitempty"`
 	unknownFields protoimpl.UnknownFields
-
-	Name         string                  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Environments []*Pipeline_Environment `protobuf:"bytes,2,rep,name=environments,proto3" json:"environments,omitempty"`
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Pipeline) Reset() {
 	*x = Pipeline{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[2]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *Pipeline) String() string {
@@ -140,8 +784,8 @@ func (x *Pipeline) String() string {
 func (*Pipeline) ProtoMessage() {}
 
 func (x *Pipeline) ProtoReflect() protoreflect.Message {
-	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[2]
-	if protoimpl.UnsafeEnabled && x != nil {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[12]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -153,7 +797,7 @@ func (x *Pipeline) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Pipeline.ProtoReflect.Descriptor instead.
 func (*Pipeline) Descriptor() ([]byte, []int) {
-	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{2}
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *Pipeline) GetName() string {
@@ -170,23 +814,188 @@ func (x *Pipeline) GetEnvironments() []*Pipeline_Environment {
 	return nil
 }
 
-type CrossNamespaceObjectReference struct {
-	state         protoimpl.MessageState
+type HelmReleaseStatus struct {
+	state       protoimpl.MessageState         `protogen:"open.v1"`
+	HelmRelease *CrossNamespaceObjectReference `protobuf:"bytes,1,opt,name=helm_release,json=helmRelease,proto3" json:"helm_release,omitempty"`
+	// The status of the Ready condition, "True", "False" or "Unknown".
+	Ready string `protobuf:"bytes,2,opt,name=ready,proto3" json:"ready,omitempty"`
+	// The chart version of the latest successful release of the HelmRelease,
+	// failed releases are ignored.
+	LastAppliedRevision string `protobuf:"bytes,3,opt,name=last_applied_revision,json=lastAppliedRevision,proto3" json:"last_applied_revision,omitempty"`
+	// The time that the Ready condition last changed, this is not updated when
+	// the HelmRelease is reconciled without a change in its readiness.
+	ReadyTransitionTime *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=ready_transition_time,json=readyTransitionTime,proto3" json:"ready_transition_time,omitempty"`
+	// The Ready condition message when the HelmRelease failed to reconcile.
+	FailureMessage string `protobuf:"bytes,5,opt,name=failure_message,json=failureMessage,proto3" json:"failure_message,omitempty"`
+	// A fingerprint of the values configured on the HelmRelease, this is empty
+	// if no values are configured.
+	ValuesDigest  string `protobuf:"bytes,6,opt,name=values_digest,json=valuesDigest,proto3" json:"values_digest,omitempty"`
+	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HelmReleaseStatus) Reset() {
+	*x = HelmReleaseStatus{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HelmReleaseStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HelmReleaseStatus) ProtoMessage() {}
+
+func (x *HelmReleaseStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HelmReleaseStatus.ProtoReflect.Descriptor instead.
+func (*HelmReleaseStatus) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *HelmReleaseStatus) GetHelmRelease() *CrossNamespaceObjectReference {
+	if x != nil {
+		return x.HelmRelease
+	}
+	return nil
+}
+
+func (x *HelmReleaseStatus) GetReady() string {
+	if x != nil {
+		return x.Ready
+	}
+	return ""
+}
+
+func (x *HelmReleaseStatus) GetLastAppliedRevision() string {
+	if x != nil {
+		return x.LastAppliedRevision
+	}
+	return ""
+}
+
+func (x *HelmReleaseStatus) GetReadyTransitionTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReadyTransitionTime
+	}
+	return nil
+}
+
+func (x *HelmReleaseStatus) GetFailureMessage() string {
+	if x != nil {
+		return x.FailureMessage
+	}
+	return ""
+}
+
+func (x *HelmReleaseStatus) GetValuesDigest() string {
+	if x != nil {
+		return x.ValuesDigest
+	}
+	return ""
+}
+
+type Diagnostic struct {
+	state         protoimpl.MessageState         `protogen:"open.v1"`
+	Pipeline      string                         `protobuf:"bytes,1,opt,name=pipeline,proto3" json:"pipeline,omitempty"`
+	Environment   string                         `protobuf:"bytes,2,opt,name=environment,proto3" json:"environment,omitempty"`
+	HelmRelease   *CrossNamespaceObjectReference `protobuf:"bytes,3,opt,name=helm_release,json=helmRelease,proto3" json:"helm_release,omitempty"`
+	Reason        string                         `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	Message       string                         `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
 	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-	Kind      string `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
-	Namespace string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
-	Name      string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+func (x *Diagnostic) Reset() {
+	*x = Diagnostic{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *CrossNamespaceObjectReference) Reset() {
-	*x = CrossNamespaceObjectReference{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[3]
+func (x *Diagnostic) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Diagnostic) ProtoMessage() {}
+
+func (x *Diagnostic) ProtoReflect() protoreflect.Message {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[14]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Diagnostic.ProtoReflect.Descriptor instead.
+func (*Diagnostic) Descriptor() ([]byte, []int) {
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Diagnostic) GetPipeline() string {
+	if x != nil {
+		return x.Pipeline
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetEnvironment() string {
+	if x != nil {
+		return x.Environment
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetHelmRelease() *CrossNamespaceObjectReference {
+	if x != nil {
+		return x.HelmRelease
 	}
+	return nil
+}
+
+func (x *Diagnostic) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type CrossNamespaceObjectReference struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrossNamespaceObjectReference) Reset() {
+	*x = CrossNamespaceObjectReference{}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *CrossNamespaceObjectReference) String() string {
@@ -196,8 +1005,8 @@ func (x *CrossNamespaceObjectReference) String() string {
 func (*CrossNamespaceObjectReference) ProtoMessage() {}
 
 func (x *CrossNamespaceObjectReference) ProtoReflect() protoreflect.Message {
-	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[3]
-	if protoimpl.UnsafeEnabled && x != nil {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[15]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -209,7 +1018,7 @@ func (x *CrossNamespaceObjectReference) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CrossNamespaceObjectReference.ProtoReflect.Descriptor instead.
 func (*CrossNamespaceObjectReference) Descriptor() ([]byte, []int) {
-	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{3}
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *CrossNamespaceObjectReference) GetKind() string {
@@ -234,21 +1043,20 @@ func (x *CrossNamespaceObjectReference) GetName() string {
 }
 
 type Pipeline_Environment struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
+	state  protoimpl.MessageState            `protogen:"open.v1"`
 	Name   string                            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Charts []*Pipeline_Environment_HelmChart `protobuf:"bytes,2,rep,name=charts,proto3" json:"charts,omitempty"`
+	// The environments that this environment is promoted from.
+	After         []string `protobuf:"bytes,3,rep,name=after,proto3" json:"after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Pipeline_Environment) Reset() {
 	*x = Pipeline_Environment{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[4]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *Pipeline_Environment) String() string {
@@ -258,8 +1066,8 @@ func (x *Pipeline_Environment) String() string {
 func (*Pipeline_Environment) ProtoMessage() {}
 
 func (x *Pipeline_Environment) ProtoReflect() protoreflect.Message {
-	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[4]
-	if protoimpl.UnsafeEnabled && x != nil {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[16]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -271,7 +1079,7 @@ func (x *Pipeline_Environment) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Pipeline_Environment.ProtoReflect.Descriptor instead.
 func (*Pipeline_Environment) Descriptor() ([]byte, []int) {
-	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{2, 0}
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{12, 0}
 }
 
 func (x *Pipeline_Environment) GetName() string {
@@ -288,23 +1096,31 @@ func (x *Pipeline_Environment) GetCharts() []*Pipeline_Environment_HelmChart {
 	return nil
 }
 
-type Pipeline_Environment_HelmChart struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
+func (x *Pipeline_Environment) GetAfter() []string {
+	if x != nil {
+		return x.After
+	}
+	return nil
+}
 
+type Pipeline_Environment_HelmChart struct {
+	state   protoimpl.MessageState         `protogen:"open.v1"`
 	Name    string                         `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Version string                         `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
 	Source  *CrossNamespaceObjectReference `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
+	// Identifies the same application across the environments.
+	Application string `protobuf:"bytes,5,opt,name=application,proto3" json:"application,omitempty"`
+	// The HelmReleases that deploy this chart in the environment.
+	Releases      []*HelmReleaseStatus `protobuf:"bytes,6,rep,name=releases,proto3" json:"releases,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Pipeline_Environment_HelmChart) Reset() {
 	*x = Pipeline_Environment_HelmChart{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[5]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *Pipeline_Environment_HelmChart) String() string {
@@ -314,8 +1130,8 @@ func (x *Pipeline_Environment_HelmChart) String() string {
 func (*Pipeline_Environment_HelmChart) ProtoMessage() {}
 
 func (x *Pipeline_Environment_HelmChart) ProtoReflect() protoreflect.Message {
-	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[5]
-	if protoimpl.UnsafeEnabled && x != nil {
+	mi := &file_pipelines_v1_pipelines_service_proto_msgTypes[17]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -327,7 +1143,7 @@ func (x *Pipeline_Environment_HelmChart) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Pipeline_Environment_HelmChart.ProtoReflect.Descriptor instead.
 func (*Pipeline_Environment_HelmChart) Descriptor() ([]byte, []int) {
-	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{2, 0, 0}
+	return file_pipelines_v1_pipelines_service_proto_rawDescGZIP(), []int{12, 0, 0}
 }
 
 func (x *Pipeline_Environment_HelmChart) GetName() string {
@@ -351,99 +1167,181 @@ func (x *Pipeline_Environment_HelmChart) GetSource() *CrossNamespaceObjectRefere
 	return nil
 }
 
-var File_pipelines_v1_pipelines_service_proto protoreflect.FileDescriptor
+func (x *Pipeline_Environment_HelmChart) GetApplication() string {
+	if x != nil {
+		return x.Application
+	}
+	return ""
+}
 
-var file_pipelines_v1_pipelines_service_proto_rawDesc = []byte{
-	0x0a, 0x24, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x2f, 0x76, 0x31, 0x2f, 0x70,
-	0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0c, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65,
-	0x73, 0x2e, 0x76, 0x31, 0x1a, 0x1c, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x61, 0x70, 0x69,
-	0x2f, 0x61, 0x6e, 0x6e, 0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x22, 0x16, 0x0a, 0x14, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x69, 0x70, 0x65, 0x6c,
-	0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x5f, 0x0a, 0x15, 0x4c,
-	0x69, 0x73, 0x74, 0x50, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x05, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x30, 0x0a, 0x07, 0x72, 0x65,
-	0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x70, 0x69,
-	0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x6c,
-	0x69, 0x6e, 0x65, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x22, 0xd0, 0x02, 0x0a,
-	0x08, 0x50, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
-	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x46, 0x0a,
-	0x0c, 0x65, 0x6e, 0x76, 0x69, 0x72, 0x6f, 0x6e, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x02, 0x20,
-	0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x2e,
-	0x76, 0x31, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x2e, 0x45, 0x6e, 0x76, 0x69,
-	0x72, 0x6f, 0x6e, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x0c, 0x65, 0x6e, 0x76, 0x69, 0x72, 0x6f, 0x6e,
-	0x6d, 0x65, 0x6e, 0x74, 0x73, 0x1a, 0xe7, 0x01, 0x0a, 0x0b, 0x45, 0x6e, 0x76, 0x69, 0x72, 0x6f,
-	0x6e, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x44, 0x0a, 0x06, 0x63, 0x68, 0x61,
-	0x72, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2c, 0x2e, 0x70, 0x69, 0x70, 0x65,
-	0x6c, 0x69, 0x6e, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e,
-	0x65, 0x2e, 0x45, 0x6e, 0x76, 0x69, 0x72, 0x6f, 0x6e, 0x6d, 0x65, 0x6e, 0x74, 0x2e, 0x48, 0x65,
-	0x6c, 0x6d, 0x43, 0x68, 0x61, 0x72, 0x74, 0x52, 0x06, 0x63, 0x68, 0x61, 0x72, 0x74, 0x73, 0x1a,
-	0x7e, 0x0a, 0x09, 0x48, 0x65, 0x6c, 0x6d, 0x43, 0x68, 0x61, 0x72, 0x74, 0x12, 0x12, 0x0a, 0x04,
-	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65,
-	0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x43, 0x0a, 0x06, 0x73, 0x6f,
-	0x75, 0x72, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x2b, 0x2e, 0x70, 0x69, 0x70,
-	0x65, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x4e,
-	0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x4f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x52, 0x65,
-	0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x22,
-	0x65, 0x0a, 0x1d, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63,
-	0x65, 0x4f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65,
-	0x12, 0x12, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
-	0x6b, 0x69, 0x6e, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63,
-	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61,
-	0x63, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x32, 0x6c, 0x0a, 0x10, 0x50, 0x69, 0x70, 0x65, 0x6c, 0x69,
-	0x6e, 0x65, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x58, 0x0a, 0x0d, 0x4c, 0x69,
-	0x73, 0x74, 0x50, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x12, 0x22, 0x2e, 0x70, 0x69,
-	0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x50,
-	0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
-	0x23, 0x2e, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4c,
-	0x69, 0x73, 0x74, 0x50, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x42, 0x43, 0x5a, 0x41, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
-	0x6f, 0x6d, 0x2f, 0x62, 0x69, 0x67, 0x6b, 0x65, 0x76, 0x6d, 0x63, 0x64, 0x2f, 0x70, 0x65, 0x61,
-	0x6e, 0x75, 0x74, 0x2d, 0x68, 0x65, 0x6c, 0x6d, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65,
-	0x73, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x2f, 0x70, 0x69, 0x70,
-	0x65, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x2f, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x33,
+func (x *Pipeline_Environment_HelmChart) GetReleases() []*HelmReleaseStatus {
+	if x != nil {
+		return x.Releases
+	}
+	return nil
 }
 
+var File_pipelines_v1_pipelines_service_proto protoreflect.FileDescriptor
+
+const file_pipelines_v1_pipelines_service_proto_rawDesc = "" +
+	"\n" +
+	"$pipelines/v1/pipelines_service.proto\x12\fpipelines.v1\x1a\x1cgoogle/api/annotations.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xdb\x01\n" +
+	"\x14ListPipelinesRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12#\n" +
+	"\rpipeline_name\x18\x02 \x01(\tR\fpipelineName\x12%\n" +
+	"\x0elabel_selector\x18\x03 \x01(\tR\rlabelSelector\x12\x1d\n" +
+	"\n" +
+	"chart_name\x18\x04 \x01(\tR\tchartName\x12\x1b\n" +
+	"\tpage_size\x18\x05 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x06 \x01(\tR\tpageToken\"\xc3\x01\n" +
+	"\x15ListPipelinesResponse\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x05R\x05count\x120\n" +
+	"\aresults\x18\x03 \x03(\v2\x16.pipelines.v1.PipelineR\aresults\x12:\n" +
+	"\vdiagnostics\x18\x04 \x03(\v2\x18.pipelines.v1.DiagnosticR\vdiagnostics\x12&\n" +
+	"\x0fnext_page_token\x18\x05 \x01(\tR\rnextPageToken\"F\n" +
+	"\x12GetPipelineRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"I\n" +
+	"\x13GetPipelineResponse\x122\n" +
+	"\bpipeline\x18\x01 \x01(\v2\x16.pipelines.v1.PipelineR\bpipeline\"\x9a\x01\n" +
+	"\x14DiffPromotionRequest\x12#\n" +
+	"\rpipeline_name\x18\x01 \x01(\tR\fpipelineName\x12 \n" +
+	"\venvironment\x18\x02 \x01(\tR\venvironment\x12\x1d\n" +
+	"\n" +
+	"chart_name\x18\x03 \x01(\tR\tchartName\x12\x1c\n" +
+	"\tnamespace\x18\x04 \x01(\tR\tnamespace\"]\n" +
+	"\x15DiffPromotionResponse\x12#\n" +
+	"\rmanifest_diff\x18\x01 \x01(\tR\fmanifestDiff\x12\x1f\n" +
+	"\vvalues_diff\x18\x02 \x01(\tR\n" +
+	"valuesDiff\"\x81\x01\n" +
+	"\x15ListPromotionsRequest\x12#\n" +
+	"\rpipeline_name\x18\x01 \x01(\tR\fpipelineName\x12%\n" +
+	"\x0einclude_values\x18\x02 \x01(\bR\rincludeValues\x12\x1c\n" +
+	"\tnamespace\x18\x03 \x01(\tR\tnamespace\"a\n" +
+	"\x16ListPromotionsResponse\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x05R\x05count\x121\n" +
+	"\aresults\x18\x02 \x03(\v2\x17.pipelines.v1.PromotionR\aresults\"X\n" +
+	"\x13ListUpgradesRequest\x12#\n" +
+	"\rpipeline_name\x18\x01 \x01(\tR\fpipelineName\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"b\n" +
+	"\x14ListUpgradesResponse\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x05R\x05count\x124\n" +
+	"\aresults\x18\x02 \x03(\v2\x1a.pipelines.v1.ChartUpgradeR\aresults\"\xae\x02\n" +
+	"\tPromotion\x12 \n" +
+	"\venvironment\x18\x01 \x01(\tR\venvironment\x12@\n" +
+	"\x04from\x18\x02 \x01(\v2,.pipelines.v1.Pipeline.Environment.HelmChartR\x04from\x12<\n" +
+	"\x02to\x18\x03 \x01(\v2,.pipelines.v1.Pipeline.Environment.HelmChartR\x02to\x12X\n" +
+	"\x11promoted_releases\x18\x04 \x03(\v2+.pipelines.v1.CrossNamespaceObjectReferenceR\x10promotedReleases\x12%\n" +
+	"\x0epromote_values\x18\x05 \x01(\bR\rpromoteValues\"\xa2\x01\n" +
+	"\fChartUpgrade\x12F\n" +
+	"\acurrent\x18\x01 \x01(\v2,.pipelines.v1.Pipeline.Environment.HelmChartR\acurrent\x12J\n" +
+	"\tavailable\x18\x02 \x01(\v2,.pipelines.v1.Pipeline.Environment.HelmChartR\tavailable\"\xdb\x03\n" +
+	"\bPipeline\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12F\n" +
+	"\fenvironments\x18\x02 \x03(\v2\".pipelines.v1.Pipeline.EnvironmentR\fenvironments\x1a\xf2\x02\n" +
+	"\vEnvironment\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12D\n" +
+	"\x06charts\x18\x02 \x03(\v2,.pipelines.v1.Pipeline.Environment.HelmChartR\x06charts\x12\x14\n" +
+	"\x05after\x18\x03 \x03(\tR\x05after\x1a\xf2\x01\n" +
+	"\tHelmChart\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12C\n" +
+	"\x06source\x18\x03 \x01(\v2+.pipelines.v1.CrossNamespaceObjectReferenceR\x06source\x12 \n" +
+	"\vapplication\x18\x05 \x01(\tR\vapplication\x12;\n" +
+	"\breleases\x18\x06 \x03(\v2\x1f.pipelines.v1.HelmReleaseStatusR\breleasesJ\x04\b\x04\x10\x05R\rvalues_digest\"\xcb\x02\n" +
+	"\x11HelmReleaseStatus\x12N\n" +
+	"\fhelm_release\x18\x01 \x01(\v2+.pipelines.v1.CrossNamespaceObjectReferenceR\vhelmRelease\x12\x14\n" +
+	"\x05ready\x18\x02 \x01(\tR\x05ready\x122\n" +
+	"\x15last_applied_revision\x18\x03 \x01(\tR\x13lastAppliedRevision\x12N\n" +
+	"\x15ready_transition_time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x13readyTransitionTime\x12'\n" +
+	"\x0ffailure_message\x18\x05 \x01(\tR\x0efailureMessage\x12#\n" +
+	"\rvalues_digest\x18\x06 \x01(\tR\fvaluesDigest\"\xcc\x01\n" +
+	"\n" +
+	"Diagnostic\x12\x1a\n" +
+	"\bpipeline\x18\x01 \x01(\tR\bpipeline\x12 \n" +
+	"\venvironment\x18\x02 \x01(\tR\venvironment\x12N\n" +
+	"\fhelm_release\x18\x03 \x01(\v2+.pipelines.v1.CrossNamespaceObjectReferenceR\vhelmRelease\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\"e\n" +
+	"\x1dCrossNamespaceObjectReference\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name2\xc3\x05\n" +
+	"\x10PipelinesService\x12o\n" +
+	"\rListPipelines\x12\".pipelines.v1.ListPipelinesRequest\x1a#.pipelines.v1.ListPipelinesResponse\"\x15\x82\xd3\xe4\x93\x02\x0f\x12\r/v1/pipelines\x12p\n" +
+	"\vGetPipeline\x12 .pipelines.v1.GetPipelineRequest\x1a!.pipelines.v1.GetPipelineResponse\"\x1c\x82\xd3\xe4\x93\x02\x16\x12\x14/v1/pipelines/{name}\x12\xb3\x01\n" +
+	"\rDiffPromotion\x12\".pipelines.v1.DiffPromotionRequest\x1a#.pipelines.v1.DiffPromotionResponse\"Y\x82\xd3\xe4\x93\x02S\x12Q/v1/pipelines/{pipeline_name}/environments/{environment}/charts/{chart_name}/diff\x12\x8d\x01\n" +
+	"\x0eListPromotions\x12#.pipelines.v1.ListPromotionsRequest\x1a$.pipelines.v1.ListPromotionsResponse\"0\x82\xd3\xe4\x93\x02*\x12(/v1/pipelines/{pipeline_name}/promotions\x12\x85\x01\n" +
+	"\fListUpgrades\x12!.pipelines.v1.ListUpgradesRequest\x1a\".pipelines.v1.ListUpgradesResponse\".\x82\xd3\xe4\x93\x02(\x12&/v1/pipelines/{pipeline_name}/upgradesBCZAgithub.com/bigkevmcd/peanut-helmpipelines/pkg/protos/pipelines/v1b\x06proto3"
+
 var (
 	file_pipelines_v1_pipelines_service_proto_rawDescOnce sync.Once
-	file_pipelines_v1_pipelines_service_proto_rawDescData = file_pipelines_v1_pipelines_service_proto_rawDesc
+	file_pipelines_v1_pipelines_service_proto_rawDescData []byte
 )
 
 func file_pipelines_v1_pipelines_service_proto_rawDescGZIP() []byte {
 	file_pipelines_v1_pipelines_service_proto_rawDescOnce.Do(func() {
-		file_pipelines_v1_pipelines_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_pipelines_v1_pipelines_service_proto_rawDescData)
+		file_pipelines_v1_pipelines_service_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pipelines_v1_pipelines_service_proto_rawDesc), len(file_pipelines_v1_pipelines_service_proto_rawDesc)))
 	})
 	return file_pipelines_v1_pipelines_service_proto_rawDescData
 }
 
-var file_pipelines_v1_pipelines_service_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
-var file_pipelines_v1_pipelines_service_proto_goTypes = []interface{}{
+var file_pipelines_v1_pipelines_service_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_pipelines_v1_pipelines_service_proto_goTypes = []any{
 	(*ListPipelinesRequest)(nil),           // 0: pipelines.v1.ListPipelinesRequest
 	(*ListPipelinesResponse)(nil),          // 1: pipelines.v1.ListPipelinesResponse
-	(*Pipeline)(nil),                       // 2: pipelines.v1.Pipeline
-	(*CrossNamespaceObjectReference)(nil),  // 3: pipelines.v1.CrossNamespaceObjectReference
-	(*Pipeline_Environment)(nil),           // 4: pipelines.v1.Pipeline.Environment
-	(*Pipeline_Environment_HelmChart)(nil), // 5: pipelines.v1.Pipeline.Environment.HelmChart
+	(*GetPipelineRequest)(nil),             // 2: pipelines.v1.GetPipelineRequest
+	(*GetPipelineResponse)(nil),            // 3: pipelines.v1.GetPipelineResponse
+	(*DiffPromotionRequest)(nil),           // 4: pipelines.v1.DiffPromotionRequest
+	(*DiffPromotionResponse)(nil),          // 5: pipelines.v1.DiffPromotionResponse
+	(*ListPromotionsRequest)(nil),          // 6: pipelines.v1.ListPromotionsRequest
+	(*ListPromotionsResponse)(nil),         // 7: pipelines.v1.ListPromotionsResponse
+	(*ListUpgradesRequest)(nil),            // 8: pipelines.v1.ListUpgradesRequest
+	(*ListUpgradesResponse)(nil),           // 9: pipelines.v1.ListUpgradesResponse
+	(*Promotion)(nil),                      // 10: pipelines.v1.Promotion
+	(*ChartUpgrade)(nil),                   // 11: pipelines.v1.ChartUpgrade
+	(*Pipeline)(nil),                       // 12: pipelines.v1.Pipeline
+	(*HelmReleaseStatus)(nil),              // 13: pipelines.v1.HelmReleaseStatus
+	(*Diagnostic)(nil),                     // 14: pipelines.v1.Diagnostic
+	(*CrossNamespaceObjectReference)(nil),  // 15: pipelines.v1.CrossNamespaceObjectReference
+	(*Pipeline_Environment)(nil),           // 16: pipelines.v1.Pipeline.Environment
+	(*Pipeline_Environment_HelmChart)(nil), // 17: pipelines.v1.Pipeline.Environment.HelmChart
+	(*timestamppb.Timestamp)(nil),          // 18: google.protobuf.Timestamp
 }
 var file_pipelines_v1_pipelines_service_proto_depIdxs = []int32{
-	2, // 0: pipelines.v1.ListPipelinesResponse.results:type_name -> pipelines.v1.Pipeline
-	4, // 1: pipelines.v1.Pipeline.environments:type_name -> pipelines.v1.Pipeline.Environment
-	5, // 2: pipelines.v1.Pipeline.Environment.charts:type_name -> pipelines.v1.Pipeline.Environment.HelmChart
-	3, // 3: pipelines.v1.Pipeline.Environment.HelmChart.source:type_name -> pipelines.v1.CrossNamespaceObjectReference
-	0, // 4: pipelines.v1.PipelinesService.ListPipelines:input_type -> pipelines.v1.ListPipelinesRequest
-	1, // 5: pipelines.v1.PipelinesService.ListPipelines:output_type -> pipelines.v1.ListPipelinesResponse
-	5, // [5:6] is the sub-list for method output_type
-	4, // [4:5] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	12, // 0: pipelines.v1.ListPipelinesResponse.results:type_name -> pipelines.v1.Pipeline
+	14, // 1: pipelines.v1.ListPipelinesResponse.diagnostics:type_name -> pipelines.v1.Diagnostic
+	12, // 2: pipelines.v1.GetPipelineResponse.pipeline:type_name -> pipelines.v1.Pipeline
+	10, // 3: pipelines.v1.ListPromotionsResponse.results:type_name -> pipelines.v1.Promotion
+	11, // 4: pipelines.v1.ListUpgradesResponse.results:type_name -> pipelines.v1.ChartUpgrade
+	17, // 5: pipelines.v1.Promotion.from:type_name -> pipelines.v1.Pipeline.Environment.HelmChart
+	17, // 6: pipelines.v1.Promotion.to:type_name -> pipelines.v1.Pipeline.Environment.HelmChart
+	15, // 7: pipelines.v1.Promotion.promoted_releases:type_name -> pipelines.v1.CrossNamespaceObjectReference
+	17, // 8: pipelines.v1.ChartUpgrade.current:type_name -> pipelines.v1.Pipeline.Environment.HelmChart
+	17, // 9: pipelines.v1.ChartUpgrade.available:type_name -> pipelines.v1.Pipeline.Environment.HelmChart
+	16, // 10: pipelines.v1.Pipeline.environments:type_name -> pipelines.v1.Pipeline.Environment
+	15, // 11: pipelines.v1.HelmReleaseStatus.helm_release:type_name -> pipelines.v1.CrossNamespaceObjectReference
+	18, // 12: pipelines.v1.HelmReleaseStatus.ready_transition_time:type_name -> google.protobuf.Timestamp
+	15, // 13: pipelines.v1.Diagnostic.helm_release:type_name -> pipelines.v1.CrossNamespaceObjectReference
+	17, // 14: pipelines.v1.Pipeline.Environment.charts:type_name -> pipelines.v1.Pipeline.Environment.HelmChart
+	15, // 15: pipelines.v1.Pipeline.Environment.HelmChart.source:type_name -> pipelines.v1.CrossNamespaceObjectReference
+	13, // 16: pipelines.v1.Pipeline.Environment.HelmChart.releases:type_name -> pipelines.v1.HelmReleaseStatus
+	0,  // 17: pipelines.v1.PipelinesService.ListPipelines:input_type -> pipelines.v1.ListPipelinesRequest
+	2,  // 18: pipelines.v1.PipelinesService.GetPipeline:input_type -> pipelines.v1.GetPipelineRequest
+	4,  // 19: pipelines.v1.PipelinesService.DiffPromotion:input_type -> pipelines.v1.DiffPromotionRequest
+	6,  // 20: pipelines.v1.PipelinesService.ListPromotions:input_type -> pipelines.v1.ListPromotionsRequest
+	8,  // 21: pipelines.v1.PipelinesService.ListUpgrades:input_type -> pipelines.v1.ListUpgradesRequest
+	1,  // 22: pipelines.v1.PipelinesService.ListPipelines:output_type -> pipelines.v1.ListPipelinesResponse
+	3,  // 23: pipelines.v1.PipelinesService.GetPipeline:output_type -> pipelines.v1.GetPipelineResponse
+	5,  // 24: pipelines.v1.PipelinesService.DiffPromotion:output_type -> pipelines.v1.DiffPromotionResponse
+	7,  // 25: pipelines.v1.PipelinesService.ListPromotions:output_type -> pipelines.v1.ListPromotionsResponse
+	9,  // 26: pipelines.v1.PipelinesService.ListUpgrades:output_type -> pipelines.v1.ListUpgradesResponse
+	22, // [22:27] is the sub-list for method output_type
+	17, // [17:22] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
 }
 
 func init() { file_pipelines_v1_pipelines_service_proto_init() }
@@ -451,87 +1349,13 @@ func file_pipelines_v1_pipelines_service_proto_init() {
 	if File_pipelines_v1_pipelines_service_proto != nil {
 		return
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_pipelines_v1_pipelines_service_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ListPipelinesRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_pipelines_v1_pipelines_service_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ListPipelinesResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_pipelines_v1_pipelines_service_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Pipeline); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_pipelines_v1_pipelines_service_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CrossNamespaceObjectReference); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_pipelines_v1_pipelines_service_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Pipeline_Environment); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_pipelines_v1_pipelines_service_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Pipeline_Environment_HelmChart); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_pipelines_v1_pipelines_service_proto_rawDesc,
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pipelines_v1_pipelines_service_proto_rawDesc), len(file_pipelines_v1_pipelines_service_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   18,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -540,7 +1364,6 @@ func file_pipelines_v1_pipelines_service_proto_init() {
 		MessageInfos:      file_pipelines_v1_pipelines_service_proto_msgTypes,
 	}.Build()
 	File_pipelines_v1_pipelines_service_proto = out.File
-	file_pipelines_v1_pipelines_service_proto_rawDesc = nil
 	file_pipelines_v1_pipelines_service_proto_goTypes = nil
 	file_pipelines_v1_pipelines_service_proto_depIdxs = nil
 }
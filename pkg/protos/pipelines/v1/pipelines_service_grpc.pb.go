@@ -11,6 +11,7 @@ import (
 
 // This is a compile-time assertion to ensure that this generated file
 // is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
 const _ = grpc.SupportPackageIsVersion7
 
 // PipelinesServiceClient is the client API for PipelinesService service.
@@ -19,6 +20,14 @@ const _ = grpc.SupportPackageIsVersion7
 type PipelinesServiceClient interface {
 	// List all Pipelines
 	ListPipelines(ctx context.Context, in *ListPipelinesRequest, opts ...grpc.CallOption) (*ListPipelinesResponse, error)
+	// Get a single Pipeline by name
+	GetPipeline(ctx context.Context, in *GetPipelineRequest, opts ...grpc.CallOption) (*GetPipelineResponse, error)
+	// Diff the charts in a promotion
+	DiffPromotion(ctx context.Context, in *DiffPromotionRequest, opts ...grpc.CallOption) (*DiffPromotionResponse, error)
+	// List the promotions between the environments in a Pipeline
+	ListPromotions(ctx context.Context, in *ListPromotionsRequest, opts ...grpc.CallOption) (*ListPromotionsResponse, error)
+	// List newer versions of the charts in a Pipeline
+	ListUpgrades(ctx context.Context, in *ListUpgradesRequest, opts ...grpc.CallOption) (*ListUpgradesResponse, error)
 }
 
 type pipelinesServiceClient struct {
@@ -38,12 +47,56 @@ func (c *pipelinesServiceClient) ListPipelines(ctx context.Context, in *ListPipe
 	return out, nil
 }
 
+func (c *pipelinesServiceClient) GetPipeline(ctx context.Context, in *GetPipelineRequest, opts ...grpc.CallOption) (*GetPipelineResponse, error) {
+	out := new(GetPipelineResponse)
+	err := c.cc.Invoke(ctx, "/pipelines.v1.PipelinesService/GetPipeline", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pipelinesServiceClient) DiffPromotion(ctx context.Context, in *DiffPromotionRequest, opts ...grpc.CallOption) (*DiffPromotionResponse, error) {
+	out := new(DiffPromotionResponse)
+	err := c.cc.Invoke(ctx, "/pipelines.v1.PipelinesService/DiffPromotion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pipelinesServiceClient) ListPromotions(ctx context.Context, in *ListPromotionsRequest, opts ...grpc.CallOption) (*ListPromotionsResponse, error) {
+	out := new(ListPromotionsResponse)
+	err := c.cc.Invoke(ctx, "/pipelines.v1.PipelinesService/ListPromotions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pipelinesServiceClient) ListUpgrades(ctx context.Context, in *ListUpgradesRequest, opts ...grpc.CallOption) (*ListUpgradesResponse, error) {
+	out := new(ListUpgradesResponse)
+	err := c.cc.Invoke(ctx, "/pipelines.v1.PipelinesService/ListUpgrades", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PipelinesServiceServer is the server API for PipelinesService service.
 // All implementations should embed UnimplementedPipelinesServiceServer
 // for forward compatibility
 type PipelinesServiceServer interface {
 	// List all Pipelines
 	ListPipelines(context.Context, *ListPipelinesRequest) (*ListPipelinesResponse, error)
+	// Get a single Pipeline by name
+	GetPipeline(context.Context, *GetPipelineRequest) (*GetPipelineResponse, error)
+	// Diff the charts in a promotion
+	DiffPromotion(context.Context, *DiffPromotionRequest) (*DiffPromotionResponse, error)
+	// List the promotions between the environments in a Pipeline
+	ListPromotions(context.Context, *ListPromotionsRequest) (*ListPromotionsResponse, error)
+	// List newer versions of the charts in a Pipeline
+	ListUpgrades(context.Context, *ListUpgradesRequest) (*ListUpgradesResponse, error)
 }
 
 // UnimplementedPipelinesServiceServer should be embedded to have forward compatible implementations.
@@ -53,6 +106,18 @@ type UnimplementedPipelinesServiceServer struct {
 func (UnimplementedPipelinesServiceServer) ListPipelines(context.Context, *ListPipelinesRequest) (*ListPipelinesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListPipelines not implemented")
 }
+func (UnimplementedPipelinesServiceServer) GetPipeline(context.Context, *GetPipelineRequest) (*GetPipelineResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPipeline not implemented")
+}
+func (UnimplementedPipelinesServiceServer) DiffPromotion(context.Context, *DiffPromotionRequest) (*DiffPromotionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DiffPromotion not implemented")
+}
+func (UnimplementedPipelinesServiceServer) ListPromotions(context.Context, *ListPromotionsRequest) (*ListPromotionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPromotions not implemented")
+}
+func (UnimplementedPipelinesServiceServer) ListUpgrades(context.Context, *ListUpgradesRequest) (*ListUpgradesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUpgrades not implemented")
+}
 
 // UnsafePipelinesServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to PipelinesServiceServer will
@@ -61,8 +126,8 @@ type UnsafePipelinesServiceServer interface {
 	mustEmbedUnimplementedPipelinesServiceServer()
 }
 
-func RegisterPipelinesServiceServer(s *grpc.Server, srv PipelinesServiceServer) {
-	s.RegisterService(&_PipelinesService_serviceDesc, srv)
+func RegisterPipelinesServiceServer(s grpc.ServiceRegistrar, srv PipelinesServiceServer) {
+	s.RegisterService(&PipelinesService_ServiceDesc, srv)
 }
 
 func _PipelinesService_ListPipelines_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
@@ -83,7 +148,82 @@ func _PipelinesService_ListPipelines_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
-var _PipelinesService_serviceDesc = grpc.ServiceDesc{
+func _PipelinesService_GetPipeline_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPipelineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PipelinesServiceServer).GetPipeline(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pipelines.v1.PipelinesService/GetPipeline",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PipelinesServiceServer).GetPipeline(ctx, req.(*GetPipelineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PipelinesService_DiffPromotion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiffPromotionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PipelinesServiceServer).DiffPromotion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pipelines.v1.PipelinesService/DiffPromotion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PipelinesServiceServer).DiffPromotion(ctx, req.(*DiffPromotionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PipelinesService_ListPromotions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPromotionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PipelinesServiceServer).ListPromotions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pipelines.v1.PipelinesService/ListPromotions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PipelinesServiceServer).ListPromotions(ctx, req.(*ListPromotionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PipelinesService_ListUpgrades_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUpgradesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PipelinesServiceServer).ListUpgrades(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pipelines.v1.PipelinesService/ListUpgrades",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PipelinesServiceServer).ListUpgrades(ctx, req.(*ListUpgradesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PipelinesService_ServiceDesc is the grpc.ServiceDesc for PipelinesService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PipelinesService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "pipelines.v1.PipelinesService",
 	HandlerType: (*PipelinesServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
@@ -91,6 +231,22 @@ var _PipelinesService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ListPipelines",
 			Handler:    _PipelinesService_ListPipelines_Handler,
 		},
+		{
+			MethodName: "GetPipeline",
+			Handler:    _PipelinesService_GetPipeline_Handler,
+		},
+		{
+			MethodName: "DiffPromotion",
+			Handler:    _PipelinesService_DiffPromotion_Handler,
+		},
+		{
+			MethodName: "ListPromotions",
+			Handler:    _PipelinesService_ListPromotions_Handler,
+		},
+		{
+			MethodName: "ListUpgrades",
+			Handler:    _PipelinesService_ListUpgrades_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pipelines/v1/pipelines_service.proto",
@@ -0,0 +1,179 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NamespacedRules are the rules that are needed in each namespace to discover
+// the pipelines in the namespace.
+var NamespacedRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{"helm.toolkit.fluxcd.io"},
+		Resources: []string{"helmreleases"},
+		Verbs:     []string{"get", "list"},
+	},
+	{
+		APIGroups: []string{"source.toolkit.fluxcd.io"},
+		Resources: []string{"helmrepositories", "gitrepositories", "buckets", "ocirepositories", "helmcharts"},
+		Verbs:     []string{"get"},
+	},
+}
+
+// ClusterRules are the additional rules that are needed to discover the
+// pipelines in every namespace.
+var ClusterRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{""},
+		Resources: []string{"namespaces"},
+		Verbs:     []string{"get", "list"},
+	},
+}
+
+// TokenReviewRules are the rules that are needed to authenticate bearer
+// tokens with TokenReviews, these are cluster-scoped and need a ClusterRole,
+// even when pipelines are only discovered in some namespaces.
+var TokenReviewRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{"authentication.k8s.io"},
+		Resources: []string{"tokenreviews"},
+		Verbs:     []string{"create"},
+	},
+}
+
+// AuthDelegatorClusterRole is the built-in ClusterRole that grants the
+// TokenReviewRules.
+const AuthDelegatorClusterRole = "system:auth-delegator"
+
+// NamespacedRoles returns a Role with the NamespacedRules, and a RoleBinding
+// that binds it to the ServiceAccount, in each of the namespaces.
+func NamespacedRoles(name string, serviceAccount client.ObjectKey, namespaces []string) []client.Object {
+	objs := []client.Object{}
+	for _, ns := range namespaces {
+		objs = append(objs,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+				Rules:      NamespacedRules,
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+				RoleRef: rbacv1.RoleRef{
+					APIGroup: rbacv1.GroupName,
+					Kind:     "Role",
+					Name:     name,
+				},
+				Subjects: []rbacv1.Subject{
+					{
+						Kind:      rbacv1.ServiceAccountKind,
+						Name:      serviceAccount.Name,
+						Namespace: serviceAccount.Namespace,
+					},
+				},
+			})
+	}
+
+	return objs
+}
+
+// TokenReviewBinding returns a ClusterRoleBinding that binds the
+// AuthDelegatorClusterRole to the ServiceAccount.
+func TokenReviewBinding(name string, serviceAccount client.ObjectKey) client.Object {
+	return &rbacv1.ClusterRoleBinding{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     AuthDelegatorClusterRole,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      serviceAccount.Name,
+				Namespace: serviceAccount.Namespace,
+			},
+		},
+	}
+}
+
+// CheckAccess checks that the client has the permissions that are needed to
+// discover pipelines in the namespaces, or in every namespace if no
+// namespaces are provided.
+//
+// Each missing permission is reported in the returned error.
+func CheckAccess(ctx context.Context, cl client.Client, namespaces []string) error {
+	checks := []authorizationv1.ResourceAttributes{}
+	if len(namespaces) == 0 {
+		checks = append(checks, resourceAttributes(ClusterRules, "")...)
+		checks = append(checks, resourceAttributes(NamespacedRules, "")...)
+	}
+	for _, ns := range namespaces {
+		checks = append(checks, resourceAttributes(NamespacedRules, ns)...)
+	}
+
+	return checkAttributes(ctx, cl, checks)
+}
+
+// CheckTokenReviewAccess checks that the client has the permissions that are
+// needed to authenticate bearer tokens.
+func CheckTokenReviewAccess(ctx context.Context, cl client.Client) error {
+	return checkAttributes(ctx, cl, resourceAttributes(TokenReviewRules, ""))
+}
+
+func checkAttributes(ctx context.Context, cl client.Client, checks []authorizationv1.ResourceAttributes) error {
+	var errs []error
+	for _, attrs := range checks {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attrs},
+		}
+		if err := cl.Create(ctx, review); err != nil {
+			return fmt.Errorf("failed to create SelfSubjectAccessReview: %w", err)
+		}
+		if !review.Status.Allowed {
+			errs = append(errs, fmt.Errorf("missing permission to %s", describe(attrs)))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func resourceAttributes(rules []rbacv1.PolicyRule, ns string) []authorizationv1.ResourceAttributes {
+	attrs := []authorizationv1.ResourceAttributes{}
+	for _, rule := range rules {
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				for _, verb := range rule.Verbs {
+					attrs = append(attrs, authorizationv1.ResourceAttributes{
+						Namespace: ns,
+						Verb:      verb,
+						Group:     group,
+						Resource:  resource,
+					})
+				}
+			}
+		}
+	}
+
+	return attrs
+}
+
+func describe(attrs authorizationv1.ResourceAttributes) string {
+	resource := strings.Join([]string{attrs.Resource, attrs.Group}, ".")
+	if attrs.Group == "" {
+		resource = attrs.Resource
+	}
+	if attrs.Namespace == "" {
+		return fmt.Sprintf("%s %s in all namespaces", attrs.Verb, resource)
+	}
+
+	return fmt.Sprintf("%s %s in namespace %s", attrs.Verb, resource, attrs.Namespace)
+}
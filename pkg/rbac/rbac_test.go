@@ -0,0 +1,125 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestNamespacedRoles(t *testing.T) {
+	objs := NamespacedRoles("peanut-helmpipelines", client.ObjectKey{Name: "peanut", Namespace: "pipelines"}, []string{"staging", "production"})
+
+	if l := len(objs); l != 4 {
+		t.Fatalf("got %d objects, want 4", l)
+	}
+	binding, ok := objs[3].(*rbacv1.RoleBinding)
+	if !ok {
+		t.Fatalf("got %T, want a RoleBinding", objs[3])
+	}
+	wantRef := rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "Role", Name: "peanut-helmpipelines"}
+	if diff := cmp.Diff(wantRef, binding.RoleRef); diff != "" {
+		t.Fatalf("incorrect role ref:\n%s", diff)
+	}
+	wantSubjects := []rbacv1.Subject{{Kind: "ServiceAccount", Name: "peanut", Namespace: "pipelines"}}
+	if diff := cmp.Diff(wantSubjects, binding.Subjects); diff != "" {
+		t.Fatalf("incorrect subjects:\n%s", diff)
+	}
+	if ns := binding.GetNamespace(); ns != "production" {
+		t.Fatalf("got namespace %q, want %q", ns, "production")
+	}
+}
+
+func TestCheckAccess(t *testing.T) {
+	allowed := map[string]bool{
+		"list helmreleases.helm.toolkit.fluxcd.io in namespace staging":         true,
+		"get helmreleases.helm.toolkit.fluxcd.io in namespace staging":          true,
+		"get helmrepositories.source.toolkit.fluxcd.io in namespace staging":    true,
+		"get gitrepositories.source.toolkit.fluxcd.io in namespace staging":     true,
+		"get buckets.source.toolkit.fluxcd.io in namespace staging":             true,
+		"get ocirepositories.source.toolkit.fluxcd.io in namespace staging":     true,
+		"get helmcharts.source.toolkit.fluxcd.io in namespace staging":          true,
+		"get helmrepositories.source.toolkit.fluxcd.io in namespace production": true,
+		"get gitrepositories.source.toolkit.fluxcd.io in namespace production":  true,
+		"get buckets.source.toolkit.fluxcd.io in namespace production":          true,
+		"get ocirepositories.source.toolkit.fluxcd.io in namespace production":  true,
+		"get helmcharts.source.toolkit.fluxcd.io in namespace production":       true,
+	}
+	cl := newFakeClient(t, allowed)
+
+	if err := CheckAccess(context.TODO(), cl, []string{"staging"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := CheckAccess(context.TODO(), cl, []string{"staging", "production"})
+	want := "missing permission to get helmreleases.helm.toolkit.fluxcd.io in namespace production\n" +
+		"missing permission to list helmreleases.helm.toolkit.fluxcd.io in namespace production"
+	if err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+
+	err = CheckAccess(context.TODO(), cl, nil)
+	if err == nil {
+		t.Fatal("expected an error checking access to all namespaces")
+	}
+}
+
+func TestTokenReviewBinding(t *testing.T) {
+	binding, ok := TokenReviewBinding("peanut-helmpipelines", client.ObjectKey{Name: "peanut", Namespace: "pipelines"}).(*rbacv1.ClusterRoleBinding)
+	if !ok {
+		t.Fatal("want a ClusterRoleBinding")
+	}
+
+	wantRef := rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "system:auth-delegator"}
+	if diff := cmp.Diff(wantRef, binding.RoleRef); diff != "" {
+		t.Fatalf("incorrect role ref:\n%s", diff)
+	}
+	wantSubjects := []rbacv1.Subject{{Kind: "ServiceAccount", Name: "peanut", Namespace: "pipelines"}}
+	if diff := cmp.Diff(wantSubjects, binding.Subjects); diff != "" {
+		t.Fatalf("incorrect subjects:\n%s", diff)
+	}
+}
+
+func TestCheckTokenReviewAccess(t *testing.T) {
+	if err := CheckTokenReviewAccess(context.TODO(), newFakeClient(t, map[string]bool{
+		"create tokenreviews.authentication.k8s.io in all namespaces": true,
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	err := CheckTokenReviewAccess(context.TODO(), newFakeClient(t, map[string]bool{}))
+	want := "missing permission to create tokenreviews.authentication.k8s.io in all namespaces"
+	if err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+}
+
+// newFakeClient returns a client that allows the SelfSubjectAccessReviews
+// with descriptions in the allowed map.
+func newFakeClient(t *testing.T, allowed map[string]bool) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := authorizationv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authorizationv1.SelfSubjectAccessReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				review.Status.Allowed = allowed[describe(*review.Spec.ResourceAttributes)]
+				return nil
+			},
+		}).
+		Build()
+}
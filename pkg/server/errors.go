@@ -0,0 +1,116 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+)
+
+// statusError converts an error from discovering pipelines to a gRPC status
+// error, errors that are already gRPC errors are returned unchanged.
+//
+// HelmReleases that can't be parsed, charts that can't be fetched, and
+// resources that aren't found are FailedPrecondition, RBAC failures are
+// PermissionDenied, and conflicting changes are Aborted.
+//
+// Timeouts, server errors and failures connecting to the API server are
+// Unavailable, other errors from the API server are Internal.
+func statusError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var releaseErr *helm.ReleaseError
+	if errors.As(err, &releaseErr) {
+		return withDetails(status.New(codes.FailedPrecondition, err.Error()), &errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{
+				{
+					Type:        "HelmRelease",
+					Subject:     releaseErr.HelmRelease.Namespace + "/" + releaseErr.HelmRelease.Name,
+					Description: releaseErr.Err.Error(),
+				},
+			},
+		})
+	}
+
+	if errors.Is(err, helm.ErrUnsupportedSource) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	var apiStatus apierrors.APIStatus
+	if errors.As(err, &apiStatus) {
+		return apiStatusError(err, apiStatus)
+	}
+
+	var netErr net.Error
+	var urlErr *url.Error
+	if errors.As(err, &netErr) || errors.As(err, &urlErr) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+// apiStatusError converts an error with a status from the API server to a
+// gRPC status error.
+func apiStatusError(err error, apiStatus apierrors.APIStatus) error {
+	switch {
+	case apierrors.IsForbidden(err):
+		return withDetails(status.New(codes.PermissionDenied, err.Error()), apiResource(err))
+	case apierrors.IsNotFound(err):
+		return withDetails(status.New(codes.FailedPrecondition, err.Error()), apiResource(err))
+	case apierrors.IsConflict(err):
+		return status.Error(codes.Aborted, err.Error())
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err), apierrors.IsTooManyRequests(err),
+		apiStatus.Status().Code >= http.StatusInternalServerError:
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+// invalidArgument returns an InvalidArgument error with the field in the
+// request that is invalid.
+func invalidArgument(field string, err error) error {
+	return withDetails(status.New(codes.InvalidArgument, err.Error()), &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: field, Description: err.Error()},
+		},
+	})
+}
+
+// apiResource identifies the resource that an API server error is about from
+// the details of the error.
+func apiResource(err error) *errdetails.ResourceInfo {
+	info := &errdetails.ResourceInfo{Description: err.Error()}
+	var apiStatus apierrors.APIStatus
+	if errors.As(err, &apiStatus) && apiStatus.Status().Details != nil {
+		details := apiStatus.Status().Details
+		info.ResourceType = details.Kind
+		if details.Group != "" {
+			info.ResourceType = fmt.Sprintf("%s.%s", details.Kind, details.Group)
+		}
+		info.ResourceName = details.Name
+	}
+
+	return info
+}
+
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) error {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
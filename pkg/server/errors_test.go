@@ -0,0 +1,144 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+)
+
+func TestStatusError(t *testing.T) {
+	helmReleases := schema.GroupResource{Group: "helm.toolkit.fluxcd.io", Resource: "helmreleases"}
+	forbidden := apierrors.NewForbidden(helmReleases, "", errors.New("RBAC denied"))
+	notFound := apierrors.NewNotFound(schema.GroupResource{Group: "source.toolkit.fluxcd.io", Resource: "helmrepositories"}, "podinfo")
+	releaseErr := &helm.ReleaseError{
+		HelmRelease: helmv2.CrossNamespaceObjectReference{Name: "redis", Namespace: "staging"},
+		Err:         errors.New("invalid values"),
+	}
+
+	statusTests := []struct {
+		name        string
+		err         error
+		wantCode    codes.Code
+		wantDetails []interface{}
+	}{
+		{
+			name:     "grpc error",
+			err:      status.Error(codes.NotFound, "not found"),
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "forbidden",
+			err:      fmt.Errorf("failed to list helm releases: %w", forbidden),
+			wantCode: codes.PermissionDenied,
+			wantDetails: []interface{}{
+				&errdetails.ResourceInfo{
+					ResourceType: "helmreleases.helm.toolkit.fluxcd.io",
+					Description:  "failed to list helm releases: " + forbidden.Error(),
+				},
+			},
+		},
+		{
+			name:     "api server failure",
+			err:      apierrors.NewServiceUnavailable("try again later"),
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:     "api server connection failure",
+			err:      &url.Error{Op: "Get", URL: "https://kubernetes", Err: errors.New("connection refused")},
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:     "api server timeout",
+			err:      apierrors.NewTimeoutError("request timed out", 1),
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:     "api server internal error",
+			err:      apierrors.NewInternalError(errors.New("etcd failure")),
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:     "not found",
+			err:      fmt.Errorf("failed to get HelmRepository: %w", notFound),
+			wantCode: codes.FailedPrecondition,
+			wantDetails: []interface{}{
+				&errdetails.ResourceInfo{
+					ResourceType: "helmrepositories.source.toolkit.fluxcd.io",
+					ResourceName: "podinfo",
+					Description:  "failed to get HelmRepository: " + notFound.Error(),
+				},
+			},
+		},
+		{
+			name:     "conflict",
+			err:      apierrors.NewConflict(helmReleases, "redis", errors.New("the object has been modified")),
+			wantCode: codes.Aborted,
+		},
+		{
+			name:     "invalid",
+			err:      apierrors.NewInvalid(schema.GroupKind{Group: "helm.toolkit.fluxcd.io", Kind: "HelmRelease"}, "redis", nil),
+			wantCode: codes.Internal,
+		},
+		{
+			name:     "invalid HelmRelease",
+			err:      fmt.Errorf("failed to discover pipelines: %w", releaseErr),
+			wantCode: codes.FailedPrecondition,
+			wantDetails: []interface{}{
+				&errdetails.PreconditionFailure{
+					Violations: []*errdetails.PreconditionFailure_Violation{
+						{Type: "HelmRelease", Subject: "staging/redis", Description: "invalid values"},
+					},
+				},
+			},
+		},
+		{
+			name:     "other error",
+			err:      errors.New("failed to render chart"),
+			wantCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range statusTests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(statusError(tt.err))
+
+			if st.Code() != tt.wantCode {
+				t.Fatalf("got error code %v, want %v", st.Code(), tt.wantCode)
+			}
+			if diff := cmp.Diff(tt.wantDetails, st.Details(), protocmp.Transform(), cmpopts.EquateEmpty()); diff != "" {
+				t.Fatalf("incorrect error details:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInvalidArgument(t *testing.T) {
+	st := status.Convert(invalidArgument("page_size", errors.New("invalid page size -1")))
+
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("got error code %v, want %v", st.Code(), codes.InvalidArgument)
+	}
+	want := []interface{}{
+		&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "page_size", Description: "invalid page size -1"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, st.Details(), protocmp.Transform(), cmpopts.EquateEmpty()); diff != "" {
+		t.Fatalf("incorrect error details:\n%s", diff)
+	}
+}
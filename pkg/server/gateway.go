@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pipelinesv1 "github.com/bigkevmcd/peanut-helmpipelines/pkg/protos/pipelines/v1"
+)
+
+// NewGatewayHandler creates an http.Handler that serves the PipelinesService
+// as REST/JSON.
+//
+// Requests are passed directly to the server, through the interceptor if one
+// is provided, so that they are authenticated, logged and counted in the same
+// way as gRPC requests, the interceptor gets the full gRPC method name.
+//
+// Responses are marshaled with the default protojson options, which leave out
+// unpopulated fields, the same as the JSON output from helm-pipelines.
+func NewGatewayHandler(ctx context.Context, srv pipelinesv1.PipelinesServiceServer, interceptor grpc.UnaryServerInterceptor) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+	)
+	if interceptor != nil {
+		srv = &interceptedServer{srv: srv, interceptor: interceptor}
+	}
+	if err := pipelinesv1.RegisterPipelinesServiceHandlerServer(ctx, mux, srv); err != nil {
+		return nil, fmt.Errorf("failed to register the gateway handler: %w", err)
+	}
+
+	return mux, nil
+}
+
+// interceptedServer calls the methods of the PipelinesServiceServer through a
+// gRPC interceptor, as the gateway calls the server directly.
+type interceptedServer struct {
+	srv         pipelinesv1.PipelinesServiceServer
+	interceptor grpc.UnaryServerInterceptor
+}
+
+func (s *interceptedServer) ListPipelines(ctx context.Context, req *pipelinesv1.ListPipelinesRequest) (*pipelinesv1.ListPipelinesResponse, error) {
+	return intercept(ctx, s, req, s.srv.ListPipelines)
+}
+
+func (s *interceptedServer) GetPipeline(ctx context.Context, req *pipelinesv1.GetPipelineRequest) (*pipelinesv1.GetPipelineResponse, error) {
+	return intercept(ctx, s, req, s.srv.GetPipeline)
+}
+
+func (s *interceptedServer) DiffPromotion(ctx context.Context, req *pipelinesv1.DiffPromotionRequest) (*pipelinesv1.DiffPromotionResponse, error) {
+	return intercept(ctx, s, req, s.srv.DiffPromotion)
+}
+
+func (s *interceptedServer) ListPromotions(ctx context.Context, req *pipelinesv1.ListPromotionsRequest) (*pipelinesv1.ListPromotionsResponse, error) {
+	return intercept(ctx, s, req, s.srv.ListPromotions)
+}
+
+func (s *interceptedServer) ListUpgrades(ctx context.Context, req *pipelinesv1.ListUpgradesRequest) (*pipelinesv1.ListUpgradesResponse, error) {
+	return intercept(ctx, s, req, s.srv.ListUpgrades)
+}
+
+// intercept calls the method through the interceptor, with the gRPC method
+// name that the gateway adds to the context.
+func intercept[Req, Resp any](ctx context.Context, s *interceptedServer, req *Req, method func(context.Context, *Req) (*Resp, error)) (*Resp, error) {
+	name, _ := runtime.RPCMethod(ctx)
+	info := &grpc.UnaryServerInfo{Server: s.srv, FullMethod: name}
+	resp, err := s.interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return method(ctx, req.(*Req))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*Resp), nil
+}
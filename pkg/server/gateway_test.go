@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/auth"
+	pipelinesv1 "github.com/bigkevmcd/peanut-helmpipelines/pkg/protos/pipelines/v1"
+	"github.com/bigkevmcd/peanut-helmpipelines/test"
+)
+
+func TestGatewayHandler(t *testing.T) {
+	hr := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""))
+	handler := newTestGateway(t, NewPipelinesServer(logr.Discard(), newFakeClient(t, &hr)), nil)
+
+	gatewayTests := []struct {
+		path       string
+		wantStatus int
+		want       map[string]interface{}
+	}{
+		{
+			path:       "/v1/pipelines?chartName=redis",
+			wantStatus: http.StatusOK,
+			want: map[string]interface{}{
+				"count": float64(1),
+				"results": []interface{}{
+					map[string]interface{}{
+						"name": "demo-pipeline",
+						"environments": []interface{}{
+							map[string]interface{}{
+								"name": "staging",
+								"charts": []interface{}{
+									map[string]interface{}{
+										"name":    "redis",
+										"version": "1.0.9",
+										"source": map[string]interface{}{
+											"kind": "HelmRepository", "namespace": "default", "name": "test-repository",
+										},
+										"releases": []interface{}{
+											map[string]interface{}{
+												"helmRelease": map[string]interface{}{
+													"kind": "HelmRelease", "namespace": "default", "name": "test-release",
+												},
+												"ready": "Unknown",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				"diagnostics": []interface{}{
+					map[string]interface{}{
+						"pipeline":    "demo-pipeline",
+						"environment": "staging",
+						"helmRelease": map[string]interface{}{
+							"kind": "HelmRelease", "namespace": "default", "name": "test-release",
+						},
+						"reason":  "MissingSource",
+						"message": "HelmRepository default/test-repository not found",
+					},
+				},
+			},
+		},
+		{
+			path:       "/v1/pipelines/demo-pipeline/promotions",
+			wantStatus: http.StatusOK,
+			want:       map[string]interface{}{},
+		},
+		{
+			path:       "/v1/pipelines/unknown-pipeline",
+			wantStatus: http.StatusNotFound,
+			want: map[string]interface{}{
+				"code":    float64(5),
+				"message": `pipeline "unknown-pipeline" not found`,
+			},
+		},
+	}
+
+	for _, tt := range gatewayTests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			got := map[string]interface{}{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("incorrect response:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGatewayHandler_authentication(t *testing.T) {
+	hr := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""))
+	handler := newTestGateway(t, NewPipelinesServer(logr.Discard(), newFakeClient(t, &hr)), auth.UnaryServerInterceptor(stubAuthenticator{}))
+
+	authTests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"invalid token", "Bearer invalid-token", http.StatusUnauthorized},
+		{"valid token", "Bearer valid-token", http.StatusOK},
+	}
+
+	for _, tt := range authTests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/v1/pipelines/demo-pipeline", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGatewayHandler_interceptor(t *testing.T) {
+	hr := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""))
+	var methods []string
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		methods = append(methods, info.FullMethod)
+		return handler(ctx, req)
+	}
+	handler := newTestGateway(t, NewPipelinesServer(logr.Discard(), newFakeClient(t, &hr)), interceptor)
+
+	for _, path := range []string{"/v1/pipelines", "/v1/pipelines/demo-pipeline", "/v1/pipelines/unknown-pipeline"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	want := []string{
+		"/pipelines.v1.PipelinesService/ListPipelines",
+		"/pipelines.v1.PipelinesService/GetPipeline",
+		"/pipelines.v1.PipelinesService/GetPipeline",
+	}
+	if diff := cmp.Diff(want, methods); diff != "" {
+		t.Fatalf("incorrect intercepted methods:\n%s", diff)
+	}
+}
+
+func newTestGateway(t *testing.T, srv pipelinesv1.PipelinesServiceServer, interceptor grpc.UnaryServerInterceptor) http.Handler {
+	t.Helper()
+	handler, err := NewGatewayHandler(context.TODO(), srv, interceptor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return handler
+}
+
+// stubAuthenticator only authenticates "valid-token".
+type stubAuthenticator struct{}
+
+func (stubAuthenticator) Authenticate(ctx context.Context, token string) (*auth.User, error) {
+	if token != "valid-token" {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	return &auth.User{Name: "testing"}, nil
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	pipelinesv1 "github.com/bigkevmcd/peanut-helmpipelines/pkg/protos/pipelines/v1"
+)
+
+// NewHealthServer creates a gRPC health server, registered with the gRPC
+// server, that reports the server and the PipelinesService as serving.
+//
+// Call Shutdown on the health server when the server starts shutting down.
+func NewHealthServer(srv *grpc.Server) *health.Server {
+	hs := health.NewServer()
+	hs.SetServingStatus(pipelinesv1.PipelinesService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+
+	return hs
+}
+
+// NewMetricsHandler creates an http.Handler that serves the Prometheus
+// metrics at /metrics, and liveness and readiness checks at /healthz and
+// /readyz.
+//
+// The readiness check reports the overall status of the health server, so
+// that the server stops receiving traffic when it starts shutting down.
+func NewMetricsHandler(hs healthpb.HealthServer) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		resp, err := hs.Check(r.Context(), &healthpb.HealthCheckRequest{})
+		if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	return mux
+}
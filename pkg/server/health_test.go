@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestNewHealthServer(t *testing.T) {
+	hs := NewHealthServer(grpc.NewServer())
+
+	for _, service := range []string{"", "pipelines.v1.PipelinesService"} {
+		resp, err := hs.Check(context.TODO(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("got status %v for service %q, want %v", resp.GetStatus(), service, healthpb.HealthCheckResponse_SERVING)
+		}
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	hs := NewHealthServer(grpc.NewServer())
+	handler := NewMetricsHandler(hs)
+
+	assertStatus := func(t *testing.T, path string, want int) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Fatalf("got status %d for %s, want %d", w.Code, path, want)
+		}
+	}
+
+	assertStatus(t, "/metrics", http.StatusOK)
+	assertStatus(t, "/healthz", http.StatusOK)
+	assertStatus(t, "/readyz", http.StatusOK)
+
+	hs.Shutdown()
+
+	assertStatus(t, "/healthz", http.StatusOK)
+	assertStatus(t, "/readyz", http.StatusServiceUnavailable)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+)
+
+// paginate returns the page of items starting at the offset in the page
+// token, and the token for the next page.
+//
+// If the page size is zero, all the remaining items are returned.
+func paginate[T any](items []T, pageSize int32, pageToken string) ([]T, string, error) {
+	if pageSize < 0 {
+		return nil, "", invalidArgument("page_size", fmt.Errorf("invalid page size %d", pageSize))
+	}
+	offset, err := decodePageToken(pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	if offset > len(items) {
+		offset = len(items)
+	}
+	end := len(items)
+	if pageSize > 0 && offset+int(pageSize) < end {
+		end = offset + int(pageSize)
+	}
+	nextPageToken := ""
+	if end < len(items) {
+		nextPageToken = encodePageToken(end)
+	}
+
+	return items[offset:end], nextPageToken, nil
+}
+
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, invalidArgument("page_token", fmt.Errorf("invalid page token %q", token))
+	}
+	offset, err := strconv.Atoi(string(b))
+	if err != nil || offset < 0 {
+		return 0, invalidArgument("page_token", fmt.Errorf("invalid page token %q", token))
+	}
+
+	return offset, nil
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPaginate(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+
+	paginateTests := []struct {
+		name      string
+		pageSize  int32
+		pageToken string
+		want      []string
+		wantNext  string
+	}{
+		{name: "no page size", want: items},
+		{name: "first page", pageSize: 2, want: []string{"a", "b"}, wantNext: encodePageToken(2)},
+		{name: "middle page", pageSize: 2, pageToken: encodePageToken(2), want: []string{"c", "d"}, wantNext: encodePageToken(4)},
+		{name: "last page", pageSize: 2, pageToken: encodePageToken(4), want: []string{"e"}},
+		{name: "page size matches remaining", pageSize: 3, pageToken: encodePageToken(2), want: []string{"c", "d", "e"}},
+		{name: "offset past the end", pageSize: 2, pageToken: encodePageToken(10), want: []string{}},
+	}
+
+	for _, tt := range paginateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, next, err := paginate(items, tt.pageSize, tt.pageToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, page); diff != "" {
+				t.Fatalf("failed to paginate:\n%s", diff)
+			}
+			if next != tt.wantNext {
+				t.Fatalf("got next page token %q, want %q", next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestPaginate_errors(t *testing.T) {
+	errorTests := []struct {
+		name      string
+		pageSize  int32
+		pageToken string
+	}{
+		{name: "negative page size", pageSize: -1},
+		{name: "invalid token", pageToken: "not a token"},
+		{name: "token is not an offset", pageToken: "dGVzdA"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := paginate([]string{"a"}, tt.pageSize, tt.pageToken)
+			if code := status.Code(err); code != codes.InvalidArgument {
+				t.Fatalf("got error code %v, want %v", code, codes.InvalidArgument)
+			}
+		})
+	}
+}
@@ -3,13 +3,20 @@ package server
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
-	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
-	"github.com/gitops-tools/apps-scanner/pkg/pipelines"
+	"github.com/gitops-tools/pkg/sets"
 	"github.com/go-logr/logr"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/convert"
 	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
 	pipelinesv1 "github.com/bigkevmcd/peanut-helmpipelines/pkg/protos/pipelines/v1"
 )
 
@@ -19,58 +26,377 @@ type pipelinesGRPCServer struct {
 	pipelinesv1.UnimplementedPipelinesServiceServer
 	logr.Logger
 	client.Client
+	clientFactory ClientFactory
+	includeValues bool
+	keys          pipelinelabels.Keys
+	namespaces    []string
+}
+
+// ClientFactory creates the client that is used to handle a request.
+type ClientFactory interface {
+	ClientFor(ctx context.Context) (client.Client, error)
+}
+
+// Option configures the server.
+type Option func(*pipelinesGRPCServer)
+
+// WithClientFactory configures the server to handle each request with a
+// client from the factory, rather than the server's own client.
+func WithClientFactory(f ClientFactory) Option {
+	return func(s *pipelinesGRPCServer) {
+		s.clientFactory = f
+	}
+}
+
+// WithValuesPromotions configures the server to treat differences in the
+// values of a chart as promotable for every ListPromotions request, this is a
+// policy that requests can't turn off with include_values.
+func WithValuesPromotions() Option {
+	return func(s *pipelinesGRPCServer) {
+		s.includeValues = true
+	}
+}
+
+// WithKeys configures the labels, or annotations, that place resources into
+// pipelines, the default is pipelinelabels.DefaultKeys.
+func WithKeys(k pipelinelabels.Keys) Option {
+	return func(s *pipelinesGRPCServer) {
+		s.keys = k
+	}
+}
+
+// WithNamespaces restricts the server to discovering pipelines in the
+// namespaces, so that it only needs a Role in each namespace, rather than a
+// ClusterRole.
+func WithNamespaces(namespaces ...string) Option {
+	return func(s *pipelinesGRPCServer) {
+		s.namespaces = namespaces
+	}
 }
 
 // NewPipelinesServer creates a new server.
-func NewPipelinesServer(l logr.Logger, c client.Client) *pipelinesGRPCServer {
-	return &pipelinesGRPCServer{Logger: l, Client: c}
+func NewPipelinesServer(l logr.Logger, c client.Client, opts ...Option) *pipelinesGRPCServer {
+	s := &pipelinesGRPCServer{Logger: l, Client: c, keys: pipelinelabels.DefaultKeys}
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
 }
 
 func (s *pipelinesGRPCServer) ListPipelines(ctx context.Context, in *pipelinesv1.ListPipelinesRequest) (*pipelinesv1.ListPipelinesResponse, error) {
-	helmReleaseList := &helmv2.HelmReleaseList{}
-	err := s.Client.List(ctx, helmReleaseList, client.HasLabels([]string{pipelines.PipelineNameLabel}))
+	selector, err := labelSelector(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkNamespace(in.GetNamespace()); err != nil {
+		return nil, err
+	}
+	cl, err := s.clientFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	helmPipelines, diagnostics, err := s.discoverPipelines(ctx, cl, in.GetNamespace())
 	if err != nil {
-		return nil, fmt.Errorf("failed to list helm releases: %w", err)
+		return nil, err
+	}
+	if in.GetNamespace() != "" || selector != nil {
+		matching := matchingReleases(helmPipelines, in.GetNamespace(), selector)
+		helmPipelines, diagnostics = matching.filterPipelines(helmPipelines), matching.filterDiagnostics(diagnostics)
 	}
-	helmPipelines, err := helm.ParseHelmReleasePipelines(helmReleaseList.Items)
+	filtered := filterPipelines(helmPipelines, in)
+	page, nextPageToken, err := paginate(filtered, in.GetPageSize(), in.GetPageToken())
 	if err != nil {
-		return nil, fmt.Errorf("failed to discover pipelines: %w", err)
+		return nil, err
 	}
 
-	return &pipelinesv1.ListPipelinesResponse{Results: pipelinesToResponse(helmPipelines)}, nil
+	return &pipelinesv1.ListPipelinesResponse{
+		Count:         int32(len(filtered)),
+		Results:       convert.Pipelines(page),
+		Diagnostics:   convert.Diagnostics(filterDiagnostics(diagnostics, in)),
+		NextPageToken: nextPageToken,
+	}, nil
 }
 
-func pipelinesToResponse(hp []helm.HelmReleasePipeline) []*pipelinesv1.Pipeline {
-	result := []*pipelinesv1.Pipeline{}
-	for _, v := range hp {
-		result = append(result, &pipelinesv1.Pipeline{
-			Name:         v.Name,
-			Environments: envsToResponseEnvironments(v.Environments),
-		})
+func (s *pipelinesGRPCServer) GetPipeline(ctx context.Context, in *pipelinesv1.GetPipelineRequest) (*pipelinesv1.GetPipelineResponse, error) {
+	if err := s.checkNamespace(in.GetNamespace()); err != nil {
+		return nil, err
 	}
-	return result
+	cl, err := s.clientFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := s.findPipeline(ctx, cl, in.GetName(), in.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pipelinesv1.GetPipelineResponse{Pipeline: convert.Pipeline(*pipeline)}, nil
 }
 
-func envsToResponseEnvironments(envs []helm.HelmReleaseEnvironment) []*pipelinesv1.Pipeline_Environment {
-	result := []*pipelinesv1.Pipeline_Environment{}
-	for _, ev := range envs {
-		pe := &pipelinesv1.Pipeline_Environment{Name: ev.Name}
-		for _, c := range ev.Charts {
-			pe.Charts = append(pe.Charts, &pipelinesv1.Pipeline_Environment_HelmChart{
-				Name:    c.Name,
-				Version: c.Version,
-				Source:  referenceToSource(c.Source),
-			})
+func (s *pipelinesGRPCServer) ListPromotions(ctx context.Context, in *pipelinesv1.ListPromotionsRequest) (*pipelinesv1.ListPromotionsResponse, error) {
+	if err := s.checkNamespace(in.GetNamespace()); err != nil {
+		return nil, err
+	}
+	cl, err := s.clientFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := s.findPipeline(ctx, cl, in.GetPipelineName(), in.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	opts := []helm.PromotionOption{}
+	if in.GetIncludeValues() || s.includeValues {
+		opts = append(opts, helm.WithValuesPromotions())
+	}
+	promotions := convert.Promotions(helm.CalculatePromotions(*pipeline, opts...))
+
+	return &pipelinesv1.ListPromotionsResponse{Count: int32(len(promotions)), Results: promotions}, nil
+}
+
+func (s *pipelinesGRPCServer) ListUpgrades(ctx context.Context, in *pipelinesv1.ListUpgradesRequest) (*pipelinesv1.ListUpgradesResponse, error) {
+	if err := s.checkNamespace(in.GetNamespace()); err != nil {
+		return nil, err
+	}
+	cl, err := s.clientFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := s.findPipeline(ctx, cl, in.GetPipelineName(), in.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	upgrades, err := helm.IdentifyUpgrades(ctx, *pipeline, cl)
+	if err != nil {
+		return nil, statusError(fmt.Errorf("failed to identify upgrades: %w", err))
+	}
+	results := convert.Upgrades(upgrades)
+
+	return &pipelinesv1.ListUpgradesResponse{Count: int32(len(results)), Results: results}, nil
+}
+
+func (s *pipelinesGRPCServer) DiffPromotion(ctx context.Context, in *pipelinesv1.DiffPromotionRequest) (*pipelinesv1.DiffPromotionResponse, error) {
+	if err := s.checkNamespace(in.GetNamespace()); err != nil {
+		return nil, err
+	}
+	cl, err := s.clientFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := s.findPipeline(ctx, cl, in.GetPipelineName(), in.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, promotion := range helm.CalculatePromotions(*pipeline) {
+		if promotion.Environment != in.GetEnvironment() || promotion.To.Name != in.GetChartName() {
+			continue
+		}
+		diff, err := helm.DiffPromotion(ctx, cl, promotion)
+		if err != nil {
+			return nil, statusError(fmt.Errorf("failed to diff promotion: %w", err))
 		}
-		result = append(result, pe)
+
+		return &pipelinesv1.DiffPromotionResponse{
+			ManifestDiff: diff.ManifestDiff,
+			ValuesDiff:   diff.ValuesDiff,
+		}, nil
 	}
-	return result
+
+	return nil, status.Errorf(codes.NotFound, "no promotion of chart %q to environment %q in pipeline %q",
+		in.GetChartName(), in.GetEnvironment(), in.GetPipelineName())
 }
 
-func referenceToSource(r helmv2.CrossNamespaceObjectReference) *pipelinesv1.CrossNamespaceObjectReference {
-	return &pipelinesv1.CrossNamespaceObjectReference{
-		Kind:      r.Kind,
-		Namespace: r.Namespace,
-		Name:      r.Name,
+// clientFor returns the client to handle the request with.
+func (s *pipelinesGRPCServer) clientFor(ctx context.Context) (client.Client, error) {
+	if s.clientFactory == nil {
+		return s.Client, nil
 	}
+	cl, err := s.clientFactory.ClientFor(ctx)
+	if err != nil {
+		s.Error(err, "failed to create client")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return cl, nil
+}
+
+// discoverPipelines discovers every pipeline the server can see, the request
+// filters are applied to the discovered pipelines, so that pipelines that
+// span namespaces are discovered whole.
+//
+// If the request has a namespace, only the HelmReleases and sources in the
+// namespace are read, so that users with a Role in the namespace can make the
+// request with their own client.
+func (s *pipelinesGRPCServer) discoverPipelines(ctx context.Context, cl client.Client, ns string) ([]helm.HelmReleasePipeline, []helm.Diagnostic, error) {
+	var helmPipelines []helm.HelmReleasePipeline
+	var diagnostics []helm.Diagnostic
+	var err error
+	if ns != "" {
+		helmPipelines, diagnostics, err = helm.DiscoverPipelinesInNamespace(ctx, cl, s.keys, ns)
+	} else {
+		helmPipelines, diagnostics, err = helm.DiscoverNamespacedPipelines(ctx, cl, s.keys, s.namespaces)
+	}
+	if err != nil {
+		s.Error(err, "failed to discover pipelines")
+		return nil, nil, statusError(err)
+	}
+
+	return helmPipelines, diagnostics, nil
+}
+
+// checkNamespace denies requests for namespaces that are not watched, when the
+// server is restricted to namespaces.
+func (s *pipelinesGRPCServer) checkNamespace(ns string) error {
+	if len(s.namespaces) == 0 || ns == "" || slices.Contains(s.namespaces, ns) {
+		return nil
+	}
+
+	return withDetails(status.Newf(codes.PermissionDenied, "the server is restricted to the namespaces %s", strings.Join(s.namespaces, ", ")),
+		&errdetails.ResourceInfo{ResourceType: "namespaces", ResourceName: ns, Description: "namespace is not watched by the server"})
+}
+
+// matchingReleases finds the HelmReleases in the pipelines that match the
+// namespace and label selector in the request.
+//
+// The selector is matched against the labels of the HelmReleases with the
+// pipeline keys as they were resolved, so HelmReleases that are placed in a
+// pipeline by their Namespace, or by annotations, are matched.
+func matchingReleases(pipelines []helm.HelmReleasePipeline, ns string, selector labels.Selector) *releaseMatcher {
+	m := &releaseMatcher{
+		releases:  sets.New[client.ObjectKey](),
+		pipelines: sets.New[string](),
+		namespace: ns,
+		anyLabels: selector == nil,
+	}
+	for _, p := range pipelines {
+		for ref, status := range p.Releases {
+			if ns != "" && ref.Namespace != ns {
+				continue
+			}
+			if selector != nil && !selector.Matches(labels.Set(status.Labels)) {
+				continue
+			}
+			m.releases.Insert(client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name})
+			m.pipelines.Insert(p.Name)
+		}
+	}
+
+	return m
+}
+
+// releaseMatcher filters pipelines and diagnostics to those with HelmReleases
+// that match a request.
+type releaseMatcher struct {
+	releases  sets.Set[client.ObjectKey]
+	pipelines sets.Set[string]
+	// namespace and anyLabels match the diagnostics for HelmReleases that are
+	// not in a pipeline, these can only be matched by their namespace.
+	namespace string
+	anyLabels bool
+}
+
+// filterPipelines returns the pipelines with at least one matching
+// HelmRelease, every environment of the pipeline is returned.
+func (m *releaseMatcher) filterPipelines(pipelines []helm.HelmReleasePipeline) []helm.HelmReleasePipeline {
+	filtered := []helm.HelmReleasePipeline{}
+	for _, p := range pipelines {
+		if m.pipelines.Has(p.Name) {
+			filtered = append(filtered, p)
+		}
+	}
+
+	return filtered
+}
+
+// filterDiagnostics returns the diagnostics for matching HelmReleases, and for
+// the pipelines of the matching HelmReleases.
+//
+// Without a label selector, the diagnostics for HelmReleases in the namespace
+// that are not in a pipeline are also returned.
+func (m *releaseMatcher) filterDiagnostics(diagnostics []helm.Diagnostic) []helm.Diagnostic {
+	filtered := []helm.Diagnostic{}
+	for _, d := range diagnostics {
+		release := client.ObjectKey{Namespace: d.HelmRelease.Namespace, Name: d.HelmRelease.Name}
+		switch {
+		case d.HelmRelease.Name == "" && m.pipelines.Has(d.Pipeline),
+			m.releases.Has(release),
+			m.anyLabels && d.HelmRelease.Name != "" && d.HelmRelease.Namespace == m.namespace:
+			filtered = append(filtered, d)
+		}
+	}
+
+	return filtered
+}
+
+func (s *pipelinesGRPCServer) findPipeline(ctx context.Context, cl client.Client, name, ns string) (*helm.HelmReleasePipeline, error) {
+	helmPipelines, _, err := s.discoverPipelines(ctx, cl, ns)
+	if err != nil {
+		return nil, err
+	}
+	for i := range helmPipelines {
+		if helmPipelines[i].Name == name {
+			return &helmPipelines[i], nil
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound, "pipeline %q not found", name)
+}
+
+// labelSelector parses the label selector in the request, this is nil if the
+// request has no label selector.
+func labelSelector(in *pipelinesv1.ListPipelinesRequest) (labels.Selector, error) {
+	if in.GetLabelSelector() == "" {
+		return nil, nil
+	}
+	selector, err := labels.Parse(in.GetLabelSelector())
+	if err != nil {
+		return nil, invalidArgument("label_selector", fmt.Errorf("invalid label selector %q: %s", in.GetLabelSelector(), err))
+	}
+
+	return selector, nil
+}
+
+// filterPipelines returns the pipelines that match the pipeline and chart
+// names in the request.
+func filterPipelines(pipelines []helm.HelmReleasePipeline, in *pipelinesv1.ListPipelinesRequest) []helm.HelmReleasePipeline {
+	filtered := []helm.HelmReleasePipeline{}
+	for _, p := range pipelines {
+		if in.GetPipelineName() != "" && p.Name != in.GetPipelineName() {
+			continue
+		}
+		if in.GetChartName() != "" && !deploysChart(p, in.GetChartName()) {
+			continue
+		}
+		filtered = append(filtered, p)
+	}
+
+	return filtered
+}
+
+func filterDiagnostics(diagnostics []helm.Diagnostic, in *pipelinesv1.ListPipelinesRequest) []helm.Diagnostic {
+	if in.GetPipelineName() == "" {
+		return diagnostics
+	}
+	filtered := []helm.Diagnostic{}
+	for _, d := range diagnostics {
+		if d.Pipeline == in.GetPipelineName() {
+			filtered = append(filtered, d)
+		}
+	}
+
+	return filtered
+}
+
+func deploysChart(p helm.HelmReleasePipeline, name string) bool {
+	for _, env := range p.Environments {
+		for _, c := range env.Charts {
+			if c.Name == name {
+				return true
+			}
+		}
+	}
+
+	return false
 }
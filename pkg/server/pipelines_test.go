@@ -2,17 +2,31 @@ package server
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
 	"testing"
 
-	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
 	"github.com/go-logr/logr"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/known/timestamppb"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/auth"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
 	pipelinesv1 "github.com/bigkevmcd/peanut-helmpipelines/pkg/protos/pipelines/v1"
 	v1 "github.com/bigkevmcd/peanut-helmpipelines/pkg/protos/pipelines/v1"
 	"github.com/bigkevmcd/peanut-helmpipelines/test"
@@ -45,6 +59,12 @@ func TestListPipelines(t *testing.T) {
 								Namespace: "default",
 								Name:      "test-repository",
 							},
+							Releases: []*pipelinesv1.HelmReleaseStatus{
+								{
+									HelmRelease: &pipelinesv1.CrossNamespaceObjectReference{Kind: "HelmRelease", Namespace: "default", Name: "test-release"},
+									Ready:       "Unknown",
+								},
+							},
 						},
 					},
 				},
@@ -56,20 +76,631 @@ func TestListPipelines(t *testing.T) {
 		cmpopts.IgnoreUnexported(pipelinesv1.Pipeline_Environment{}),
 		cmpopts.IgnoreUnexported(pipelinesv1.Pipeline_Environment_HelmChart{}),
 		cmpopts.IgnoreUnexported(pipelinesv1.CrossNamespaceObjectReference{}),
+		cmpopts.IgnoreUnexported(pipelinesv1.HelmReleaseStatus{}),
 		cmpopts.IgnoreUnexported(pipelinesv1.Pipeline{})); diff != "" {
 		t.Fatalf("incorrect pipelines response:\n%s", diff)
 	}
 }
 
+func TestListPipelines_diagnostics(t *testing.T) {
+	staging := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", "production"), test.Named("redis", "staging"))
+	production := test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("redis", "production"))
+	other := test.NewHelmRelease(test.InPipeline("other-pipeline", "staging", ""), test.Named("other", "staging"),
+		test.ChartSource("HelmRepository", "default", "missing-repository"))
+	fc := newFakeClient(t, &staging, &production, &other, newHelmRepository("https://example.com"))
+	srv := NewPipelinesServer(logr.Discard(), fc)
+
+	resp, err := srv.ListPipelines(context.TODO(), &pipelinesv1.ListPipelinesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(resp.GetResults()); l != 1 {
+		t.Fatalf("got %d pipelines, want 1", l)
+	}
+	want := []*pipelinesv1.Diagnostic{
+		{
+			Pipeline: "demo-pipeline",
+			Reason:   "EnvironmentCycle",
+			Message:  "environments form a cycle: production -> staging -> production",
+		},
+		{
+			Pipeline:    "other-pipeline",
+			Environment: "staging",
+			HelmRelease: &pipelinesv1.CrossNamespaceObjectReference{Kind: "HelmRelease", Namespace: "staging", Name: "other"},
+			Reason:      "MissingSource",
+			Message:     "HelmRepository default/missing-repository not found",
+		},
+	}
+	if diff := cmp.Diff(want, resp.GetDiagnostics(), protocmp.Transform()); diff != "" {
+		t.Fatalf("incorrect diagnostics:\n%s", diff)
+	}
+}
+
+func TestListPipelines_filtering(t *testing.T) {
+	releases := []helmv2.HelmRelease{
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("redis", "staging")),
+		test.NewHelmRelease(test.InPipeline("other-pipeline", "staging", ""), test.Named("postgres", "other"),
+			test.ChartVersion("postgresql", "13.0.1"), test.Labelled("team", "db")),
+		test.NewHelmRelease(test.InPipeline("third-pipeline", "staging", ""), test.Named("redis", "third")),
+	}
+	objs := []runtime.Object{newHelmRepository("https://example.com")}
+	for i := range releases {
+		objs = append(objs, &releases[i])
+	}
+	srv := NewPipelinesServer(logr.Discard(), newFakeClient(t, objs...))
+
+	filterTests := []struct {
+		name     string
+		req      *pipelinesv1.ListPipelinesRequest
+		want     []string
+		wantNext bool
+	}{
+		{name: "no filters", req: &pipelinesv1.ListPipelinesRequest{}, want: []string{"demo-pipeline", "other-pipeline", "third-pipeline"}},
+		{name: "namespace", req: &pipelinesv1.ListPipelinesRequest{Namespace: "other"}, want: []string{"other-pipeline"}},
+		{name: "pipeline name", req: &pipelinesv1.ListPipelinesRequest{PipelineName: "third-pipeline"}, want: []string{"third-pipeline"}},
+		{name: "label selector", req: &pipelinesv1.ListPipelinesRequest{LabelSelector: "team=db"}, want: []string{"other-pipeline"}},
+		{name: "chart name", req: &pipelinesv1.ListPipelinesRequest{ChartName: "redis"}, want: []string{"demo-pipeline", "third-pipeline"}},
+		{name: "first page", req: &pipelinesv1.ListPipelinesRequest{PageSize: 2}, want: []string{"demo-pipeline", "other-pipeline"}, wantNext: true},
+		{name: "last page", req: &pipelinesv1.ListPipelinesRequest{PageSize: 2, PageToken: encodePageToken(2)}, want: []string{"third-pipeline"}},
+	}
+
+	for _, tt := range filterTests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := srv.ListPipelines(context.TODO(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, p := range resp.GetResults() {
+				names = append(names, p.GetName())
+			}
+			if diff := cmp.Diff(tt.want, names); diff != "" {
+				t.Fatalf("incorrect pipelines:\n%s", diff)
+			}
+			if hasNext := resp.GetNextPageToken() != ""; hasNext != tt.wantNext {
+				t.Fatalf("got next page token %q, want next page %v", resp.GetNextPageToken(), tt.wantNext)
+			}
+			if tt.req.GetPageSize() == 0 && resp.GetCount() != int32(len(tt.want)) {
+				t.Fatalf("got count %d, want %d", resp.GetCount(), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestListPipelines_filtering_pipelines_across_namespaces(t *testing.T) {
+	staging := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("redis", "staging"))
+	production := test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("redis", "production"),
+		test.Labelled("team", "db"))
+	unlabelled := test.NewHelmRelease(test.Named("unlabelled", "production"), test.Labelled("team", "db"))
+	srv := NewPipelinesServer(logr.Discard(), newFakeClient(t, &staging, &production, &unlabelled, newHelmRepository("https://example.com")))
+
+	filterTests := []struct {
+		name            string
+		req             *pipelinesv1.ListPipelinesRequest
+		wantEnvs        []string
+		wantDiagnostics []string
+	}{
+		{
+			name:            "namespace",
+			req:             &pipelinesv1.ListPipelinesRequest{Namespace: "production"},
+			wantEnvs:        []string{"production"},
+			wantDiagnostics: []string{"UnwatchedAfterEnvironment"},
+		},
+		{
+			name:            "label selector",
+			req:             &pipelinesv1.ListPipelinesRequest{LabelSelector: "team=db"},
+			wantEnvs:        []string{"staging", "production"},
+			wantDiagnostics: []string{},
+		},
+	}
+
+	for _, tt := range filterTests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := srv.ListPipelines(context.TODO(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.GetCount() != 1 {
+				t.Fatalf("got %d pipelines, want 1", resp.GetCount())
+			}
+			envs := []string{}
+			for _, env := range resp.GetResults()[0].GetEnvironments() {
+				envs = append(envs, env.GetName())
+			}
+			if diff := cmp.Diff(tt.wantEnvs, envs); diff != "" {
+				t.Fatalf("incorrect environments:\n%s", diff)
+			}
+			reasons := []string{}
+			for _, d := range resp.GetDiagnostics() {
+				reasons = append(reasons, d.GetReason())
+			}
+			if diff := cmp.Diff(tt.wantDiagnostics, reasons); diff != "" {
+				t.Fatalf("incorrect diagnostics:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListPipelines_label_selector_resolved_keys(t *testing.T) {
+	inherited := test.NewHelmRelease(test.Named("redis", "podinfo-staging"))
+	namespace := test.NewNamespace("podinfo-staging", test.InPipeline("demo-pipeline", "staging", ""))
+	annotated := test.NewHelmRelease(test.Named("redis", "annotated"),
+		test.Annotated(pipelinelabels.DefaultKeys.Pipeline, "annotated-pipeline"),
+		test.Annotated(pipelinelabels.DefaultKeys.Environment, "production"))
+	keys := pipelinelabels.DefaultKeys
+	keys.Annotations = true
+	srv := NewPipelinesServer(logr.Discard(), newFakeClient(t, &inherited, namespace, &annotated, newHelmRepository("https://example.com")),
+		WithKeys(keys))
+
+	selectorTests := []struct {
+		selector string
+		want     []string
+	}{
+		{"gitops.pro/pipeline-environment=staging", []string{"demo-pipeline"}},
+		{"gitops.pro/pipeline=annotated-pipeline", []string{"annotated-pipeline"}},
+		{"gitops.pro/pipeline", []string{"annotated-pipeline", "demo-pipeline"}},
+	}
+
+	for _, tt := range selectorTests {
+		t.Run(tt.selector, func(t *testing.T) {
+			resp, err := srv.ListPipelines(context.TODO(), &pipelinesv1.ListPipelinesRequest{LabelSelector: tt.selector})
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, p := range resp.GetResults() {
+				names = append(names, p.GetName())
+			}
+			if diff := cmp.Diff(tt.want, names); diff != "" {
+				t.Fatalf("incorrect pipelines:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListPipelines_count_with_pagination(t *testing.T) {
+	staging := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("redis", "staging"))
+	other := test.NewHelmRelease(test.InPipeline("other-pipeline", "staging", ""), test.Named("redis", "other"))
+	srv := NewPipelinesServer(logr.Discard(), newFakeClient(t, &staging, &other))
+
+	resp, err := srv.ListPipelines(context.TODO(), &pipelinesv1.ListPipelinesRequest{PageSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := resp.GetCount(); c != 2 {
+		t.Fatalf("got count %d, want 2", c)
+	}
+	if l := len(resp.GetResults()); l != 1 {
+		t.Fatalf("got %d results, want 1", l)
+	}
+}
+
+func TestListPipelines_invalid_label_selector(t *testing.T) {
+	srv := NewPipelinesServer(logr.Discard(), newFakeClient(t))
+
+	_, err := srv.ListPipelines(context.TODO(), &pipelinesv1.ListPipelinesRequest{LabelSelector: "team in (db"})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Fatalf("got error code %v, want %v", code, codes.InvalidArgument)
+	}
+}
+
+func TestListPipelines_forbidden(t *testing.T) {
+	srv := NewPipelinesServer(logr.Discard(), forbiddenClient{Client: newFakeClient(t)})
+
+	_, err := srv.ListPipelines(context.TODO(), &pipelinesv1.ListPipelinesRequest{})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Fatalf("got error code %v, want %v", code, codes.PermissionDenied)
+	}
+}
+
+func TestListPipelines_client_factory(t *testing.T) {
+	hr := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""))
+	factory := &stubClientFactory{Client: newFakeClient(t, &hr)}
+	srv := NewPipelinesServer(logr.Discard(), newFakeClient(t), WithClientFactory(factory))
+	ctx := auth.WithUser(context.TODO(), &auth.User{Name: "testing"})
+
+	resp, err := srv.ListPipelines(ctx, &pipelinesv1.ListPipelinesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.GetCount() != 1 {
+		t.Fatalf("got %d pipelines, want 1", resp.GetCount())
+	}
+	if diff := cmp.Diff([]string{"testing"}, factory.users); diff != "" {
+		t.Fatalf("incorrect users:\n%s", diff)
+	}
+}
+
+func TestClientFactory_namespaced_user(t *testing.T) {
+	staging := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("redis", "staging"))
+	production := test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("redis", "production"))
+	cl := namespacedClient{Client: newFakeClient(t, &staging, &production), namespaces: []string{"staging"}}
+	srv := NewPipelinesServer(logr.Discard(), newFakeClient(t), WithClientFactory(&stubClientFactory{Client: cl}))
+	ctx := auth.WithUser(context.TODO(), &auth.User{Name: "testing"})
+
+	requestTests := []struct {
+		name string
+		call func(ns string) error
+	}{
+		{
+			name: "ListPipelines",
+			call: func(ns string) error {
+				_, err := srv.ListPipelines(ctx, &pipelinesv1.ListPipelinesRequest{Namespace: ns})
+				return err
+			},
+		},
+		{
+			name: "GetPipeline",
+			call: func(ns string) error {
+				_, err := srv.GetPipeline(ctx, &pipelinesv1.GetPipelineRequest{Name: "demo-pipeline", Namespace: ns})
+				return err
+			},
+		},
+		{
+			name: "ListPromotions",
+			call: func(ns string) error {
+				_, err := srv.ListPromotions(ctx, &pipelinesv1.ListPromotionsRequest{PipelineName: "demo-pipeline", Namespace: ns})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range requestTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.call("")); code != codes.PermissionDenied {
+				t.Fatalf("got error code %v across namespaces, want %v", code, codes.PermissionDenied)
+			}
+			if err := tt.call("staging"); err != nil {
+				t.Fatalf("failed in the allowed namespace: %s", err)
+			}
+		})
+	}
+}
+
+func TestListPipelines_client_factory_error(t *testing.T) {
+	srv := NewPipelinesServer(logr.Discard(), newFakeClient(t), WithClientFactory(failingClientFactory{}))
+
+	_, err := srv.ListPipelines(context.TODO(), &pipelinesv1.ListPipelinesRequest{})
+	if code := status.Code(err); code != codes.Internal {
+		t.Fatalf("got error code %v, want %v", code, codes.Internal)
+	}
+}
+
+func TestListPipelines_keys(t *testing.T) {
+	keys := pipelinelabels.Keys{
+		Pipeline:    "app.example.com/pipeline",
+		Environment: "app.example.com/stage",
+		After:       "app.example.com/after",
+		Application: "app.example.com/name",
+	}
+	hr := test.NewHelmRelease(test.Labelled(keys.Pipeline, "demo-pipeline"), test.Labelled(keys.Environment, "staging"))
+	other := test.NewHelmRelease(test.Named("other-release", "default"), test.InPipeline("other-pipeline", "staging", ""))
+	srv := NewPipelinesServer(logr.Discard(), newFakeClient(t, &hr, &other), WithKeys(keys))
+
+	resp, err := srv.ListPipelines(context.TODO(), &pipelinesv1.ListPipelinesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.GetCount() != 1 || resp.GetResults()[0].GetName() != "demo-pipeline" {
+		t.Fatalf("got pipelines %v, want demo-pipeline", resp.GetResults())
+	}
+}
+
+func TestListPipelines_namespaces(t *testing.T) {
+	staging := test.NewHelmRelease(test.Named("staging-deploy", "staging"), test.InPipeline("demo-pipeline", "staging", ""))
+	other := test.NewHelmRelease(test.Named("other-deploy", "other"), test.InPipeline("other-pipeline", "staging", ""))
+	srv := NewPipelinesServer(logr.Discard(), newFakeClient(t, &staging, &other), WithNamespaces("staging", "production"))
+
+	resp, err := srv.ListPipelines(context.TODO(), &pipelinesv1.ListPipelinesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetCount() != 1 || resp.GetResults()[0].GetName() != "demo-pipeline" {
+		t.Fatalf("got pipelines %v, want demo-pipeline", resp.GetResults())
+	}
+
+	_, err = srv.ListPipelines(context.TODO(), &pipelinesv1.ListPipelinesRequest{Namespace: "other"})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Fatalf("got error code %v, want %v", code, codes.PermissionDenied)
+	}
+}
+
+func TestGetPipeline(t *testing.T) {
+	hr := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""))
+	srv := NewPipelinesServer(logr.Discard(), newFakeClient(t, &hr))
+
+	resp, err := srv.GetPipeline(context.TODO(), &pipelinesv1.GetPipelineRequest{Name: "demo-pipeline"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &pipelinesv1.Pipeline{
+		Name: "demo-pipeline",
+		Environments: []*pipelinesv1.Pipeline_Environment{
+			{
+				Name: "staging",
+				Charts: []*pipelinesv1.Pipeline_Environment_HelmChart{
+					{
+						Name:    "redis",
+						Version: "1.0.9",
+						Source:  &pipelinesv1.CrossNamespaceObjectReference{Kind: "HelmRepository", Namespace: "default", Name: "test-repository"},
+						Releases: []*pipelinesv1.HelmReleaseStatus{
+							{
+								HelmRelease: &pipelinesv1.CrossNamespaceObjectReference{Kind: "HelmRelease", Namespace: "default", Name: "test-release"},
+								Ready:       "Unknown",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, resp.GetPipeline(), protocmp.Transform()); diff != "" {
+		t.Fatalf("incorrect pipeline:\n%s", diff)
+	}
+}
+
+func TestGetPipeline_unknown_pipeline(t *testing.T) {
+	srv := NewPipelinesServer(logr.Discard(), newFakeClient(t))
+
+	_, err := srv.GetPipeline(context.TODO(), &pipelinesv1.GetPipelineRequest{Name: "unknown-pipeline"})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Fatalf("got error code %v, want %v", code, codes.NotFound)
+	}
+}
+
+func TestListPromotions(t *testing.T) {
+	staging := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""),
+		test.Named("redis", "staging"), test.ChartVersion("redis", "1.0.12"))
+	production := test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"),
+		test.Named("redis", "production"), test.ChartVersion("redis", "1.0.9"))
+	fc := newFakeClient(t, &staging, &production)
+	srv := NewPipelinesServer(logr.Discard(), fc)
+
+	resp, err := srv.ListPromotions(context.TODO(), &pipelinesv1.ListPromotionsRequest{PipelineName: "demo-pipeline"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &pipelinesv1.ListPromotionsResponse{
+		Count: 1,
+		Results: []*pipelinesv1.Promotion{
+			{
+				Environment: "production",
+				From: &pipelinesv1.Pipeline_Environment_HelmChart{
+					Name: "redis", Version: "1.0.9",
+					Source: &pipelinesv1.CrossNamespaceObjectReference{Kind: "HelmRepository", Namespace: "default", Name: "test-repository"},
+				},
+				To: &pipelinesv1.Pipeline_Environment_HelmChart{
+					Name: "redis", Version: "1.0.12",
+					Source: &pipelinesv1.CrossNamespaceObjectReference{Kind: "HelmRepository", Namespace: "default", Name: "test-repository"},
+				},
+				PromotedReleases: []*pipelinesv1.CrossNamespaceObjectReference{
+					{Kind: "HelmRelease", Namespace: "production", Name: "redis"},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, resp, protocmp.Transform()); diff != "" {
+		t.Fatalf("incorrect promotions response:\n%s", diff)
+	}
+}
+
+func TestListPromotions_values_promotions(t *testing.T) {
+	staging := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""),
+		test.Named("redis", "staging"), test.Values(`{"replicas":3}`))
+	production := test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"),
+		test.Named("redis", "production"), test.Values(`{"replicas":1}`))
+	fc := newFakeClient(t, &staging, &production)
+
+	resp, err := NewPipelinesServer(logr.Discard(), fc).ListPromotions(context.TODO(),
+		&pipelinesv1.ListPromotionsRequest{PipelineName: "demo-pipeline"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetCount() != 0 {
+		t.Fatalf("got %d promotions, want 0", resp.GetCount())
+	}
+
+	// The server option includes values even when the request doesn't.
+	resp, err = NewPipelinesServer(logr.Discard(), fc, WithValuesPromotions()).ListPromotions(context.TODO(),
+		&pipelinesv1.ListPromotionsRequest{PipelineName: "demo-pipeline", IncludeValues: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetCount() != 1 {
+		t.Fatalf("got %d promotions, want 1", resp.GetCount())
+	}
+}
+
+func TestListPromotions_unknown_pipeline(t *testing.T) {
+	srv := NewPipelinesServer(logr.Discard(), newFakeClient(t))
+
+	_, err := srv.ListPromotions(context.TODO(), &pipelinesv1.ListPromotionsRequest{PipelineName: "unknown"})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Fatalf("got error code %v, want %v", code, codes.NotFound)
+	}
+}
+
+func TestListUpgrades(t *testing.T) {
+	testServer := httptest.NewServer(http.FileServer(http.Dir("../helm/testdata/diff-charts")))
+	defer testServer.Close()
+
+	hr := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""),
+		test.ChartVersion("test-service", "1.0.1"))
+	fc := newFakeClient(t, &hr, newHelmRepository(testServer.URL))
+	srv := NewPipelinesServer(logr.Discard(), fc)
+
+	resp, err := srv.ListUpgrades(context.TODO(), &pipelinesv1.ListUpgradesRequest{PipelineName: "demo-pipeline"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := &pipelinesv1.CrossNamespaceObjectReference{Kind: "HelmRepository", Namespace: "default", Name: "test-repository"}
+	want := &pipelinesv1.ListUpgradesResponse{
+		Count: 1,
+		Results: []*pipelinesv1.ChartUpgrade{
+			{
+				Current:   &pipelinesv1.Pipeline_Environment_HelmChart{Name: "test-service", Version: "1.0.1", Source: source},
+				Available: &pipelinesv1.Pipeline_Environment_HelmChart{Name: "test-service", Version: "1.1.2", Source: source},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, resp, protocmp.Transform()); diff != "" {
+		t.Fatalf("incorrect upgrades response:\n%s", diff)
+	}
+}
+
+func TestDiffPromotion(t *testing.T) {
+	testServer := httptest.NewServer(http.FileServer(http.Dir("../helm/testdata/diff-charts")))
+	defer testServer.Close()
+
+	staging := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""),
+		test.Named("test-service", "staging"), test.ChartVersion("test-service", "1.1.2"))
+	production := test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"),
+		test.Named("test-service", "production"), test.ChartVersion("test-service", "1.0.1"))
+	fc := newFakeClient(t, &staging, &production, newHelmRepository(testServer.URL))
+	srv := NewPipelinesServer(logr.Discard(), fc)
+	in := &pipelinesv1.DiffPromotionRequest{
+		PipelineName: "demo-pipeline",
+		Environment:  "production",
+		ChartName:    "test-service",
+	}
+
+	resp, err := srv.DiffPromotion(context.TODO(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `--- test-service-1.0.1
++++ test-service-1.1.2
+@@ -1,4 +1,5 @@
+ image:
+   repository: example/test-service
+-  tag: 1.0.1
++  tag: 1.1.2
++logLevel: info
+ replicaCount: 1
+`
+	if diff := cmp.Diff(want, resp.GetValuesDiff()); diff != "" {
+		t.Fatalf("incorrect values diff:\n%s", diff)
+	}
+	if resp.GetManifestDiff() == "" {
+		t.Fatal("expected a manifest diff")
+	}
+}
+
+func TestDiffPromotion_unknown_promotion(t *testing.T) {
+	hr := test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""))
+	fc := newFakeClient(t, &hr)
+	srv := NewPipelinesServer(logr.Discard(), fc)
+	in := &pipelinesv1.DiffPromotionRequest{
+		PipelineName: "demo-pipeline",
+		Environment:  "production",
+		ChartName:    "redis",
+	}
+
+	_, err := srv.DiffPromotion(context.TODO(), in)
+	if code := status.Code(err); code != codes.NotFound {
+		t.Fatalf("got error code %v, want %v", code, codes.NotFound)
+	}
+}
+
 func newFakeClient(t *testing.T, objs ...runtime.Object) client.Client {
 	t.Helper()
 	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
 	if err := helmv2.AddToScheme(scheme); err != nil {
 		t.Fatal(err)
 	}
+	if err := sourcev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
 
 	return fake.NewClientBuilder().
 		WithScheme(scheme).
 		WithRuntimeObjects(objs...).
 		Build()
 }
+
+// stubClientFactory records the users that clients are created for.
+type stubClientFactory struct {
+	client.Client
+	users []string
+}
+
+func (f *stubClientFactory) ClientFor(ctx context.Context) (client.Client, error) {
+	f.users = append(f.users, auth.UserFromContext(ctx).Name)
+	return f.Client, nil
+}
+
+// failingClientFactory fails to create clients.
+type failingClientFactory struct{}
+
+func (failingClientFactory) ClientFor(ctx context.Context) (client.Client, error) {
+	return nil, errors.New("failed to create client")
+}
+
+// forbiddenClient fails to list resources as if RBAC denied access.
+type forbiddenClient struct {
+	client.Client
+}
+
+func (c forbiddenClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return apierrors.NewForbidden(schema.GroupResource{Resource: "namespaces"}, "", errors.New("RBAC denied"))
+}
+
+// namespacedClient only allows listing and getting resources in the
+// namespaces, as if the user had a Role in each namespace.
+type namespacedClient struct {
+	client.Client
+	namespaces []string
+}
+
+func (c namespacedClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.check(key.Namespace); err != nil {
+		return err
+	}
+
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c namespacedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if err := c.check(listOpts.Namespace); err != nil {
+		return err
+	}
+
+	return c.Client.List(ctx, list, opts...)
+}
+
+func (c namespacedClient) check(ns string) error {
+	if slices.Contains(c.namespaces, ns) {
+		return nil
+	}
+
+	return apierrors.NewForbidden(schema.GroupResource{Group: "helm.toolkit.fluxcd.io", Resource: "helmreleases"}, "", errors.New("RBAC denied"))
+}
+
+func newHelmRepository(serverURL string) *sourcev1.HelmRepository {
+	return &sourcev1.HelmRepository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-repository",
+			Namespace: "default",
+		},
+		Spec: sourcev1.HelmRepositorySpec{
+			URL: serverURL,
+		},
+		Status: sourcev1.HelmRepositoryStatus{
+			URL: serverURL + "/index.yaml",
+		},
+	}
+}
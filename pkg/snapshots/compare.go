@@ -0,0 +1,211 @@
+package snapshots
+
+import (
+	"strings"
+
+	"github.com/gitops-tools/pkg/sets"
+)
+
+// ChangeType is the kind of change between two snapshots.
+type ChangeType string
+
+const (
+	// Added is a pipeline, environment or chart that is only in the newer
+	// snapshot.
+	Added ChangeType = "Added"
+	// Removed is a pipeline, environment or chart that is only in the older
+	// snapshot.
+	Removed ChangeType = "Removed"
+	// Invalid is a pipeline that is only in the older snapshot because it
+	// failed validation in the newer snapshot, the To is the reasons.
+	Invalid ChangeType = "Invalid"
+	// VersionChanged is a chart that is deployed at a different version.
+	VersionChanged ChangeType = "VersionChanged"
+	// ValuesChanged is a chart that is deployed with different values.
+	ValuesChanged ChangeType = "ValuesChanged"
+	// ReleasesChanged is a chart that is deployed by different HelmReleases.
+	ReleasesChanged ChangeType = "ReleasesChanged"
+)
+
+// Change is a difference between two snapshots.
+//
+// The Environment is empty for changes to a pipeline, and the Chart is empty
+// for changes to an environment.
+type Change struct {
+	Type        ChangeType `json:"type"`
+	Pipeline    string     `json:"pipeline"`
+	Environment string     `json:"environment,omitempty"`
+	Chart       string     `json:"chart,omitempty"`
+	From        string     `json:"from,omitempty"`
+	To          string     `json:"to,omitempty"`
+}
+
+// Compare returns the changes from the older to the newer snapshot.
+//
+// Charts are matched across the snapshots by their application, or by their
+// name and source if they have no application.
+func Compare(older, newer *Snapshot) []Change {
+	changes := []Change{}
+	olderPipelines, newerPipelines := pipelinesByName(older), pipelinesByName(newer)
+	invalid := invalidPipelines(newer)
+	for _, name := range sortedKeys(olderPipelines, newerPipelines) {
+		from, inOlder := olderPipelines[name]
+		to, inNewer := newerPipelines[name]
+		reasons, isInvalid := invalid[name]
+		switch {
+		case !inNewer && isInvalid:
+			changes = append(changes, Change{Type: Invalid, Pipeline: name, To: joinSorted(reasons)})
+		case !inNewer:
+			changes = append(changes, Change{Type: Removed, Pipeline: name})
+		case !inOlder:
+			changes = append(changes, Change{Type: Added, Pipeline: name})
+		default:
+			changes = append(changes, compareEnvironments(name, from, to)...)
+		}
+	}
+
+	return changes
+}
+
+func compareEnvironments(pipeline string, older, newer Pipeline) []Change {
+	changes := []Change{}
+	olderEnvs, newerEnvs := environmentsByName(older), environmentsByName(newer)
+	for _, name := range sortedKeys(olderEnvs, newerEnvs) {
+		from, inOlder := olderEnvs[name]
+		to, inNewer := newerEnvs[name]
+		switch {
+		case !inNewer:
+			changes = append(changes, Change{Type: Removed, Pipeline: pipeline, Environment: name})
+		case !inOlder:
+			changes = append(changes, Change{Type: Added, Pipeline: pipeline, Environment: name})
+		default:
+			changes = append(changes, compareCharts(pipeline, name, from, to)...)
+		}
+	}
+
+	return changes
+}
+
+func compareCharts(pipeline, environment string, older, newer Environment) []Change {
+	changes := []Change{}
+	olderCharts, newerCharts := chartsByKey(older), chartsByKey(newer)
+	for _, key := range sortedKeys(olderCharts, newerCharts) {
+		from, inOlder := olderCharts[key]
+		to, inNewer := newerCharts[key]
+		change := Change{Pipeline: pipeline, Environment: environment, Chart: key}
+		switch {
+		case !inNewer:
+			change.Type, change.From = Removed, from.versions
+			changes = append(changes, change)
+		case !inOlder:
+			change.Type, change.To = Added, to.versions
+			changes = append(changes, change)
+		default:
+			if from.versions != to.versions {
+				change.Type, change.From, change.To = VersionChanged, from.versions, to.versions
+				changes = append(changes, change)
+			}
+			if from.valuesDigests != to.valuesDigests {
+				change.Type, change.From, change.To = ValuesChanged, from.valuesDigests, to.valuesDigests
+				changes = append(changes, change)
+			}
+			if from.releases != to.releases {
+				change.Type, change.From, change.To = ReleasesChanged, from.releases, to.releases
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	return changes
+}
+
+// deployedChart is the versions, values and HelmReleases of a chart in an
+// environment, more than one version of a chart can be deployed in an
+// environment.
+type deployedChart struct {
+	versions      string
+	valuesDigests string
+	releases      string
+}
+
+func chartsByKey(env Environment) map[string]deployedChart {
+	versions := map[string]sets.Set[string]{}
+	digests := map[string]sets.Set[string]{}
+	releases := map[string]sets.Set[string]{}
+	for _, c := range env.Charts {
+		key := c.key()
+		if versions[key] == nil {
+			versions[key], digests[key], releases[key] = sets.New[string](), sets.New[string](), sets.New[string]()
+		}
+		versions[key].Insert(c.Version)
+		for _, r := range c.Releases {
+			if r.ValuesDigest != "" {
+				digests[key].Insert(r.ValuesDigest)
+			}
+			releases[key].Insert(r.Namespace + "/" + r.Name)
+		}
+	}
+
+	charts := map[string]deployedChart{}
+	for key := range versions {
+		charts[key] = deployedChart{
+			versions:      joinSorted(versions[key]),
+			valuesDigests: joinSorted(digests[key]),
+			releases:      joinSorted(releases[key]),
+		}
+	}
+
+	return charts
+}
+
+func pipelinesByName(s *Snapshot) map[string]Pipeline {
+	result := map[string]Pipeline{}
+	for _, p := range s.Pipelines {
+		result[p.Name] = p
+	}
+
+	return result
+}
+
+// invalidPipelines returns the reasons for the diagnostics of the pipelines
+// that are only in the diagnostics of the snapshot.
+func invalidPipelines(s *Snapshot) map[string]sets.Set[string] {
+	pipelines := pipelinesByName(s)
+	result := map[string]sets.Set[string]{}
+	for _, d := range s.Diagnostics {
+		if _, ok := pipelines[d.Pipeline]; ok {
+			continue
+		}
+		if result[d.Pipeline] == nil {
+			result[d.Pipeline] = sets.New[string]()
+		}
+		result[d.Pipeline].Insert(d.Reason)
+	}
+
+	return result
+}
+
+func environmentsByName(p Pipeline) map[string]Environment {
+	result := map[string]Environment{}
+	for _, env := range p.Environments {
+		result[env.Name] = env
+	}
+
+	return result
+}
+
+func joinSorted(s sets.Set[string]) string {
+	return strings.Join(s.SortedList(func(x, y string) bool { return x < y }), ", ")
+}
+
+// sortedKeys returns the keys that are in either map, in order.
+func sortedKeys[T any](a, b map[string]T) []string {
+	keys := sets.New[string]()
+	for k := range a {
+		keys.Insert(k)
+	}
+	for k := range b {
+		keys.Insert(k)
+	}
+	return keys.SortedList(func(x, y string) bool { return x < y })
+}
@@ -0,0 +1,120 @@
+package snapshots
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompare(t *testing.T) {
+	source := Reference{Kind: "HelmRepository", Namespace: "default", Name: "test-repository"}
+	redis := func(version, digest string, releases ...string) Chart {
+		c := Chart{Name: "redis", Version: version, Source: source}
+		for _, r := range releases {
+			c.Releases = append(c.Releases, Release{Reference: Reference{Kind: "HelmRelease", Namespace: "default", Name: r}, ValuesDigest: digest})
+		}
+		return c
+	}
+	snapshot := func(pipelines ...Pipeline) *Snapshot {
+		return &Snapshot{Version: Version, Pipelines: pipelines}
+	}
+	pipeline := func(name string, envs ...Environment) Pipeline {
+		return Pipeline{Name: name, Environments: envs}
+	}
+
+	compareTests := []struct {
+		name  string
+		older *Snapshot
+		newer *Snapshot
+		want  []Change
+	}{
+		{
+			"no changes",
+			snapshot(pipeline("demo-pipeline", Environment{Name: "staging", Charts: []Chart{redis("1.0.9", "", "redis")}})),
+			snapshot(pipeline("demo-pipeline", Environment{Name: "staging", Charts: []Chart{redis("1.0.9", "", "redis")}})),
+			[]Change{},
+		},
+		{
+			"pipelines added and removed",
+			snapshot(pipeline("old-pipeline")),
+			snapshot(pipeline("new-pipeline")),
+			[]Change{
+				{Type: Added, Pipeline: "new-pipeline"},
+				{Type: Removed, Pipeline: "old-pipeline"},
+			},
+		},
+		{
+			"pipeline failed validation",
+			snapshot(pipeline("demo-pipeline"), pipeline("other-pipeline")),
+			&Snapshot{
+				Version: Version,
+				Diagnostics: []Diagnostic{
+					{Pipeline: "demo-pipeline", Reason: "EnvironmentCycle", Message: "environments form a cycle: production -> staging -> production"},
+					{Pipeline: "demo-pipeline", Reason: "DuplicateEnvironment", Message: "environment staging is both first and after production"},
+				},
+			},
+			[]Change{
+				{Type: Invalid, Pipeline: "demo-pipeline", To: "DuplicateEnvironment, EnvironmentCycle"},
+				{Type: Removed, Pipeline: "other-pipeline"},
+			},
+		},
+		{
+			"diagnostics for a valid pipeline",
+			snapshot(pipeline("demo-pipeline")),
+			&Snapshot{
+				Version:   Version,
+				Pipelines: []Pipeline{pipeline("demo-pipeline")},
+				Diagnostics: []Diagnostic{
+					{Pipeline: "demo-pipeline", Reason: "MissingSource", Message: "HelmRepository default/test-repository not found"},
+				},
+			},
+			[]Change{},
+		},
+		{
+			"environments added and removed",
+			snapshot(pipeline("demo-pipeline", Environment{Name: "qa"})),
+			snapshot(pipeline("demo-pipeline", Environment{Name: "staging"})),
+			[]Change{
+				{Type: Removed, Pipeline: "demo-pipeline", Environment: "qa"},
+				{Type: Added, Pipeline: "demo-pipeline", Environment: "staging"},
+			},
+		},
+		{
+			"charts added and removed",
+			snapshot(pipeline("demo-pipeline", Environment{Name: "staging", Charts: []Chart{redis("1.0.9", "")}})),
+			snapshot(pipeline("demo-pipeline", Environment{Name: "staging", Charts: []Chart{{Application: "cache", Name: "redis", Version: "1.0.12", Source: source}}})),
+			[]Change{
+				{Type: Added, Pipeline: "demo-pipeline", Environment: "staging", Chart: "cache", To: "1.0.12"},
+				{Type: Removed, Pipeline: "demo-pipeline", Environment: "staging", Chart: "redis (HelmRepository/default/test-repository)", From: "1.0.9"},
+			},
+		},
+		{
+			"chart changes",
+			snapshot(pipeline("demo-pipeline", Environment{Name: "staging", Charts: []Chart{redis("1.0.9", "abc123", "redis")}})),
+			snapshot(pipeline("demo-pipeline", Environment{Name: "staging", Charts: []Chart{redis("1.0.12", "def456", "redis", "cache")}})),
+			[]Change{
+				{Type: VersionChanged, Pipeline: "demo-pipeline", Environment: "staging", Chart: "redis (HelmRepository/default/test-repository)", From: "1.0.9", To: "1.0.12"},
+				{Type: ValuesChanged, Pipeline: "demo-pipeline", Environment: "staging", Chart: "redis (HelmRepository/default/test-repository)", From: "abc123", To: "def456"},
+				{Type: ReleasesChanged, Pipeline: "demo-pipeline", Environment: "staging", Chart: "redis (HelmRepository/default/test-repository)", From: "default/redis", To: "default/cache, default/redis"},
+			},
+		},
+		{
+			"more than one version in an environment",
+			snapshot(pipeline("demo-pipeline", Environment{Name: "staging", Charts: []Chart{redis("1.0.9", "")}})),
+			snapshot(pipeline("demo-pipeline", Environment{Name: "staging", Charts: []Chart{redis("1.0.12", ""), redis("1.0.9", "")}})),
+			[]Change{
+				{Type: VersionChanged, Pipeline: "demo-pipeline", Environment: "staging", Chart: "redis (HelmRepository/default/test-repository)", From: "1.0.9", To: "1.0.12, 1.0.9"},
+			},
+		},
+	}
+
+	for _, tt := range compareTests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := Compare(tt.older, tt.newer)
+
+			if diff := cmp.Diff(tt.want, changes); diff != "" {
+				t.Fatalf("failed to compare snapshots:\n%s", diff)
+			}
+		})
+	}
+}
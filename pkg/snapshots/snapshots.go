@@ -0,0 +1,176 @@
+package snapshots
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+)
+
+// Version is the version of the snapshot format written by Write.
+const Version = "helm-pipelines.snapshot/v1"
+
+// Snapshot is a point-in-time record of the pipelines in a cluster.
+//
+// The Diagnostics record the problems found when discovering the pipelines,
+// pipelines that failed validation are only in the Diagnostics.
+type Snapshot struct {
+	Version     string       `json:"version"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	Pipelines   []Pipeline   `json:"pipelines"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// Pipeline is the record of a pipeline in a Snapshot.
+type Pipeline struct {
+	Name         string        `json:"name"`
+	Environments []Environment `json:"environments"`
+}
+
+// Environment is the record of the charts in an environment of a pipeline.
+type Environment struct {
+	Name   string   `json:"name"`
+	After  []string `json:"after,omitempty"`
+	Charts []Chart  `json:"charts"`
+}
+
+// Chart is the record of a chart version, and the HelmReleases that deploy
+// it.
+type Chart struct {
+	Application string    `json:"application,omitempty"`
+	Name        string    `json:"name"`
+	Version     string    `json:"version"`
+	Source      Reference `json:"source"`
+	Releases    []Release `json:"releases,omitempty"`
+}
+
+// key identifies the chart across snapshots, by the application, or by the
+// name and source of charts without an application.
+func (c Chart) key() string {
+	if c.Application != "" {
+		return c.Application
+	}
+
+	return c.Name + " (" + c.Source.String() + ")"
+}
+
+// Release is the record of a HelmRelease that deploys a chart.
+type Release struct {
+	Reference
+	Ready               string `json:"ready,omitempty"`
+	LastAppliedRevision string `json:"lastAppliedRevision,omitempty"`
+	ValuesDigest        string `json:"valuesDigest,omitempty"`
+}
+
+// Diagnostic is the record of a problem with the configuration of a pipeline.
+//
+// The HelmRelease is nil for problems with the pipeline as a whole.
+type Diagnostic struct {
+	Pipeline    string     `json:"pipeline"`
+	Environment string     `json:"environment,omitempty"`
+	HelmRelease *Reference `json:"helmRelease,omitempty"`
+	Reason      string     `json:"reason"`
+	Message     string     `json:"message"`
+}
+
+// Reference identifies a resource in the cluster.
+type Reference struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// String returns the reference as Kind/namespace/name, or Kind/name if there
+// is no namespace.
+func (r Reference) String() string {
+	if r.Namespace == "" {
+		return r.Kind + "/" + r.Name
+	}
+
+	return r.Kind + "/" + r.Namespace + "/" + r.Name
+}
+
+// New creates a Snapshot of the pipelines, and the diagnostics from
+// discovering them.
+func New(pipelines []helm.HelmReleasePipeline, diagnostics []helm.Diagnostic, createdAt time.Time) *Snapshot {
+	s := &Snapshot{Version: Version, CreatedAt: createdAt.UTC(), Pipelines: []Pipeline{}}
+	for _, p := range pipelines {
+		sp := Pipeline{Name: p.Name, Environments: []Environment{}}
+		for _, env := range p.Environments {
+			se := Environment{Name: env.Name, After: env.After, Charts: []Chart{}}
+			for _, c := range env.Charts {
+				se.Charts = append(se.Charts, Chart{
+					Application: c.Application,
+					Name:        c.Name,
+					Version:     c.Version,
+					Source:      Reference{Kind: c.Source.Kind, Namespace: c.Source.Namespace, Name: c.Source.Name},
+					Releases:    releases(p, env.Name, c),
+				})
+			}
+			sort.Slice(se.Charts, func(i, j int) bool {
+				if ki, kj := se.Charts[i].key(), se.Charts[j].key(); ki != kj {
+					return ki < kj
+				}
+				return se.Charts[i].Version < se.Charts[j].Version
+			})
+			sp.Environments = append(sp.Environments, se)
+		}
+		s.Pipelines = append(s.Pipelines, sp)
+	}
+	for _, d := range diagnostics {
+		sd := Diagnostic{Pipeline: d.Pipeline, Environment: d.Environment, Reason: string(d.Reason), Message: d.Message}
+		if d.HelmRelease.Name != "" {
+			sd.HelmRelease = &Reference{Kind: d.HelmRelease.Kind, Namespace: d.HelmRelease.Namespace, Name: d.HelmRelease.Name}
+		}
+		s.Diagnostics = append(s.Diagnostics, sd)
+	}
+
+	return s
+}
+
+// Write writes the Snapshot as indented JSON.
+func Write(w io.Writer, s *Snapshot) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Read reads a Snapshot written by Write, snapshots with a different version
+// are rejected.
+func Read(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if s.Version != Version {
+		return nil, fmt.Errorf("unsupported snapshot version %q, want %q", s.Version, Version)
+	}
+
+	return &s, nil
+}
+
+// releases returns the HelmReleases that deploy the chart in the environment.
+func releases(p helm.HelmReleasePipeline, env string, c helm.HelmReleaseChart) []Release {
+	var result []Release
+	for _, r := range p.ChartHelmReleases[c] {
+		status, ok := p.Releases[r]
+		if !ok || status.Environment != env {
+			continue
+		}
+		result = append(result, Release{
+			Reference:           Reference{Kind: r.Kind, Namespace: r.Namespace, Name: r.Name},
+			Ready:               string(status.Ready),
+			LastAppliedRevision: status.LastAppliedRevision,
+			ValuesDigest:        status.ValuesDigest,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,141 @@
+package snapshots
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/helm"
+	"github.com/bigkevmcd/peanut-helmpipelines/pkg/pipelinelabels"
+	"github.com/bigkevmcd/peanut-helmpipelines/test"
+)
+
+func TestNew(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC)
+	pipelines, diagnostics := parsePipelines(t,
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging"),
+			test.ChartVersion("redis", "1.0.12"), test.Deployed("redis", "1.0.12")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "production", "staging"), test.Named("production-deploy", "production"),
+			test.ChartVersion("redis", "1.0.9")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "", ""), test.Named("unplaced-deploy", "staging")),
+		test.NewHelmRelease(test.InPipeline("broken-pipeline", "staging", "production"), test.Named("staging-deploy", "broken")),
+		test.NewHelmRelease(test.InPipeline("broken-pipeline", "production", "staging"), test.Named("production-deploy", "broken")),
+	)
+
+	snapshot := New(pipelines, diagnostics, now)
+
+	source := Reference{Kind: "HelmRepository", Namespace: "default", Name: "test-repository"}
+	want := &Snapshot{
+		Version:   Version,
+		CreatedAt: now,
+		Pipelines: []Pipeline{
+			{
+				Name: "demo-pipeline",
+				Environments: []Environment{
+					{
+						Name: "staging",
+						Charts: []Chart{
+							{
+								Name: "redis", Version: "1.0.12", Source: source,
+								Releases: []Release{
+									{
+										Reference:           Reference{Kind: "HelmRelease", Namespace: "staging", Name: "staging-deploy"},
+										Ready:               "Unknown",
+										LastAppliedRevision: "1.0.12",
+									},
+								},
+							},
+						},
+					},
+					{
+						Name:  "production",
+						After: []string{"staging"},
+						Charts: []Chart{
+							{
+								Name: "redis", Version: "1.0.9", Source: source,
+								Releases: []Release{
+									{
+										Reference: Reference{Kind: "HelmRelease", Namespace: "production", Name: "production-deploy"},
+										Ready:     "Unknown",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Diagnostics: []Diagnostic{
+			{
+				Pipeline:    "demo-pipeline",
+				HelmRelease: &Reference{Kind: "HelmRelease", Namespace: "staging", Name: "unplaced-deploy"},
+				Reason:      "MissingEnvironment",
+				Message:     "HelmRelease staging/unplaced-deploy has no gitops.pro/pipeline-environment label",
+			},
+			{
+				Pipeline: "broken-pipeline",
+				Reason:   "EnvironmentCycle",
+				Message:  "environments form a cycle: production -> staging -> production",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, snapshot); diff != "" {
+		t.Fatalf("failed to create snapshot:\n%s", diff)
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	pipelines, diagnostics := parsePipelines(t,
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "staging", ""), test.Named("staging-deploy", "staging")),
+		test.NewHelmRelease(test.InPipeline("demo-pipeline", "", ""), test.Named("unplaced-deploy", "staging")),
+	)
+	snapshot := New(pipelines, diagnostics, time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC))
+
+	var b bytes.Buffer
+	if err := Write(&b, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(snapshot, read); diff != "" {
+		t.Fatalf("failed to read snapshot:\n%s", diff)
+	}
+}
+
+func TestRead_errors(t *testing.T) {
+	readTests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"invalid JSON", `{"version":`, "failed to read snapshot: unexpected EOF"},
+		{"unknown version", `{"version":"helm-pipelines.snapshot/v2"}`, `unsupported snapshot version "helm-pipelines.snapshot/v2", want "helm-pipelines.snapshot/v1"`},
+		{"no version", `{"pipelines":[]}`, `unsupported snapshot version "", want "helm-pipelines.snapshot/v1"`},
+	}
+
+	for _, tt := range readTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.data))
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func parsePipelines(t *testing.T, releases ...helmv2.HelmRelease) ([]helm.HelmReleasePipeline, []helm.Diagnostic) {
+	t.Helper()
+	pipelines, diagnostics, err := helm.ParseHelmReleasePipelines(pipelinelabels.DefaultKeys, releases)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pipelines, diagnostics
+}
@@ -3,7 +3,9 @@ package test
 import (
 	"time"
 
-	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/pkg/apis/meta"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -21,7 +23,7 @@ func NewHelmRelease(opts ...func(client.Object)) helmv2.HelmRelease {
 		},
 		Spec: helmv2.HelmReleaseSpec{
 			Interval: metav1.Duration{Duration: time.Minute},
-			Chart: helmv2.HelmChartTemplate{
+			Chart: &helmv2.HelmChartTemplate{
 				Spec: helmv2.HelmChartTemplateSpec{
 					Chart:   "redis",
 					Version: "1.0.9",
@@ -48,3 +50,81 @@ func ChartVersion(chart, version string) func(client.Object) {
 		hr.Spec.Chart.Spec.Version = version
 	}
 }
+
+// Values sets the values on a HelmRelease from a JSON string.
+func Values(v string) func(client.Object) {
+	return func(o client.Object) {
+		hr := o.(*helmv2.HelmRelease)
+		hr.Spec.Values = &apiextensionsv1.JSON{Raw: []byte(v)}
+	}
+}
+
+// ChartSource sets the source of the chart on a HelmRelease.
+func ChartSource(kind, namespace, name string) func(client.Object) {
+	return func(o client.Object) {
+		hr := o.(*helmv2.HelmRelease)
+		hr.Spec.Chart.Spec.SourceRef = helmv2.CrossNamespaceObjectReference{
+			Kind:      kind,
+			Name:      name,
+			Namespace: namespace,
+		}
+	}
+}
+
+// ChartRef replaces the chart template on a HelmRelease with a reference to
+// an OCIRepository or HelmChart.
+func ChartRef(kind, namespace, name string) func(client.Object) {
+	return func(o client.Object) {
+		hr := o.(*helmv2.HelmRelease)
+		hr.Spec.Chart = nil
+		hr.Spec.ChartRef = &helmv2.CrossNamespaceSourceReference{
+			Kind:      kind,
+			Name:      name,
+			Namespace: namespace,
+		}
+	}
+}
+
+// Deployed records a release of the chart version in the history of a
+// HelmRelease.
+func Deployed(chart, version string) func(client.Object) {
+	return released(chart, version, "deployed")
+}
+
+// Failed records a failed release of a chart version as the latest release in
+// the history of a HelmRelease.
+func Failed(chart, version string) func(client.Object) {
+	return released(chart, version, "failed")
+}
+
+func released(chart, version, status string) func(client.Object) {
+	return func(o client.Object) {
+		hr := o.(*helmv2.HelmRelease)
+		for _, s := range hr.Status.History {
+			if status == "deployed" && s.Status == "deployed" {
+				s.Status = "superseded"
+			}
+		}
+		hr.Status.History = append(helmv2.Snapshots{{
+			Name:         hr.GetName(),
+			Namespace:    hr.GetNamespace(),
+			Version:      len(hr.Status.History) + 1,
+			ChartName:    chart,
+			ChartVersion: version,
+			Status:       status,
+		}}, hr.Status.History...)
+	}
+}
+
+// Ready marks a HelmRelease as ready since the transition time.
+func Ready(transitionTime time.Time) func(client.Object) {
+	return func(o client.Object) {
+		hr := o.(*helmv2.HelmRelease)
+		hr.Status.Conditions = append(hr.Status.Conditions, metav1.Condition{
+			Type:               meta.ReadyCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             "ReconciliationSucceeded",
+			LastTransitionTime: metav1.NewTime(transitionTime),
+		})
+	}
+}
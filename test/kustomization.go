@@ -4,6 +4,7 @@ import (
 	"time"
 
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	"github.com/fluxcd/pkg/apis/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -38,6 +39,18 @@ func Source(name, namespace string) func(client.Object) {
 	}
 }
 
+// OCISource sets an OCIRepository source on a Kustomization.
+func OCISource(name, namespace string) func(client.Object) {
+	return func(k client.Object) {
+		kz := k.(*kustomizev1.Kustomization)
+		kz.Spec.SourceRef = kustomizev1.CrossNamespaceSourceReference{
+			Kind:      "OCIRepository",
+			Name:      name,
+			Namespace: namespace,
+		}
+	}
+}
+
 // Path sets the path on a Kustomization.
 func Path(path string) func(client.Object) {
 	return func(k client.Object) {
@@ -45,3 +58,42 @@ func Path(path string) func(client.Object) {
 		kz.Spec.Path = path
 	}
 }
+
+// BucketSource sets a Bucket source on a Kustomization.
+func BucketSource(name, namespace string) func(client.Object) {
+	return func(k client.Object) {
+		kz := k.(*kustomizev1.Kustomization)
+		kz.Spec.SourceRef = kustomizev1.CrossNamespaceSourceReference{
+			Kind:      "Bucket",
+			Name:      name,
+			Namespace: namespace,
+		}
+	}
+}
+
+// Applied sets the last applied and last attempted revisions on a
+// Kustomization.
+func Applied(lastApplied, lastAttempted string) func(client.Object) {
+	return func(k client.Object) {
+		kz := k.(*kustomizev1.Kustomization)
+		kz.Status.LastAppliedRevision = lastApplied
+		kz.Status.LastAttemptedRevision = lastAttempted
+	}
+}
+
+// Reconciled sets the Ready condition on a Kustomization.
+func Reconciled(status metav1.ConditionStatus, message string) func(client.Object) {
+	return func(k client.Object) {
+		kz := k.(*kustomizev1.Kustomization)
+		reason := meta.ReconciliationSucceededReason
+		if status == metav1.ConditionFalse {
+			reason = meta.ReconciliationFailedReason
+		}
+		kz.Status.Conditions = append(kz.Status.Conditions, metav1.Condition{
+			Type:    meta.ReadyCondition,
+			Status:  status,
+			Reason:  reason,
+			Message: message,
+		})
+	}
+}
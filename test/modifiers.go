@@ -27,3 +27,27 @@ func Named(name, namespace string) func(client.Object) {
 		hr.SetNamespace(namespace)
 	}
 }
+
+// Labelled is an option that sets a label on created resources.
+func Labelled(key, value string) func(client.Object) {
+	return func(o client.Object) {
+		lbls := o.GetLabels()
+		if lbls == nil {
+			lbls = map[string]string{}
+		}
+		lbls[key] = value
+		o.SetLabels(lbls)
+	}
+}
+
+// Annotated is an option that sets an annotation on created resources.
+func Annotated(key, value string) func(client.Object) {
+	return func(o client.Object) {
+		annotations := o.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[key] = value
+		o.SetAnnotations(annotations)
+	}
+}
@@ -0,0 +1,20 @@
+package test
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewNamespace creates test Namespace resources.
+func NewNamespace(name string, opts ...func(client.Object)) *corev1.Namespace {
+	ns := corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	for _, o := range opts {
+		o(&ns)
+	}
+	return &ns
+}
@@ -0,0 +1,81 @@
+package test
+
+import (
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewOCIRepository creates test OCIRepository resources that pull the tag
+// from the URL.
+func NewOCIRepository(url, tag string, opts ...func(client.Object)) *sourcev1.OCIRepository {
+	repo := sourcev1.OCIRepository{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       sourcev1.OCIRepositoryKind,
+			APIVersion: sourcev1.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-repository",
+			Namespace: "default",
+		},
+		Spec: sourcev1.OCIRepositorySpec{
+			URL:       url,
+			Reference: &sourcev1.OCIRepositoryRef{Tag: tag},
+		},
+	}
+	for _, o := range opts {
+		o(&repo)
+	}
+	return &repo
+}
+
+// NewHelmChart creates test HelmChart resources for a chart in the
+// "test-repository" HelmRepository.
+func NewHelmChart(chart, version string, opts ...func(client.Object)) *sourcev1.HelmChart {
+	hc := sourcev1.HelmChart{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       sourcev1.HelmChartKind,
+			APIVersion: sourcev1.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-chart",
+			Namespace: "default",
+		},
+		Spec: sourcev1.HelmChartSpec{
+			Chart:   chart,
+			Version: version,
+			SourceRef: sourcev1.LocalHelmChartSourceReference{
+				Kind: sourcev1.HelmRepositoryKind,
+				Name: "test-repository",
+			},
+		},
+	}
+	for _, o := range opts {
+		o(&hc)
+	}
+	return &hc
+}
+
+// NewBucket creates test Bucket resources that fetch the objects with the
+// prefix from the bucket.
+func NewBucket(endpoint, bucketName, prefix string, opts ...func(client.Object)) *sourcev1.Bucket {
+	bucket := sourcev1.Bucket{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       sourcev1.BucketKind,
+			APIVersion: sourcev1.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-bucket",
+			Namespace: "default",
+		},
+		Spec: sourcev1.BucketSpec{
+			Endpoint:   endpoint,
+			BucketName: bucketName,
+			Prefix:     prefix,
+		},
+	}
+	for _, o := range opts {
+		o(&bucket)
+	}
+	return &bucket
+}